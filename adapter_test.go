@@ -0,0 +1,60 @@
+package log
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// adapterLog stands for a bridge method, its caller is the one reported.
+func adapterLog(l *Logger, msg string) error {
+	return l.Log(WARN, 2, msg)
+}
+
+func TestLog(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} ${short_file}:${line} ${message}\n")
+	l.SetLevel(INFO)
+
+	if err := l.Log(DEBUG, 1, "dropped"); err != nil {
+		t.Errorf("Log below the level = %v", err)
+	}
+	l.Log(INFO, 1, "100% assembled %s")
+	err := adapterLog(l, "bridged")
+	line := here() - 1
+	if err != nil {
+		t.Fatal(err)
+	}
+	exited := false
+	l.SetExitFunc(func(int) { exited = true })
+	l.Log(FATAL, 1, "no exit")
+	if exited {
+		t.Error("Log(FATAL) exited")
+	}
+	if err := l.Log(Level(42), 1, "invalid"); err == nil {
+		t.Error("Log with an invalid level = nil")
+	}
+
+	lines := out.lines()
+	want := []string{"INFO adapter_test.go:" + strconv.Itoa(line-1) + " 100% assembled %s", "WARN adapter_test.go:" + strconv.Itoa(line) + " bridged"}
+	if len(lines) < 3 || lines[0] != want[0] || lines[1] != want[1] {
+		t.Errorf("lines = %q, want %q first", lines, want)
+	}
+}
+
+func TestLogReturnsTheWriteError(t *testing.T) {
+	w := &failingWriter{}
+	l := New("", INFO, 0, 0)
+	l.SetOutput(w)
+	l.SetErrorHandler(func(error) {})
+	diskFull := errors.New("disk full")
+	w.fail(diskFull)
+	if err := l.Log(INFO, 1, "lost"); !errors.Is(err, diskFull) {
+		t.Errorf("Log = %v, want %v", err, diskFull)
+	}
+	w.fail(nil)
+	if err := l.Log(INFO, 1, "written"); err != nil {
+		t.Errorf("Log = %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// combined matches a line of the combined access log format, as parsed by
+// the usual analyzers.
+var combined = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^]]+)\] "([^"]*)" (\d{3}|-) (\d+|-) "([^"]*)" "([^"]*)"$`)
+
+func TestApacheCombined(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	if err := l.SetFormat(ApacheCombined); err != nil {
+		t.Fatal(err)
+	}
+	l.WithFields(Fields{
+		"remote_addr": "203.0.113.7",
+		"remote_user": "alice",
+		"request":     "GET /index.html HTTP/1.1",
+		"status":      200,
+		"body_bytes":  2326,
+		"referer":     "https://example.com/",
+		"user_agent":  "curl/8.0",
+	}).Info("ignored")
+	// absent and empty values render as "-"
+	l.WithFields(Fields{"remote_addr": "198.51.100.1", "request": "", "status": nil}).Info("ignored")
+
+	lines := out.lines()
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	m := combined.FindStringSubmatch(lines[0])
+	if m == nil {
+		t.Fatalf("line %q isn't in the combined format", lines[0])
+	}
+	want := []string{"203.0.113.7", "alice", "", "GET /index.html HTTP/1.1", "200", "2326", "https://example.com/", "curl/8.0"}
+	for i, w := range want {
+		if i != 2 && m[i+1] != w {
+			t.Errorf("group %d = %q, want %q", i+1, m[i+1], w)
+		}
+	}
+	tm, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[3])
+	if err != nil || time.Since(tm) > time.Minute {
+		t.Errorf("time %q: %v", m[3], err)
+	}
+
+	m = combined.FindStringSubmatch(lines[1])
+	if m == nil {
+		t.Fatalf("line %q isn't in the combined format", lines[1])
+	}
+	want = []string{"198.51.100.1", "-", "", "-", "-", "-", "-", "-"}
+	for i, w := range want {
+		if i != 2 && m[i+1] != w {
+			t.Errorf("group %d = %q, want %q", i+1, m[i+1], w)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// archive namings, see SetArchiveNaming
+const (
+	SuffixAfterExt  = "{name}{ext}.{index}"        // app.log.1, the default
+	SuffixBeforeExt = "{name}.{index}{ext}"        // app.1.log
+	SuffixTimestamp = "{name}{ext}.{time}.{index}" // app.log.20240115T000000.1, the default with a retention
+)
+
+// archiveTimeLayout renders {time}, the local time of the rotation.
+const archiveTimeLayout = "20060102T150405"
+
+// SetArchiveDir moves rotated files into dir instead of keeping them next
+// to the active file, pruning then operates on dir. The directory is
+// created when needed and may live on another filesystem.
+func (l *Logger) SetArchiveDir(dir string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.archiveDir = dir
+}
+
+func SetArchiveDir(dir string) {
+	global.SetArchiveDir(dir)
+}
+
+// datedLayout names the subdirectories of WithDatedArchives.
+const datedLayout = "2006/01/02"
+
+// WithDatedArchives moves rotated files into subdirectories of the
+// archive directory named after the day of the rotation, e.g.
+// logs/2024/01/15/app.log.3. The backups limit spans the whole tree:
+// the oldest archives are pruned, and so are the directories left empty.
+func WithDatedArchives(enabled bool) Option {
+	return func(l *Logger) {
+		l.datedArchives = enabled
+	}
+}
+
+// archiveLayout returns the directory the archives of the file name go to,
+// the root of the days with WithDatedArchives, and their naming. With a
+// retention the archives carry their time by default, see SuffixTimestamp.
+func (l *Logger) archiveLayout(name string) (dir, naming string) {
+	dir = l.archiveDir
+	if dir == "" {
+		dir = filepath.Dir(name)
+	}
+	naming = l.archiveNaming
+	if naming == "" {
+		naming = SuffixAfterExt
+		if l.retention() != nil {
+			naming = SuffixTimestamp
+		}
+	}
+	return dir, naming
+}
+
+type archiveFile struct {
+	path string
+	idx  int
+	ext  string    // compression extension
+	time time.Time // the rotation time, zero when the naming has no {time}
+}
+
+// listArchives returns the archives of base found in dir, named as naming
+// or one of the default ways, so changing the naming doesn't orphan them.
+func listArchives(dir, base, naming string) ([]archiveFile, error) {
+	list, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var archives []archiveFile
+	for _, file := range list {
+		if file.IsDir() {
+			continue
+		}
+		name, ext := splitExtension(file.Name())
+		idx, at, ok := parseArchiveName(naming, base, name)
+		if !ok {
+			if idx, ok = parseArchiveIndex(base, file.Name()); ok {
+				_, at, _ = parseArchiveName(SuffixTimestamp, base, name)
+			}
+		}
+		if ok {
+			archives = append(archives, archiveFile{filepath.Join(dir, file.Name()), idx, ext, at})
+		}
+	}
+	return archives, nil
+}
+
+// pruneDated keeps the newest backups-1 archives of base across the dated
+// directories of root, newest day first and lowest index first within a
+// day, and removes the directories left empty.
+func pruneDated(root, base, naming string, backups int) error {
+	days, err := archiveDays(root)
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for i := len(days) - 1; i >= 0; i-- {
+		archives, err := listArchives(days[i], base, naming)
+		if err != nil {
+			return err
+		}
+		sort.Slice(archives, func(i, j int) bool {
+			return archives[i].idx < archives[j].idx
+		})
+		for _, a := range archives {
+			if kept+1 < backups {
+				kept++
+				continue
+			}
+			os.Remove(a.path)
+		}
+		// only removed when empty, up to the year
+		for dir := days[i]; dir != root && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// archiveDays returns the dated directories of root, oldest first.
+func archiveDays(root string) ([]string, error) {
+	return filepath.Glob(filepath.Join(root, "[0-9][0-9][0-9][0-9]", "[0-9][0-9]", "[0-9][0-9]"))
+}
+
+// SetArchiveNaming sets how rotated files are named, either one of
+// SuffixAfterExt, SuffixBeforeExt and SuffixTimestamp or a template of
+// {name} (the file name without extension), {ext}, {index} and optionally
+// {time}, e.g. "{name}-{time}-{index}{ext}". Archives named the default
+// ways are still shifted and pruned, and renamed to the new naming on the
+// next rotation. Pruning by age needs the {time} of the archives, or their
+// day with WithDatedArchives: the others are only pruned by backups.
+func (l *Logger) SetArchiveNaming(naming string) error {
+	if strings.Count(naming, "{index}") != 1 {
+		return fmt.Errorf("log: archive naming %q needs one {index}", naming)
+	}
+	if strings.Count(naming, "{time}") > 1 {
+		return fmt.Errorf("log: archive naming %q has more than one {time}", naming)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.archiveNaming = naming
+	return nil
+}
+
+func SetArchiveNaming(naming string) error {
+	return global.SetArchiveNaming(naming)
+}
+
+// archiveName renders the name of backup idx of the file base, rotated at
+// the given time.
+func archiveName(naming, base string, idx int, at time.Time) string {
+	ext := filepath.Ext(base)
+	return strings.NewReplacer(
+		"{name}", strings.TrimSuffix(base, ext),
+		"{ext}", ext,
+		"{index}", strconv.Itoa(idx),
+		"{time}", at.Format(archiveTimeLayout),
+	).Replace(naming)
+}
+
+// namingPart is a literal of a naming, or one of its {index} and {time}.
+type namingPart struct {
+	literal     string
+	placeholder string
+}
+
+// namingParts splits naming into literals, {name} and {ext} rendered for
+// base, and the {index} and {time} placeholders.
+func namingParts(naming, base string) []namingPart {
+	ext := filepath.Ext(base)
+	values := map[string]string{"{name}": strings.TrimSuffix(base, ext), "{ext}": ext}
+	var parts []namingPart
+	var literal strings.Builder
+	for naming != "" {
+		placeholder := ""
+		for _, p := range []string{"{name}", "{ext}", "{index}", "{time}"} {
+			if strings.HasPrefix(naming, p) {
+				placeholder = p
+				break
+			}
+		}
+		switch placeholder {
+		case "":
+			literal.WriteByte(naming[0])
+			naming = naming[1:]
+			continue
+		case "{name}", "{ext}":
+			literal.WriteString(values[placeholder])
+		default:
+			if literal.Len() > 0 {
+				parts = append(parts, namingPart{literal: literal.String()})
+				literal.Reset()
+			}
+			parts = append(parts, namingPart{placeholder: placeholder})
+		}
+		naming = naming[len(placeholder):]
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, namingPart{literal: literal.String()})
+	}
+	return parts
+}
+
+// parseArchiveName reports the backup index of name and its rotation time,
+// zero when the naming has no {time}. name must be exactly the naming of
+// base with digits for {index} and a valid time for {time} (e.g.
+// "app.log.3"); anything else is not an archive.
+func parseArchiveName(naming, base, name string) (idx int, at time.Time, ok bool) {
+	parts := namingParts(naming, base)
+	for i, p := range parts {
+		switch p.placeholder {
+		case "":
+			if !strings.HasPrefix(name, p.literal) {
+				return 0, time.Time{}, false
+			}
+			name = name[len(p.literal):]
+		case "{time}":
+			if len(name) < len(archiveTimeLayout) {
+				return 0, time.Time{}, false
+			}
+			t, err := time.ParseInLocation(archiveTimeLayout, name[:len(archiveTimeLayout)], time.Local)
+			if err != nil {
+				return 0, time.Time{}, false
+			}
+			at, name = t, name[len(archiveTimeLayout):]
+		case "{index}":
+			// the digits up to the trailing literal, or all of them when
+			// another placeholder follows
+			end := len(name) - len(strings.TrimLeft(name, "0123456789"))
+			if i == len(parts)-2 && parts[i+1].placeholder == "" {
+				end = len(name) - len(parts[i+1].literal)
+			}
+			if end < 0 || !allDigits(name[:end]) {
+				return 0, time.Time{}, false
+			}
+			n, err := strconv.Atoi(name[:end])
+			if err != nil || n < 1 {
+				return 0, time.Time{}, false
+			}
+			idx, name = n, name[end:]
+		}
+	}
+	return idx, at, idx > 0 && name == ""
+}
+
+// parseArchiveIndex reports the backup index of name, an archive of base
+// named one of the default ways, SuffixAfterExt or SuffixTimestamp, and
+// maybe compressed: "app.log.3", "app.log.20240115T000000.3.gz". Anything
+// else, e.g. "app.log.gz", "app.log.old" or "app.log.0", is not an archive.
+func parseArchiveIndex(base, name string) (int, bool) {
+	name, _ = splitExtension(name)
+	for _, naming := range []string{SuffixAfterExt, SuffixTimestamp} {
+		if idx, _, ok := parseArchiveName(naming, base, name); ok {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// allDigits reports whether s is a non-empty run of ASCII digits.
+func allDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// rename is os.Rename, tests fail it with EXDEV to cross filesystems.
+var rename = os.Rename
+
+// moveFile renames src to dst, falling back to copy and remove when they
+// are on different filesystems. The modification time is preserved so age
+// based pruning keeps working.
+func moveFile(src, dst string) error {
+	err := rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	// only complete copies get the archive name
+	part := dst + partSuffix
+	if err := copyFile(src, part, fi.Mode()); err != nil {
+		os.Remove(part)
+		return err
+	}
+	if err := os.Chtimes(part, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseArchiveIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		idx  int
+		ok   bool
+	}{
+		{"app.log.1", 1, true},
+		{"app.log.2", 2, true},
+		{"app.log.20", 20, true},
+		{"app.log.007", 7, true},
+		{"app.log.1.gz", 1, true},
+		{"app.log.12.gz", 12, true},
+		{"app.log.20240115T000000.3", 3, true},
+		{"app.log.20240115T235959.3.gz", 3, true},
+		{"app.log", 0, false},
+		{"app.log.", 0, false},
+		{"app.log.0", 0, false},
+		{"app.log.00", 0, false},
+		{"app.log.-1", 0, false},
+		{"app.log.+1", 0, false},
+		{"app.log. 1", 0, false},
+		{"app.log.1 ", 0, false},
+		{"app.log.gz", 0, false},
+		{"app.log.old", 0, false},
+		{"app.log.1.old", 0, false},
+		{"app.log.1a", 0, false},
+		{"app.log.1.tmp", 0, false},
+		{"app.log.1.gz.tmp", 0, false},
+		{"app.log.1" + partSuffix, 0, false},
+		{"app.log..1", 0, false},
+		{"app.log1", 0, false},
+		{"app.1.log", 0, false},
+		{"xapp.log.1", 0, false},
+		{"other.log.1", 0, false},
+		{"app.log.99999999999999999999", 0, false},
+		{"app.log.20241315T000000.3", 0, false},
+		{"app.log.20240115T000000", 0, false},
+		{"app.log.20240115T000000.", 0, false},
+		{"app.log.20240115.3", 0, false},
+		{"app.log.3.20240115T000000", 0, false},
+		{"app.log.١", 0, false},
+	}
+	for _, tt := range tests {
+		idx, ok := parseArchiveIndex("app.log", tt.name)
+		if idx != tt.idx || ok != tt.ok {
+			t.Errorf("parseArchiveIndex(%q) = %d, %v, want %d, %v", tt.name, idx, ok, tt.idx, tt.ok)
+		}
+	}
+}
+
+func TestParseArchiveName(t *testing.T) {
+	at := time.Date(2024, 1, 15, 13, 4, 5, 0, time.Local)
+	tests := []struct {
+		naming, name string
+		idx          int
+		time         time.Time
+		ok           bool
+	}{
+		{SuffixBeforeExt, "app.3.log", 3, time.Time{}, true},
+		{SuffixBeforeExt, "app.log.3", 0, time.Time{}, false},
+		{SuffixBeforeExt, "app..log", 0, time.Time{}, false},
+		{SuffixTimestamp, "app.log.20240115T130405.2", 2, at, true},
+		{SuffixTimestamp, "app.log.2", 0, time.Time{}, false},
+		{"{name}-{time}-{index}{ext}", "app-20240115T130405-12.log", 12, at, true},
+		{"{name}-{time}-{index}{ext}", "app-20240115T130405-.log", 0, time.Time{}, false},
+		{"{name}-{index}-{time}{ext}", "app-4-20240115T130405.log", 4, at, true},
+		{"{name}-{index}-{time}{ext}", "app-4x-20240115T130405.log", 0, time.Time{}, false},
+		{"{index}", "5", 5, time.Time{}, true},
+		{"{name}{ext}.{index}", "app.log.5", 5, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		idx, got, ok := parseArchiveName(tt.naming, "app.log", tt.name)
+		if idx != tt.idx || !got.Equal(tt.time) || ok != tt.ok {
+			t.Errorf("parseArchiveName(%q, %q) = %d, %v, %v, want %d, %v, %v", tt.naming, tt.name, idx, got, ok, tt.idx, tt.time, tt.ok)
+		}
+	}
+}
+
+func TestArchiveNameRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 15, 13, 4, 5, 0, time.Local)
+	for _, naming := range []string{SuffixAfterExt, SuffixBeforeExt, SuffixTimestamp, "{name}-{time}-{index}{ext}", "{name}{ext}.{index}.{time}"} {
+		for _, base := range []string{"app.log", "app", "a.b.log", "{index}.log"} {
+			for _, idx := range []int{1, 9, 10, 123} {
+				name := archiveName(naming, base, idx, at)
+				got, gotAt, ok := parseArchiveName(naming, base, name)
+				if !ok || got != idx || (strings.Contains(naming, "{time}") && !gotAt.Equal(at)) {
+					t.Errorf("%s of %s: parseArchiveName(%q) = %d, %v, %v", naming, base, name, got, gotAt, ok)
+				}
+			}
+		}
+	}
+}
+
+func FuzzParseArchiveIndex(f *testing.F) {
+	for _, seed := range []string{"app.log.1", "app.log.20", "app.log.1.gz", "app.log.gz", "app.log.old", "app.log.20240115T000000.3", "app.log.-1", "app.log..1"} {
+		f.Add("app.log", seed)
+	}
+	f.Fuzz(func(t *testing.T, base, name string) {
+		idx, ok := parseArchiveIndex(base, name)
+		if !ok {
+			if idx != 0 {
+				t.Errorf("parseArchiveIndex(%q, %q) = %d without an archive", base, name, idx)
+			}
+			return
+		}
+		if idx < 1 || !strings.HasPrefix(name, base+".") {
+			t.Fatalf("parseArchiveIndex(%q, %q) = %d", base, name, idx)
+		}
+		// the index is the last run of digits, before any compression
+		rest, _ := splitExtension(name)
+		digits := rest[strings.LastIndexByte(rest, '.')+1:]
+		if n, err := strconv.Atoi(digits); err != nil || n != idx {
+			t.Fatalf("parseArchiveIndex(%q, %q) = %d, the name ends with %q", base, name, idx, digits)
+		}
+		// and an archive renamed to the next index is still one
+		if next, ok := parseArchiveIndex(base, archiveName(SuffixAfterExt, base, idx+1, time.Time{})); !ok || next != idx+1 {
+			t.Fatalf("the archive %d of %q after %q = %d, %v", idx+1, base, name, next, ok)
+		}
+	})
+}
+
+func TestArchiveDir(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	archives := filepath.Join(dir, "bulk", "logs")
+	l := New(name, INFO, 0, 3)
+	defer l.Close()
+	l.SetArchiveDir(archives)
+	for i := 1; i <= 4; i++ {
+		l.Infof("entry %d", i)
+		if err := l.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if left, _ := filepath.Glob(name + ".*"); len(left) > 0 {
+		t.Errorf("archives left next to the active file: %v", left)
+	}
+	if got := readFile(t, filepath.Join(archives, "app.log.1")); !strings.Contains(got, "entry 4") {
+		t.Errorf("app.log.1 = %q", got)
+	}
+	if got := readFile(t, filepath.Join(archives, "app.log.2")); !strings.Contains(got, "entry 3") {
+		t.Errorf("app.log.2 = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(archives, "app.log.3")); !os.IsNotExist(err) {
+		t.Errorf("app.log.3 not pruned: %v", err)
+	}
+}
+
+func TestMoveFileAcrossFilesystems(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "app.log.1.tmp"), filepath.Join(dir, "app.log.1")
+	if err := ioutil.WriteFile(src, []byte("archived\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(src, old, old); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { rename = os.Rename }()
+	rename = func(from, to string) error {
+		if from == src {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.EXDEV}
+		}
+		return os.Rename(from, to)
+	}
+
+	if err := moveFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source not removed: %v", err)
+	}
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(old) {
+		t.Errorf("mtime = %v, want %v", fi.ModTime(), old)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", fi.Mode())
+	}
+	if got := readFile(t, dst); got != "archived\n" {
+		t.Errorf("content = %q", got)
+	}
+	if parts, _ := filepath.Glob(filepath.Join(dir, "*"+partSuffix)); len(parts) > 0 {
+		t.Errorf("leftovers: %v", parts)
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+var defaultBuildInfoKeys = []string{"module", "version", "vcs.revision", "vcs.time", "vcs.modified", "go", "os", "arch", "pid", "gomaxprocs"}
+
+// SetBuildInfoKeys restricts the keys reported by LogBuildInfo,
+// no keys restores the default set.
+func (l *Logger) SetBuildInfoKeys(keys ...string) {
+	l.buildInfoKeys = keys
+}
+
+// LogBuildInfo emits a single INFO entry describing the running binary:
+// module version, VCS revision/time/dirty flag, go version, os/arch and pid.
+// Keys that can't be determined (go run, tests) are left out.
+func (l *Logger) LogBuildInfo() {
+	l.emit(l.buildInfoEntry(), 2, "", []interface{}{"build info"})
+}
+
+func SetBuildInfoKeys(keys ...string) {
+	global.SetBuildInfoKeys(keys...)
+}
+
+func LogBuildInfo() {
+	global.emit(global.buildInfoEntry(), 2, "", []interface{}{"build info"})
+}
+
+func (l *Logger) buildInfoEntry() Entry {
+	info := map[string]string{
+		"go":         runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"pid":        pid,
+		"gomaxprocs": strconv.Itoa(runtime.GOMAXPROCS(0)),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info["module"] = bi.Main.Path
+		if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info["version"] = bi.Main.Version
+		}
+		if bi.GoVersion != "" {
+			info["go"] = bi.GoVersion
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				info[s.Key] = s.Value
+			}
+		}
+	}
+
+	keys := l.buildInfoKeys
+	if len(keys) == 0 {
+		keys = defaultBuildInfoKeys
+	}
+	fields := make(Fields, len(keys))
+	for _, k := range keys {
+		if v, ok := info[k]; ok && v != "" {
+			fields[k] = v
+		}
+	}
+	return Entry{Level: INFO, Fields: fields}
+}
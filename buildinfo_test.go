@@ -0,0 +1,65 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func buildInfoFields(t *testing.T, keys ...string) Fields {
+	t.Helper()
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} ${short_file} ${message}\n")
+	l.SetBuildInfoKeys(keys...)
+	var fields Fields
+	l.AddHook(funcHook(func(e *Entry) error {
+		fields = make(Fields, len(e.Fields))
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		return nil
+	}))
+	l.LogBuildInfo()
+
+	if got := out.String(); got != "INFO buildinfo_test.go build info\n" {
+		t.Errorf("output = %q", got)
+	}
+	return fields
+}
+
+func TestLogBuildInfo(t *testing.T) {
+	fields := buildInfoFields(t)
+	want := map[string]string{
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"pid":        strconv.Itoa(os.Getpid()),
+		"gomaxprocs": strconv.Itoa(runtime.GOMAXPROCS(0)),
+	}
+	for k, w := range want {
+		if fields[k] != w {
+			t.Errorf("%s = %v, want %q", k, fields[k], w)
+		}
+	}
+	if g, _ := fields["go"].(string); !strings.HasPrefix(g, "go") {
+		t.Errorf("go = %v, want the go version", fields["go"])
+	}
+	// a test binary has no main module version, the key is left out
+	if _, ok := fields["version"]; ok {
+		t.Errorf("version = %v, want it left out", fields["version"])
+	}
+}
+
+func TestSetBuildInfoKeys(t *testing.T) {
+	fields := buildInfoFields(t, "pid", "arch", "unknown")
+	if len(fields) != 2 || fields["pid"] == nil || fields["arch"] == nil {
+		t.Errorf("fields = %v, want pid and arch only", fields)
+	}
+
+	// no keys restores the default set
+	if fields := buildInfoFields(t); fields["gomaxprocs"] == nil {
+		t.Errorf("fields = %v, want the default keys", fields)
+	}
+}
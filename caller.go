@@ -0,0 +1,105 @@
+package log
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// callerRule drops (or keeps) the entries logged from files under prefix.
+type callerRule struct {
+	prefix string
+	allow  bool
+}
+
+// DenyCallerPrefix drops the entries below the caller filter level (ERROR
+// by default, see SetCallerFilterLevel) logged from source files under
+// pathPrefix, e.g. noisy vendored code logging through an injected Logger.
+// The prefix is matched against the file as reported by runtime.Caller:
+// an absolute path, or the module path when built with -trimpath. Forced
+// entries are never dropped.
+func (l *Logger) DenyCallerPrefix(pathPrefix string) {
+	l.addCallerRule(callerRule{pathPrefix, false})
+}
+
+// AllowCallerPrefix keeps the entries logged from files under pathPrefix,
+// the longest matching prefix wins: allow "vendor/ours/" within a denied
+// "vendor/". With only allow rules, entries from other files are dropped.
+func (l *Logger) AllowCallerPrefix(pathPrefix string) {
+	l.addCallerRule(callerRule{pathPrefix, true})
+}
+
+func (l *Logger) addCallerRule(r callerRule) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	old, _ := l.callerRules.Load().([]callerRule)
+	rules := make([]callerRule, 0, len(old)+1)
+	for _, o := range old {
+		if o.prefix != r.prefix {
+			rules = append(rules, o)
+		}
+	}
+	rules = append(rules, r)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].prefix) > len(rules[j].prefix)
+	})
+	l.callerRules.Store(rules)
+}
+
+// SetCallerFilterLevel sets the level from which entries pass the caller
+// rules whatever their file, ERROR by default.
+func (l *Logger) SetCallerFilterLevel(v Level) {
+	l.lazyInit()
+	l.callerLevel = v
+}
+
+func DenyCallerPrefix(pathPrefix string) {
+	global.DenyCallerPrefix(pathPrefix)
+}
+
+func AllowCallerPrefix(pathPrefix string) {
+	global.AllowCallerPrefix(pathPrefix)
+}
+
+func SetCallerFilterLevel(v Level) {
+	global.SetCallerFilterLevel(v)
+}
+
+// hasCallerRules reports whether entries at v are subject to caller rules.
+func (l *Logger) hasCallerRules(v Level) bool {
+	if v >= l.callerLevel {
+		return false
+	}
+	rules, _ := l.callerRules.Load().([]callerRule)
+	return len(rules) > 0
+}
+
+// callerDenied reports whether the rules drop entries logged from file.
+func (l *Logger) callerDenied(file string) bool {
+	rules, _ := l.callerRules.Load().([]callerRule)
+	onlyAllow := true
+	for _, r := range rules {
+		if strings.HasPrefix(file, r.prefix) {
+			return !r.allow
+		}
+		onlyAllow = onlyAllow && r.allow
+	}
+	return onlyAllow
+}
+
+// caller is runtime.Caller(skip) without its allocations, the program
+// counter being the one of the call.
+func caller(skip int) (pc uintptr, file string, line int) {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0, "", 0
+	}
+	pc = pcs[0] - 1
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return pc, "", 0
+	}
+	file, line = fn.FileLine(pc)
+	return pc, file, line
+}
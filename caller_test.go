@@ -0,0 +1,104 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// here returns the line it's called from, so a case logs and reads its
+// line in one statement.
+func here() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// TestCallerDepth checks that every public entry point reports the line
+// calling it, not a frame within the package.
+func TestCallerDepth(t *testing.T) {
+	ctx := context.Background()
+	err := errors.New("failed")
+	tests := []struct {
+		name string
+		log  func(l *Logger) int
+	}{
+		{"Print", func(l *Logger) int { l.Print("m"); return here() }},
+		{"Printf", func(l *Logger) int { l.Printf("%s", "m"); return here() }},
+		{"Debug", func(l *Logger) int { l.Debug("m"); return here() }},
+		{"Infof", func(l *Logger) int { l.Infof("%s", "m"); return here() }},
+		{"Warn", func(l *Logger) int { l.Warn("m"); return here() }},
+		{"Errorf", func(l *Logger) int { l.Errorf("%s", "m"); return here() }},
+		{"Fatal", func(l *Logger) int { l.Fatal("m"); return here() }},
+		{"Log", func(l *Logger) int { l.Log(INFO, 1, "m"); return here() }},
+		{"InfoCtx", func(l *Logger) int { l.InfoCtx(ctx, "m"); return here() }},
+		{"ErrorfCtx", func(l *Logger) int { l.ErrorfCtx(ctx, "%s", "m"); return here() }},
+		{"Err", func(l *Logger) int { l.Err(err, "m"); return here() }},
+		{"Warne", func(l *Logger) int { l.Warne(err); return here() }},
+		{"Auto", func(l *Logger) int { l.Auto(err, "m"); return here() }},
+		{"Autof", func(l *Logger) int { l.Autof(err, "%s", "m"); return here() }},
+		{"Timed", func(l *Logger) int { l.Timed("m")(); return here() }},
+		{"TimeTrack", func(l *Logger) int { l.TimeTrack(time.Now(), "m"); return here() }},
+		{"Entry.Info", func(l *Logger) int { l.WithField("k", 1).Info("m"); return here() }},
+		{"Entry.Warnf", func(l *Logger) int { l.With().Str("k", "v").Warnf("%s", "m"); return here() }},
+		{"Entry.Fatalf", func(l *Logger) int { l.Force().Fatalf("%s", "m"); return here() }},
+		{"Entry.Log", func(l *Logger) int { l.With().Log(INFO, 1, "m"); return here() }},
+		{"Entry.WithContext", func(l *Logger) int { l.With().WithContext(ctx).Error("m"); return here() }},
+		{"StdLogger", func(l *Logger) int { l.StdLogger(INFO).Print("m"); return here() }},
+		{"StdLogger.Printf", func(l *Logger) int { l.StdLogger(WARN).Printf("%s", "m"); return here() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			l.SetFormat("${short_file}:${line}\n")
+			l.SetExitFunc(func(int) {})
+			line := tt.log(l)
+			if got, want := out.lines(), "caller_test.go:"+strconv.Itoa(line); len(got) == 0 || got[0] != want {
+				t.Errorf("lines = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCallerDepthOfPackageFunctions(t *testing.T) {
+	ctx := context.Background()
+	err := errors.New("failed")
+	tests := []struct {
+		name string
+		log  func() int
+	}{
+		{"Print", func() int { Print("m"); return here() }},
+		{"Printf", func() int { Printf("%s", "m"); return here() }},
+		{"Debugf", func() int { Debugf("%s", "m"); return here() }},
+		{"Info", func() int { Info("m"); return here() }},
+		{"Warnf", func() int { Warnf("%s", "m"); return here() }},
+		{"Error", func() int { Error("m"); return here() }},
+		{"Fatalf", func() int { Fatalf("%s", "m"); return here() }},
+		{"Log", func() int { Log(INFO, 1, "m"); return here() }},
+		{"WarnCtx", func() int { WarnCtx(ctx, "m"); return here() }},
+		{"Err", func() int { Err(err); return here() }},
+		{"Auto", func() int { Auto(err); return here() }},
+		{"Timed", func() int { Timed("m")(); return here() }},
+		{"TimeTrack", func() int { TimeTrack(time.Now(), "m"); return here() }},
+		{"WithField", func() int { WithField("k", 1).Info("m"); return here() }},
+		{"Event", func() int { Event("started").Info("m"); return here() }},
+	}
+	old := GetLogger()
+	defer SetLogger(old)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			l.SetFormat("${short_file}:${line}\n")
+			l.SetExitFunc(func(int) {})
+			SetLogger(l)
+			line := tt.log()
+			if got, want := out.lines(), "caller_test.go:"+strconv.Itoa(line); len(got) == 0 || got[0] != want {
+				t.Errorf("lines = %q, want %q", got, want)
+			}
+		})
+	}
+}
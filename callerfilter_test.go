@@ -0,0 +1,59 @@
+package log
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCallerDenied(t *testing.T) {
+	var l Logger
+	l.DenyCallerPrefix("/src/vendor/")
+	l.AllowCallerPrefix("/src/vendor/ours/")
+	l.DenyCallerPrefix("/src/vendor/ours/noisy.go")
+	tests := []struct {
+		file   string
+		denied bool
+	}{
+		{"/src/main.go", false},
+		{"/src/vendor/lib/x.go", true},
+		{"/src/vendor/ours/y.go", false},
+		{"/src/vendor/ours/noisy.go", true},
+	}
+	for _, tt := range tests {
+		if got := l.callerDenied(tt.file); got != tt.denied {
+			t.Errorf("callerDenied(%s) = %v, want %v", tt.file, got, tt.denied)
+		}
+	}
+
+	// with only allow rules, the other files are denied
+	var allow Logger
+	allow.AllowCallerPrefix("/src/app/")
+	if allow.callerDenied("/src/app/a.go") || !allow.callerDenied("/src/lib/b.go") {
+		t.Error("an allow list doesn't deny the other files")
+	}
+}
+
+func TestCallerRules(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.DenyCallerPrefix(filepath.Dir(file))
+	l.Info("denied")
+	l.Error("kept from ERROR")
+	l.SetCallerFilterLevel(FATAL)
+	l.Error("denied too")
+	c := l.Child("child")
+	c.Warn("denied in the child")
+	l.AllowCallerPrefix(file)
+	l.Info("allowed")
+
+	want := []string{"ERROR kept from ERROR", "INFO allowed"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if st := l.Stats().Suppressed; st.Caller != 2 {
+		t.Errorf("suppressed %+v, want 2 by caller", st)
+	}
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"io"
+	"sync"
+)
+
+// maxChildren bounds the WithPrefix cache, further prefixes get a new
+// child on every call instead of leaking.
+const maxChildren = 1024
+
+var children = struct {
+	sync.Mutex
+	m map[string]*Logger
+}{m: make(map[string]*Logger)}
+
+// WithPrefix returns a child of the global logger with its own prefix, so
+// packages don't fight over SetPrefix. Children write through the global
+// logger, sharing its output and rotation, and follow it when it's
+// replaced with SetLogger. They follow its level too unless given their
+// own with SetLevel, the other settings are copied on creation. Children
+// are cached per prefix.
+func WithPrefix(p string) *Logger {
+	children.Lock()
+	defer children.Unlock()
+
+	if c, ok := children.m[p]; ok {
+		return c
+	}
+	c := global.child(p, func() *Logger { return global })
+	if len(children.m) < maxChildren {
+		children.m[p] = c
+	}
+	return c
+}
+
+// Child returns a Logger with its own prefix writing through l, sharing
+// its output and rotation. The child follows the level of l at log time
+// until it's given its own with SetLevel, InheritLevel goes back to
+// following it. The other settings are copied from l.
+func (l *Logger) Child(prefix string) *Logger {
+	return l.child(prefix, func() *Logger { return l })
+}
+
+func (l *Logger) child(prefix string, parent func() *Logger) *Logger {
+	l.lazyInit()
+	c := &Logger{
+		level:          levelUnset,
+		parent:         parent,
+		prefix:         prefix,
+		levelStyle:     l.levelStyle,
+		colorScope:     l.colorScope,
+		theme:          l.theme,
+		json:           l.json,
+		jsonConfig:     l.jsonConfig,
+		maxMessage:     l.maxMessage,
+		lineEnding:     l.lineEnding,
+		exitFunc:       l.exitFunc,
+		fatalBehavior:  l.fatalBehavior,
+		sanitize:       l.sanitize,
+		stackPolicy:    l.stackPolicy,
+		stackMarker:    l.stackMarker,
+		hidePID:        l.hidePID,
+		hidePrefix:     l.hidePrefix,
+		hideCaller:     l.hideCaller,
+		journalPrefix:  l.journalPrefix,
+		journalTarget:  2,
+		unknownTag:     l.unknownTag,
+		goroutineID:    l.goroutineID,
+		fieldFormatter: l.fieldFormatter,
+		fingerprinter:  l.fingerprinter,
+		levelMapper:    l.levelMapper,
+		ctxExtractor:   l.ctxExtractor,
+		output:         parentOutput{parent},
+	}
+	c.template.Store(c.mustTemplate(l.template.Load().(*textFormat).src))
+	c.lazyInit()
+	c.timedLevel = l.timedLevel
+	c.callerLevel = l.callerLevel
+	if filters, ok := l.filters.Load().([]Filter); ok {
+		c.filters.Store(filters)
+	}
+	if filters, ok := l.messageFilters.Load().([]Filter); ok {
+		c.messageFilters.Store(filters)
+	}
+	if classifiers, ok := l.classifiers.Load().([]func(error) (Level, bool)); ok {
+		c.classifiers.Store(classifiers)
+	}
+	if rules, ok := l.callerRules.Load().([]callerRule); ok {
+		c.callerRules.Store(rules)
+	}
+	if hooks, ok := l.hooks.Load().([]Hook); ok {
+		c.hooks.Store(hooks)
+	}
+	if hooks, ok := l.writeHooks.Load().([]Hook); ok {
+		c.writeHooks.Store(hooks)
+	}
+	if l.ColorEnabled() {
+		c.EnableColor()
+	}
+	return c
+}
+
+// Writer returns a writer of already formatted entries to l's output,
+// with its rotation, e.g. for AddOutputLevel of another Logger.
+func (l *Logger) Writer() io.Writer {
+	return parentOutput{func() *Logger { return l }}
+}
+
+// parentOutput writes through the parent logger, with its rotation.
+type parentOutput struct {
+	parent func() *Logger
+}
+
+func (o parentOutput) Write(b []byte) (int, error) {
+	p := o.parent()
+	p.lazyInit()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := p.writeLocked(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (o parentOutput) writeEntry(b []byte) ([]byte, string, int, error) {
+	p := o.parent()
+	p.lazyInit()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.writeEntryLocked(b)
+}
+
+func (o parentOutput) Sync() error {
+	return o.parent().Sync()
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLevelCascade(t *testing.T) {
+	var out syncBuffer
+	root := newTestLogger(&out)
+	root.SetLevel(INFO)
+	mid := root.Child("mid")
+	leaf := mid.Child("leaf")
+	other := root.Child("other")
+	for _, l := range []*Logger{mid, leaf, other} {
+		l.SetFormat("${prefix} ${level} ${message}\n")
+	}
+	enabled := func() string {
+		var s []string
+		for _, l := range []*Logger{root, mid, leaf, other} {
+			s = append(s, levelName(l.Level()))
+		}
+		return strings.Join(s, " ")
+	}
+
+	steps := []struct {
+		change func()
+		want   string
+	}{
+		{func() {}, "INFO INFO INFO INFO"},
+		{func() { root.SetLevel(DEBUG) }, "DEBUG DEBUG DEBUG DEBUG"},
+		{func() { mid.SetLevel(WARN) }, "DEBUG WARN WARN DEBUG"},
+		{func() { leaf.SetLevel(ERROR) }, "DEBUG WARN ERROR DEBUG"},
+		{func() { root.SetLevel(INFO) }, "INFO WARN ERROR INFO"},
+		{func() { mid.InheritLevel() }, "INFO INFO ERROR INFO"},
+		{func() { leaf.InheritLevel() }, "INFO INFO INFO INFO"},
+		{func() { root.InheritLevel() }, "INFO INFO INFO INFO"},
+	}
+	for i, s := range steps {
+		s.change()
+		if got := enabled(); got != s.want {
+			t.Errorf("step %d: levels = %s, want %s", i, got, s.want)
+		}
+	}
+
+	root.SetLevel(DEBUG)
+	mid.SetLevel(WARN)
+	leaf.Info("dropped by mid's level")
+	leaf.Warn("through mid")
+	other.Debug("through root")
+	if got, want := out.String(), "leaf WARN through mid\nother DEBUG through root\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLevelCycle(t *testing.T) {
+	a := New("", DEBUG, 0, 0)
+	b := a.Child("b")
+	// a cycle can't be built through the API, forge one
+	a.parent = func() *Logger { return b }
+	a.level, b.level = levelUnset, levelUnset
+	if v := b.Level(); v != INFO {
+		t.Errorf("Level() = %s in a cycle, want INFO", levelName(v))
+	}
+}
+
+func TestChildCopiesPrefixAndJournalSettings(t *testing.T) {
+	stdout := redirect(t, &os.Stdout)
+	l := New("", INFO, 0, 0)
+	l.SetOutput(os.Stdout)
+	l.ShowPID(false)
+	l.ShowCaller(false)
+	l.ShowPrefix(false)
+	l.EnableJournalPrefix(true)
+	l.SetPrefix("root")
+	c := l.Child("child")
+	if !c.hidePrefix || !c.journalPrefix {
+		t.Fatalf("hidePrefix = %v, journalPrefix = %v, want both copied", c.hidePrefix, c.journalPrefix)
+	}
+	l.Warn("from the root")
+	c.Warn("from the child")
+	c.Child("grandchild").Error("from the grandchild")
+
+	lines := strings.Split(strings.TrimSuffix(stdout(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q", lines)
+	}
+	for i, want := range []string{"<4>", "<4>", "<3>"} {
+		if !strings.HasPrefix(lines[i], want) || strings.Contains(lines[i], "[root]") || strings.Contains(lines[i], "child]") {
+			t.Errorf("line %q, want the priority %s and no prefix", lines[i], want)
+		}
+	}
+
+	// a child given its own output only follows it
+	var out syncBuffer
+	c.SetOutput(&out)
+	c.Warn("buffered")
+	if got := out.String(); strings.HasPrefix(got, "<") || !strings.Contains(got, "buffered") {
+		t.Errorf("output = %q, want no priority off the journal", got)
+	}
+}
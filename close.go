@@ -0,0 +1,218 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// Counts returns the number of entries written per level since the logger
+// was created, keyed by lowercase level name.
+func (l *Logger) Counts() map[string]uint64 {
+	counts := make(map[string]uint64, len(levelNames))
+	for v, name := range levelNames {
+		counts[strings.ToLower(name)] = atomic.LoadUint64(&l.counts[v])
+	}
+	return counts
+}
+
+// Close flushes and closes the output, including writers given to SetOutput
+// that implement io.Closer, after waiting a few seconds at most for
+// pending rotations. With WithCloseSummary it first logs how many
+// entries were written per level. Later entries are discarded.
+func (l *Logger) Close() error {
+	return l.close(3)
+}
+
+func (l *Logger) close(calldepth int) error {
+	if l.closeSummary {
+		l.emit(Entry{Level: INFO}, calldepth, "", []interface{}{l.summary()})
+	}
+	return l.closeOutput()
+}
+
+// closeOutput flushes and closes the output: the managed file, or a user
+// supplied writer implementing io.Closer (stdout and stderr are left open).
+// The file is released even when it's no longer the output.
+func (l *Logger) closeOutput() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.scheduleStop != nil {
+		close(l.scheduleStop)
+		l.scheduleStop = nil
+	}
+	err := l.syncLocked()
+	if f := l.file; f != nil && !f.maint.wait(closeDrainTimeout) && err == nil {
+		err = fmt.Errorf("log: %d rotations still pending", f.maint.pending())
+	}
+	w := l.output
+	if f := l.file; f != nil && w != io.Writer(f) {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	l.file = nil
+	if f, ok := w.(*os.File); ok && (f == os.Stdout || f == os.Stderr) {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+		l.output = ioutil.Discard
+	}
+	return err
+}
+
+// Sync flushes buffered outputs (anything with Flush() error) and commits
+// the file to stable storage.
+func (l *Logger) Sync() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.syncLocked()
+}
+
+// Barrier returns once every entry logged before the call is in stable
+// storage and the rotations queued so far are archived, so that the files
+// can be read, or when ctx is done. Writes are synchronous, so this is
+// Sync followed by a sentinel job on the archival queue.
+func (l *Logger) Barrier(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- l.barrier()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Logger) barrier() error {
+	l.mutex.Lock()
+	o, f := l.output, l.file
+	l.mutex.Unlock()
+	if p, ok := o.(parentOutput); ok {
+		return p.parent().barrier()
+	}
+
+	err := l.Sync()
+	if f != nil {
+		archived := make(chan struct{})
+		f.maint.push(func() {
+			close(archived)
+		})
+		<-archived
+	}
+	return err
+}
+
+func (l *Logger) syncLocked() error {
+	var err error
+	if f, ok := l.output.(interface{ Flush() error }); ok {
+		err = f.Flush()
+	}
+	if s, ok := l.output.(interface{ Sync() error }); ok {
+		if serr := s.Sync(); err == nil && !isUnsupportedSync(serr) {
+			err = serr
+		}
+	}
+	return err
+}
+
+// isUnsupportedSync ignores fsync on terminals and pipes, which can't be synced.
+func isUnsupportedSync(err error) bool {
+	return err != nil && (errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP))
+}
+
+// SetExitFunc replaces os.Exit as called after a FATAL entry, mostly for
+// tests. The output is then only synced, the Logger stays usable.
+func (l *Logger) SetExitFunc(exit func(code int)) {
+	l.exitFunc = exit
+}
+
+func SetExitFunc(exit func(code int)) {
+	global.SetExitFunc(exit)
+}
+
+// fatal behaviors, see SetFatalBehavior
+const (
+	FatalExit    = iota // exit the process with status 1
+	FatalPanic          // panic, so deferred functions run
+	FatalLogOnly        // just log the entry and carry on
+)
+
+// SetFatalBehavior selects what happens after a FATAL entry is written,
+// FatalExit by default.
+func (l *Logger) SetFatalBehavior(behavior int) {
+	l.fatalBehavior = behavior
+}
+
+func SetFatalBehavior(behavior int) {
+	global.SetFatalBehavior(behavior)
+}
+
+// exit runs the fatal behavior once the FATAL entry is written, the output
+// is finalized before os.Exit and synced otherwise.
+func (l *Logger) exit() {
+	switch l.fatalBehavior {
+	case FatalPanic:
+		l.Sync()
+		msg, _ := l.fatalMsg.Load().(string)
+		panic("log: fatal: " + msg)
+	case FatalLogOnly:
+		l.Sync()
+		return
+	}
+
+	if l.exitFunc != nil {
+		// the process goes on, and so may the logging
+		l.Sync()
+		l.exitFunc(1)
+		return
+	}
+	l.closeOutput()
+	os.Exit(1)
+}
+
+// summary renders e.g. "logged 12034 entries: 3 error, 27 warn, 12004 info".
+func (l *Logger) summary() string {
+	var total uint64
+	var parts []string
+	for v := FATAL; v >= DEBUG; v-- {
+		n := atomic.LoadUint64(&l.counts[v])
+		total += n
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToLower(levelNames[v])))
+		}
+	}
+	if total == 0 {
+		return "logged 0 entries"
+	}
+	return fmt.Sprintf("logged %d entries: %s", total, strings.Join(parts, ", "))
+}
+
+func Counts() map[string]uint64 {
+	return global.Counts()
+}
+
+func Sync() error {
+	return global.Sync()
+}
+
+func Barrier(ctx context.Context) error {
+	return global.Barrier(ctx)
+}
+
+func Close() error {
+	return global.close(3)
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openFile reports whether the registry still holds name open.
+func openFile(name string) bool {
+	files.Lock()
+	defer files.Unlock()
+
+	_, ok := files.m[fileKey(name)]
+	return ok
+}
+
+func TestSetOutputReleasesTheFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	l.Info("to the file")
+	if !openFile(name) {
+		t.Fatal("the file is not open")
+	}
+	var out syncBuffer
+	l.SetOutput(&out)
+	if openFile(name) {
+		t.Error("SetOutput kept the file open")
+	}
+	l.Info("to the buffer")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, name); strings.Contains(got, "to the buffer") {
+		t.Errorf("file = %q", got)
+	}
+	if got := out.String(); !strings.Contains(got, "to the buffer") {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestCloseReleasesASharedFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	a := New(name, INFO, 0, 0)
+	b := New(name, INFO, 0, 0)
+	// closing one keeps the file open for the other
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !openFile(name) {
+		t.Fatal("closed the file still used by another Logger")
+	}
+	b.Info("still written")
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if openFile(name) {
+		t.Error("the file is still open")
+	}
+	if got := readFile(t, name); !strings.Contains(got, "still written") {
+		t.Errorf("file = %q", got)
+	}
+}
+
+func TestInjectedExitKeepsLogging(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+	code := 0
+	l.SetExitFunc(func(c int) { code = c })
+
+	l.Fatal("first")
+	l.Fatal("second")
+	if code != 1 {
+		t.Errorf("exit code = %d", code)
+	}
+	if got := readFile(t, name); !strings.Contains(got, "first") || !strings.Contains(got, "second") {
+		t.Errorf("file = %q, want both entries", got)
+	}
+}
+
+func TestFatalAtLevelOff(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(OFF)
+	var written string
+	l.SetExitFunc(func(int) { written = out.String() })
+
+	l.Error("dropped")
+	l.Fatalf("cannot start: %s", "no config")
+	if !strings.Contains(written, "FATAL cannot start: no config") {
+		t.Errorf("output before exit = %q", written)
+	}
+	if strings.Contains(written, "dropped") {
+		t.Errorf("output = %q, the level OFF let an ERROR through", written)
+	}
+}
+
+func TestBarrier(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, INFO, 0, 1000, WithMaxSize(2*KB))
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	child := l.Child("stage")
+	child.SetFormat("${message}\n")
+
+	for stage := 0; stage < 3; stage++ {
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					child.Infof("#s%d-g%d-%d", stage, g, i)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if err := child.Barrier(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// the next stage reads what this one logged
+		if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) > 0 {
+			t.Fatalf("stage %d: rotations still pending: %v", stage, tmp)
+		}
+		files, _ := filepath.Glob(name + "*")
+		seen := 0
+		for _, file := range files {
+			seen += strings.Count(readFile(t, file), fmt.Sprintf("#s%d-", stage))
+		}
+		if seen != 4*50 {
+			t.Errorf("stage %d: %d entries in the files, want %d", stage, seen, 4*50)
+		}
+	}
+}
+
+func TestBarrierHonorsTheContext(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "app.log"), INFO, 0, 0)
+	release := make(chan struct{})
+	l.file.maint.push(func() { <-release })
+	defer func() {
+		close(release)
+		l.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Barrier(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Barrier = %v behind a stuck archival, want DeadlineExceeded", err)
+	}
+}
+
+func TestMaintenanceWaitDoesNotLeak(t *testing.T) {
+	var m maintenance
+	if !m.wait(0) {
+		t.Fatal("wait on an empty queue timed out")
+	}
+	release := make(chan struct{})
+	m.push(func() { <-release })
+	m.push(func() {})
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		if m.wait(time.Millisecond) {
+			t.Fatal("wait returned behind a stuck job")
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after the timed out waits, %d before", after, before)
+	}
+
+	close(release)
+	if !m.wait(5 * time.Second) {
+		t.Fatal("wait timed out once the jobs could run")
+	}
+	// the queue drains and fills again
+	done := false
+	m.push(func() { done = true })
+	if !m.wait(5*time.Second) || !done {
+		t.Error("wait returned before the next job ran")
+	}
+	m.stop()
+}
@@ -0,0 +1,264 @@
+// Package compat mirrors the standard library log package on top of a
+// log.Logger, so migrating a file is a matter of swapping its import:
+//
+//	import log "github.com/seaguest/log/compat"
+//
+// The flags are translated into the format of the Logger, which gives the
+// same output as the standard library.
+package compat
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/seaguest/log"
+)
+
+// the flags of the standard library
+const (
+	Ldate         = stdlog.Ldate
+	Ltime         = stdlog.Ltime
+	Lmicroseconds = stdlog.Lmicroseconds
+	Llongfile     = stdlog.Llongfile
+	Lshortfile    = stdlog.Lshortfile
+	LUTC          = stdlog.LUTC
+	Lmsgprefix    = stdlog.Lmsgprefix
+	LstdFlags     = stdlog.LstdFlags
+)
+
+// Logger has the methods of the standard library *log.Logger, entries are
+// logged at INFO.
+type Logger struct {
+	mutex sync.Mutex
+	l     *log.Logger
+	out   io.Writer
+	flag  int
+}
+
+// New is log.New of the standard library.
+func New(out io.Writer, prefix string, flag int) *Logger {
+	l := log.New("", log.INFO, 0, 0)
+	l.SetOutput(out)
+	l.DisableColor()
+	return Wrap(l, out, prefix, flag)
+}
+
+// Wrap returns a Logger writing through l, e.g. to a rotated file, with
+// the format of the flags. out is returned by Writer.
+func Wrap(l *log.Logger, out io.Writer, prefix string, flag int) *Logger {
+	c := &Logger{l: l, out: out}
+	c.l.SetPrefix(prefix)
+	c.SetFlags(flag)
+	return c
+}
+
+// format returns the template equivalent to flag.
+func format(flag int) string {
+	var b strings.Builder
+	if flag&Lmsgprefix == 0 {
+		b.WriteString("${prefix}")
+	}
+	tag := "time"
+	if flag&LUTC != 0 {
+		tag = "time_utc"
+	}
+	if flag&Ldate != 0 {
+		b.WriteString("${" + tag + ":2006/01/02} ")
+	}
+	if flag&(Ltime|Lmicroseconds) != 0 {
+		layout := "15:04:05"
+		if flag&Lmicroseconds != 0 {
+			layout += ".000000"
+		}
+		b.WriteString("${" + tag + ":" + layout + "} ")
+	}
+	switch {
+	case flag&Lshortfile != 0:
+		b.WriteString("${short_file}:${line}: ")
+	case flag&Llongfile != 0:
+		b.WriteString("${long_file}:${line}: ")
+	}
+	if flag&Lmsgprefix != 0 {
+		b.WriteString("${prefix}")
+	}
+	b.WriteString("${message}\n")
+	return b.String()
+}
+
+// Logger returns the underlying Logger.
+func (c *Logger) Logger() *log.Logger {
+	return c.l
+}
+
+// Output logs s, calldepth being the frames to skip for the caller, 1
+// for the caller of Output.
+func (c *Logger) Output(calldepth int, s string) error {
+	return c.l.Log(log.INFO, calldepth+1, s)
+}
+
+func (c *Logger) Print(v ...interface{}) {
+	c.Output(2, fmt.Sprint(v...))
+}
+
+func (c *Logger) Printf(format string, v ...interface{}) {
+	c.Output(2, fmt.Sprintf(format, v...))
+}
+
+func (c *Logger) Println(v ...interface{}) {
+	c.Output(2, fmt.Sprintln(v...))
+}
+
+func (c *Logger) Fatal(v ...interface{}) {
+	c.Output(2, fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+func (c *Logger) Fatalf(format string, v ...interface{}) {
+	c.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func (c *Logger) Fatalln(v ...interface{}) {
+	c.Output(2, fmt.Sprintln(v...))
+	os.Exit(1)
+}
+
+func (c *Logger) Panic(v ...interface{}) {
+	s := fmt.Sprint(v...)
+	c.Output(2, s)
+	panic(s)
+}
+
+func (c *Logger) Panicf(format string, v ...interface{}) {
+	s := fmt.Sprintf(format, v...)
+	c.Output(2, s)
+	panic(s)
+}
+
+func (c *Logger) Panicln(v ...interface{}) {
+	s := fmt.Sprintln(v...)
+	c.Output(2, s)
+	panic(s)
+}
+
+func (c *Logger) Flags() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.flag
+}
+
+func (c *Logger) SetFlags(flag int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.flag = flag
+	c.l.SetFormat(format(flag))
+}
+
+func (c *Logger) Prefix() string {
+	return c.l.Prefix()
+}
+
+func (c *Logger) SetPrefix(prefix string) {
+	c.l.SetPrefix(prefix)
+}
+
+func (c *Logger) Writer() io.Writer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.out
+}
+
+func (c *Logger) SetOutput(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.out = w
+	c.l.SetOutput(w)
+}
+
+var std = New(os.Stderr, "", LstdFlags)
+
+// Default returns the Logger used by the package functions.
+func Default() *Logger {
+	return std
+}
+
+func Output(calldepth int, s string) error {
+	return std.Output(calldepth+1, s)
+}
+
+func Print(v ...interface{}) {
+	std.Output(2, fmt.Sprint(v...))
+}
+
+func Printf(format string, v ...interface{}) {
+	std.Output(2, fmt.Sprintf(format, v...))
+}
+
+func Println(v ...interface{}) {
+	std.Output(2, fmt.Sprintln(v...))
+}
+
+func Fatal(v ...interface{}) {
+	std.Output(2, fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+func Fatalf(format string, v ...interface{}) {
+	std.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func Fatalln(v ...interface{}) {
+	std.Output(2, fmt.Sprintln(v...))
+	os.Exit(1)
+}
+
+func Panic(v ...interface{}) {
+	s := fmt.Sprint(v...)
+	std.Output(2, s)
+	panic(s)
+}
+
+func Panicf(format string, v ...interface{}) {
+	s := fmt.Sprintf(format, v...)
+	std.Output(2, s)
+	panic(s)
+}
+
+func Panicln(v ...interface{}) {
+	s := fmt.Sprintln(v...)
+	std.Output(2, s)
+	panic(s)
+}
+
+func Flags() int {
+	return std.Flags()
+}
+
+func SetFlags(flag int) {
+	std.SetFlags(flag)
+}
+
+func Prefix() string {
+	return std.Prefix()
+}
+
+func SetPrefix(prefix string) {
+	std.SetPrefix(prefix)
+}
+
+func Writer() io.Writer {
+	return std.Writer()
+}
+
+func SetOutput(w io.Writer) {
+	std.SetOutput(w)
+}
@@ -0,0 +1,102 @@
+package compat
+
+import (
+	"bytes"
+	stdlog "log"
+	"regexp"
+	"testing"
+)
+
+var (
+	dates = regexp.MustCompile(`\d{4}/\d{2}/\d{2}`)
+	times = regexp.MustCompile(`\d{2}:\d{2}:\d{2}`)
+	micro = regexp.MustCompile(`\.\d{6} `)
+)
+
+// mask hides the date and time, which may differ between the two calls.
+func mask(s string) string {
+	s = dates.ReplaceAllString(s, "YYYY/MM/DD")
+	s = times.ReplaceAllString(s, "hh:mm:ss")
+	return micro.ReplaceAllString(s, ".uuuuuu ")
+}
+
+func TestFlagsMatchTheStandardLibrary(t *testing.T) {
+	flags := []int{
+		0,
+		Ldate,
+		Ltime,
+		Ldate | Ltime,
+		Ltime | Lmicroseconds,
+		LstdFlags | Lmicroseconds,
+		LstdFlags | LUTC,
+		Lshortfile,
+		Llongfile,
+		LstdFlags | Lshortfile,
+		Lshortfile | Llongfile,
+		Lmsgprefix,
+		LstdFlags | Lshortfile | Lmsgprefix,
+	}
+	for _, flag := range flags {
+		for _, prefix := range []string{"", "app: "} {
+			var want, got bytes.Buffer
+			std := stdlog.New(&want, prefix, flag)
+			c := New(&got, prefix, flag)
+			both := func(s string) {
+				std.Output(2, s)
+				c.Output(2, s)
+			}
+			both("hello")
+			both("with newline\n")
+			if mask(got.String()) != mask(want.String()) {
+				t.Errorf("flags %d, prefix %q:\n got %q\nwant %q", flag, prefix, got.String(), want.String())
+			}
+		}
+	}
+}
+
+func TestPrintMethods(t *testing.T) {
+	var want, got bytes.Buffer
+	std := stdlog.New(&want, "p ", Lshortfile)
+	c := New(&got, "p ", Lshortfile)
+	std.Print("a", 1, 2, "b")
+	c.Print("a", 1, 2, "b")
+	std.Printf("%d items", 3)
+	c.Printf("%d items", 3)
+	std.Println("a", 1, 2, "b")
+	c.Println("a", 1, 2, "b")
+
+	// the line numbers differ by one, the rest must not
+	lines := regexp.MustCompile(`:\d+:`)
+	if g, w := lines.ReplaceAllString(got.String(), ":N:"), lines.ReplaceAllString(want.String(), ":N:"); g != w {
+		t.Errorf("got %q\nwant %q", g, w)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var got bytes.Buffer
+	c := New(&got, "", 0)
+	defer func() {
+		if r := recover(); r != "boom 3" {
+			t.Errorf("recovered %v, want boom 3", r)
+		}
+		if got.String() != "boom 3\n" {
+			t.Errorf("output = %q", got.String())
+		}
+	}()
+	c.Panicf("boom %d", 3)
+}
+
+func TestAccessors(t *testing.T) {
+	var out, other bytes.Buffer
+	c := New(&out, "a ", Ldate)
+	if c.Flags() != Ldate || c.Prefix() != "a " || c.Writer() != &out {
+		t.Errorf("Flags, Prefix, Writer = %d, %q, %v", c.Flags(), c.Prefix(), c.Writer())
+	}
+	c.SetFlags(0)
+	c.SetPrefix("b ")
+	c.SetOutput(&other)
+	c.Print("moved")
+	if out.Len() != 0 || other.String() != "b moved\n" || c.Writer() != &other {
+		t.Errorf("outputs = %q, %q", out.String(), other.String())
+	}
+}
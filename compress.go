@@ -0,0 +1,175 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Codec compresses rotated files, see SetCompression.
+type Codec interface {
+	// Compress writes the compressed content of src to dst.
+	Compress(dst, src string) error
+	// Extension is appended to the archive name, e.g. ".gz".
+	Extension() string
+}
+
+// Decompressor is implemented by the codecs able to read their archives
+// back, e.g. for VerifyLogFile.
+type Decompressor interface {
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip is the built-in gzip codec.
+var Gzip Codec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Extension() string {
+	return ".gz"
+}
+
+func (gzipCodec) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (gzipCodec) Compress(dst, src string) error {
+	return CompressFile(dst, src, func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	})
+}
+
+// CompressFile writes src through the writer returned by wrap into dst.
+// dst only appears once complete and synced, so a crash never leaves a
+// truncated archive. It's the building block of codecs.
+func CompressFile(dst, src string, wrap func(w io.Writer) (io.WriteCloser, error)) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	part := dst + partSuffix
+	out, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(part, dst)
+		}
+		if err != nil {
+			os.Remove(part)
+		}
+	}()
+
+	w, err := wrap(out)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+var extensions = struct {
+	sync.RWMutex
+	list   []string
+	codecs map[string]Codec // by extension, see RegisterCodec
+}{list: []string{".gz"}, codecs: map[string]Codec{".gz": Gzip}}
+
+// RegisterExtension makes rotation recognize archives ending with ext,
+// e.g. compressed by a codec which was used before. SetCompression
+// registers the extension of its codec.
+func RegisterExtension(ext string) {
+	extensions.Lock()
+	defer extensions.Unlock()
+
+	for _, e := range extensions.list {
+		if e == ext {
+			return
+		}
+	}
+	extensions.list = append(extensions.list, ext)
+	// longest first, so ".tar.gz" wins over ".gz"
+	sort.Slice(extensions.list, func(i, j int) bool {
+		return len(extensions.list[i]) > len(extensions.list[j])
+	})
+}
+
+// RegisterCodec registers the extension of c and, when c is also a
+// Decompressor, how to read its archives back. SetCompression registers
+// its codec, codec packages register themselves.
+func RegisterCodec(c Codec) {
+	RegisterExtension(c.Extension())
+	extensions.Lock()
+	defer extensions.Unlock()
+
+	extensions.codecs[c.Extension()] = c
+}
+
+// decompressor returns how to read the archives ending with ext.
+func decompressor(ext string) (Decompressor, bool) {
+	extensions.RLock()
+	defer extensions.RUnlock()
+
+	d, ok := extensions.codecs[ext].(Decompressor)
+	return d, ok
+}
+
+// splitExtension cuts a registered extension off name.
+func splitExtension(name string) (string, string) {
+	extensions.RLock()
+	defer extensions.RUnlock()
+
+	for _, ext := range extensions.list {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), ext
+		}
+	}
+	return name, ""
+}
+
+// SetCompression compresses rotated files with c, e.g. Gzip, nil disables
+// compression. Archives are compressed in the background after rotation,
+// keeping their modification time.
+func (l *Logger) SetCompression(c Codec) {
+	if c != nil {
+		RegisterCodec(c)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.codec = c
+}
+
+func SetCompression(c Codec) {
+	global.SetCompression(c)
+}
+
+// compressArchive replaces name by its compressed version.
+func compressArchive(c Codec, name string) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	dst := name + c.Extension()
+	if err := c.Compress(dst, name); err != nil {
+		return err
+	}
+	if err := os.Chtimes(dst, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,211 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasttemplate"
+)
+
+// Config mirrors the logger settings, typically loaded from a config file
+// and applied at once with ApplyConfig. In JSON the keys are snake_case,
+// the level is a name like "warn", the size is in bytes or a string like
+// "100MB" and the age a duration like "168h".
+type Config struct {
+	Level            Level         `json:"level"`
+	File             string        `json:"file"`     // empty logs to stdout
+	MaxSize          Size          `json:"max_size"` // per file, 0 for no limit
+	MaxAge           time.Duration `json:"max_age"`  // of the archives, 0 for no limit
+	Backups          int           `json:"backups"`
+	Format           string        `json:"format"` // template, empty means the default format
+	JSON             bool          `json:"json"`
+	Color            bool          `json:"color"`
+	Prefix           string        `json:"prefix"`
+	ArchiveDir       string        `json:"archive_dir"`
+	MaxMessageLength int           `json:"max_message_length"`
+	LevelStyle       int           `json:"level_style"`
+	ColorScope       int           `json:"color_scope"`
+}
+
+// MarshalJSON renders the max age as a duration string, e.g. "168h0m0s".
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	return json.Marshal(struct {
+		plain
+		MaxAge string `json:"max_age"`
+	}{plain(c), c.MaxAge.String()})
+}
+
+// UnmarshalJSON accepts the level as a name or a number, the size as
+// bytes or a string with a unit, e.g. "512KB", and the age as a string
+// for time.ParseDuration. Missing keys leave the fields untouched, unknown
+// keys are an error.
+func (c *Config) UnmarshalJSON(b []byte) error {
+	type plain Config
+	aux := struct {
+		*plain
+		Level   json.RawMessage `json:"level"`
+		MaxSize json.RawMessage `json:"max_size"`
+		MaxAge  json.RawMessage `json:"max_age"`
+	}{plain: (*plain)(c)}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&aux); err != nil {
+		return fmt.Errorf("log: config: %v", err)
+	}
+	if aux.Level != nil {
+		if err := c.Level.UnmarshalJSON(aux.Level); err != nil {
+			return fmt.Errorf("log: config level: unknown level %s", aux.Level)
+		}
+	}
+	if aux.MaxSize != nil {
+		if err := c.MaxSize.UnmarshalJSON(aux.MaxSize); err != nil {
+			return fmt.Errorf("log: config max_size: invalid size %s", aux.MaxSize)
+		}
+	}
+	if aux.MaxAge != nil {
+		var s string
+		if err := json.Unmarshal(aux.MaxAge, &s); err != nil {
+			return fmt.Errorf("log: config max_age: invalid duration %s", aux.MaxAge)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("log: config max_age: %v", err)
+		}
+		c.MaxAge = d
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	if c.Level < DEBUG || c.Level > OFF {
+		return fmt.Errorf("log: invalid level %d", c.Level)
+	}
+	if c.MaxSize < 0 {
+		return fmt.Errorf("log: invalid max size %d", c.MaxSize)
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("log: invalid max age %s", c.MaxAge)
+	}
+	if c.Backups < 0 {
+		return fmt.Errorf("log: invalid backups %d", c.Backups)
+	}
+	if c.Format != "" {
+		if _, err := fasttemplate.NewTemplate(c.Format, "${", "}"); err != nil {
+			return fmt.Errorf("log: invalid format: %v", err)
+		}
+	}
+	if c.LevelStyle < LevelFull || c.LevelStyle > LevelPadded {
+		return fmt.Errorf("log: invalid level style %d", c.LevelStyle)
+	}
+	if c.ColorScope < ColorLevelOnly || c.ColorScope > ColorFullLine {
+		return fmt.Errorf("log: invalid color scope %d", c.ColorScope)
+	}
+	return nil
+}
+
+// ApplyConfig validates c as a whole and then applies it. The format
+// settings are swapped in one snapshot, so every entry is rendered
+// entirely with either the old or the new ones; the file, the rotation
+// settings and then the level are switched under the lock. On error
+// nothing is changed, including when the new file can't be opened.
+func (l *Logger) ApplyConfig(c Config) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	l.lazyInit()
+	format := c.Format
+	if format == "" {
+		format = defaultFormat
+	}
+	if c.Format != "" {
+		tf, err := l.newTemplate(c.Format)
+		if err != nil {
+			return err
+		}
+		if err := l.checkTags(tf); err != nil {
+			return err
+		}
+	}
+
+	defer l.notifyColor()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if c.File != l.pattern {
+		pattern, filename := l.pattern, l.filename
+		l.pattern, l.filename = c.File, expandFilename(c.File, time.Now())
+		if l.filename != "" {
+			if err := l.open(); err != nil {
+				l.pattern, l.filename = pattern, filename
+				return err
+			}
+		} else {
+			if l.file != nil {
+				l.file.Close()
+				l.file = nil
+			}
+			l.setOutputLocked(l.console())
+		}
+	}
+
+	l.maxsize = int(c.MaxSize)
+	l.maxAge = c.MaxAge
+	l.backups = c.Backups
+	l.archiveDir = c.ArchiveDir
+
+	l.reformats.Lock()
+	l.json = c.JSON
+	l.prefix = c.Prefix
+	l.maxMessage = c.MaxMessageLength
+	l.levelStyle = c.LevelStyle
+	l.colorScope = c.ColorScope
+	l.colorOn = c.Color
+	if c.Color {
+		l.color.Enable()
+	} else {
+		l.color.Disable()
+	}
+	l.template.Store(l.mustTemplate(format))
+	l.reformats.Unlock()
+
+	atomic.StoreInt32(&l.level, int32(c.Level))
+	return nil
+}
+
+// Config returns the current effective configuration.
+func (l *Logger) Config() Config {
+	l.lazyInit()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.reformats.Lock()
+	defer l.reformats.Unlock()
+
+	tf := l.template.Load().(*textFormat)
+	return Config{
+		Level:            l.Level(),
+		File:             l.pattern,
+		MaxSize:          Size(l.maxsize),
+		MaxAge:           l.maxAge,
+		Backups:          l.backups,
+		Format:           tf.src,
+		JSON:             tf.json,
+		Color:            tf.color,
+		Prefix:           tf.prefix,
+		ArchiveDir:       l.archiveDir,
+		MaxMessageLength: tf.maxMsg,
+		LevelStyle:       l.levelStyle,
+		ColorScope:       l.colorScope,
+	}
+}
+
+func ApplyConfig(c Config) error {
+	return global.ApplyConfig(c)
+}
+
+func GetConfig() Config {
+	return global.Config()
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApplyConfigWhileLogging(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	text := Config{Level: INFO, Format: "${prefix} ${level} ${message}\n", Prefix: "text", LevelStyle: LevelShort}
+	structured := Config{Level: INFO, JSON: true, Prefix: "json", MaxMessageLength: 3}
+	if err := l.ApplyConfig(text); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			l.ApplyConfig(structured)
+			l.ApplyConfig(text)
+		}
+	}()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				l.Info("hello")
+			}
+		}()
+	}
+	wg.Wait()
+	<-done
+
+	for _, line := range out.lines() {
+		if line == "text I hello" {
+			continue
+		}
+		var v struct {
+			Level  string `json:"level"`
+			Prefix string `json:"prefix"`
+			Msg    string `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("line %q is neither format: %v", line, err)
+		}
+		if v.Level != "info" || v.Prefix != "json" || v.Msg != "hel...(truncated)" {
+			t.Fatalf("line %q mixes settings", line)
+		}
+	}
+}
+
+func TestConfigKeepsMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, INFO, 0, 3, WithMaxSize(512*KB))
+	defer l.Close()
+	c := l.Config()
+	if c.MaxSize != 512*KB {
+		t.Fatalf("MaxSize = %v, want 512KB", c.MaxSize)
+	}
+	if err := l.ApplyConfig(c); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		l.Info("hello")
+	}
+	l.Sync()
+	if archives, _ := filepath.Glob(name + ".*"); len(archives) > 0 {
+		t.Errorf("rotated below the max size: %v", archives)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Config
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.MaxSize != 512*KB {
+		t.Errorf("round trip of %s: MaxSize = %v", b, back.MaxSize)
+	}
+}
+
+func TestConfigMaxSizeJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Size
+	}{
+		{`0`, 0},
+		{`1000`, 1000},
+		{`"512KB"`, 512 * KB},
+		{`"100MB"`, 100 * MB},
+		{`"1.5k"`, 1536},
+	}
+	for _, tt := range tests {
+		var c Config
+		if err := json.Unmarshal([]byte(`{"max_size":`+tt.in+`}`), &c); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+		} else if c.MaxSize != tt.want {
+			t.Errorf("%s: MaxSize = %d, want %d", tt.in, c.MaxSize, tt.want)
+		}
+	}
+	for _, in := range []string{`-1`, `"lots"`, `true`} {
+		var c Config
+		if err := json.Unmarshal([]byte(`{"max_size":`+in+`}`), &c); err == nil {
+			t.Errorf("%s: no error", in)
+		}
+	}
+}
+
+func TestConfigJSONRoundTrip(t *testing.T) {
+	var c Config
+	in := `{"level":"warn","max_size":"100MB","max_age":"168h","backups":7}`
+	if err := json.Unmarshal([]byte(in), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != WARN || c.MaxSize != 100*MB || c.MaxAge != 168*time.Hour || c.Backups != 7 {
+		t.Fatalf("Unmarshal(%s) = %+v", in, c)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"level":"warn"`, `"max_size":"100MB"`, `"max_age":"168h0m0s"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("Marshal = %s, want %s", b, want)
+		}
+	}
+	var back Config
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != c {
+		t.Errorf("round trip = %+v, want %+v", back, c)
+	}
+}
+
+func TestConfigJSONErrors(t *testing.T) {
+	tests := []struct {
+		in, field string
+	}{
+		{`{"level":"loud"}`, "level"},
+		{`{"max_size":"big"}`, "max_size"},
+		{`{"max_age":"a week"}`, "max_age"},
+		{`{"max_age":168}`, "max_age"},
+		{`{"max_sise":"1MB"}`, "max_sise"},
+		{`{"backups":"7"}`, "backups"},
+	}
+	for _, tt := range tests {
+		var c Config
+		err := json.Unmarshal([]byte(tt.in), &c)
+		if err == nil || !strings.Contains(err.Error(), tt.field) {
+			t.Errorf("Unmarshal(%s) = %v, want an error naming %s", tt.in, err, tt.field)
+		}
+	}
+}
+
+func TestMaxAgePrunesArchives(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	old := time.Now().Add(-48 * time.Hour)
+	for i := 1; i <= 2; i++ {
+		archive := filepath.Join(dir, archiveName(SuffixTimestamp, "app.log", i, old))
+		if err := ioutil.WriteFile(archive, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := New(name, INFO, 0, 10, WithMaxAge(24*time.Hour))
+	defer l.Close()
+	l.Info("hello")
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, _ := filepath.Glob(name + ".*")
+	if len(archives) != 1 || readFile(t, archives[0]) == "old\n" {
+		t.Errorf("archives = %v, want only the fresh one", archives)
+	}
+}
@@ -0,0 +1,136 @@
+package log
+
+import "context"
+
+type valuesKey struct{}
+
+// ContextWithValues returns a copy of ctx carrying kv, alternating keys and
+// values, added as fields to the entries logged with it by the *Ctx
+// methods, e.g. the tenant of a request. They override the values of
+// the same keys already in ctx. A dangling value is kept under "!BADKEY".
+func ContextWithValues(ctx context.Context, kv ...string) context.Context {
+	parent, _ := ctx.Value(valuesKey{}).(Fields)
+	values := make(Fields, len(parent)+(len(kv)+1)/2)
+	for k, v := range parent {
+		values[k] = v
+	}
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			values[badKey] = kv[i]
+			break
+		}
+		values[kv[i]] = kv[i+1]
+	}
+	return context.WithValue(ctx, valuesKey{}, values)
+}
+
+// SetContextExtractor sets a function returning the fields of the entries
+// logged with ctx by the *Ctx methods, for values put in the context by
+// other code, e.g. a tracing middleware. For a key set at several layers
+// the entry's fields win over the values of ContextWithValues, which win
+// over the extractor's.
+func (l *Logger) SetContextExtractor(fn func(ctx context.Context) Fields) {
+	l.ctxExtractor = fn
+}
+
+func SetContextExtractor(fn func(ctx context.Context) Fields) {
+	global.SetContextExtractor(fn)
+}
+
+// WithContext returns a copy of the entry logged with ctx, like the *Ctx
+// methods: l.WithField("user", id).WithContext(ctx).Info("login").
+func (e *Entry) WithContext(ctx context.Context) *Entry {
+	c := e.mutable()
+	c.ctx = ctx
+	return c
+}
+
+// contextFields returns fields merged with the ones of ctx, see
+// SetContextExtractor for the precedence.
+func (l *Logger) contextFields(ctx context.Context, fields Fields) Fields {
+	var extracted Fields
+	if l.ctxExtractor != nil {
+		extracted = l.ctxExtractor(ctx)
+	}
+	values, _ := ctx.Value(valuesKey{}).(Fields)
+	if len(extracted) == 0 && len(values) == 0 {
+		return fields
+	}
+	merged := make(Fields, len(extracted)+len(values)+len(fields))
+	for _, layer := range []Fields{extracted, values, fields} {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// logCtx logs with the context of the caller. Writes are synchronous, so
+// ctx can't cancel them; it travels with the entry for field extraction.
+func (l *Logger) logCtx(ctx context.Context, v Level, format string, args []interface{}) {
+	l.emit(Entry{Level: v, ctx: ctx}, 3, format, args)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, i ...interface{}) {
+	l.logCtx(ctx, DEBUG, "", i)
+}
+
+func (l *Logger) DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	l.logCtx(ctx, DEBUG, format, args)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, i ...interface{}) {
+	l.logCtx(ctx, INFO, "", i)
+}
+
+func (l *Logger) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	l.logCtx(ctx, INFO, format, args)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, i ...interface{}) {
+	l.logCtx(ctx, WARN, "", i)
+}
+
+func (l *Logger) WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	l.logCtx(ctx, WARN, format, args)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, i ...interface{}) {
+	l.logCtx(ctx, ERROR, "", i)
+}
+
+func (l *Logger) ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	l.logCtx(ctx, ERROR, format, args)
+}
+
+func DebugCtx(ctx context.Context, i ...interface{}) {
+	global.logCtx(ctx, DEBUG, "", i)
+}
+
+func DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	global.logCtx(ctx, DEBUG, format, args)
+}
+
+func InfoCtx(ctx context.Context, i ...interface{}) {
+	global.logCtx(ctx, INFO, "", i)
+}
+
+func InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	global.logCtx(ctx, INFO, format, args)
+}
+
+func WarnCtx(ctx context.Context, i ...interface{}) {
+	global.logCtx(ctx, WARN, "", i)
+}
+
+func WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	global.logCtx(ctx, WARN, format, args)
+}
+
+func ErrorCtx(ctx context.Context, i ...interface{}) {
+	global.logCtx(ctx, ERROR, "", i)
+}
+
+func ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	global.logCtx(ctx, ERROR, format, args)
+}
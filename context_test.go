@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type tenantKey struct{}
+
+func TestContextWithValues(t *testing.T) {
+	ctx := ContextWithValues(context.Background(), "tenant", "acme", "region", "eu")
+	ctx = ContextWithValues(ctx, "tenant", "globex", "dangling")
+	values, _ := ctx.Value(valuesKey{}).(Fields)
+	want := Fields{"tenant": "globex", "region": "eu", badKey: "dangling"}
+	if len(values) != len(want) {
+		t.Fatalf("values = %v, want %v", values, want)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("%s = %v, want %v", k, values[k], v)
+		}
+	}
+}
+
+func TestContextPrecedence(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}${fields}\n")
+	l.SetContextExtractor(func(ctx context.Context) Fields {
+		return Fields{"tenant": ctx.Value(tenantKey{}), "user": "extracted", "trace": "t1"}
+	})
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = ContextWithValues(ctx, "user", "values", "region", "eu")
+
+	l.InfoCtx(ctx, "ctx")
+	l.WithField("user", "entry").WithContext(ctx).Info("entry")
+	l.With().Str("trace", "typed").WithContext(ctx).Info("typed")
+	l.Info("no ctx")
+
+	want := []string{
+		"ctx region=eu tenant=acme trace=t1 user=values",
+		"entry region=eu tenant=acme trace=t1 user=entry",
+		"typed region=eu tenant=acme trace=typed user=values",
+		"no ctx",
+	}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestGlobalContextExtractor(t *testing.T) {
+	saved := global
+	defer func() { global = saved }()
+	var out syncBuffer
+	global = newTestLogger(&out)
+	global.SetFormat("${message}${fields}\n")
+
+	SetContextExtractor(func(ctx context.Context) Fields {
+		return Fields{"tenant": ctx.Value(tenantKey{})}
+	})
+	InfoCtx(context.WithValue(context.Background(), tenantKey{}, "acme"), "picked up")
+	if got, want := out.String(), "picked up tenant=acme\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
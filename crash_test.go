@@ -0,0 +1,70 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrashFile(t *testing.T) {
+	dir := t.TempDir()
+	var out syncBuffer
+	l := New("", INFO, 0, 0, WithCrashFile(filepath.Join(dir, "crash.log")))
+	l.SetOutput(&out)
+	l.SetFormat("${level} ${message}${fields}\n")
+	l.SetFatalBehavior(FatalLogOnly)
+
+	// enough goroutines for way more than 4 KiB of stacks
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-stop
+		}()
+	}
+	l.Fatal("crashed")
+	close(stop)
+	wg.Wait()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "crash.*.log"))
+	if len(files) != 1 || !regexp.MustCompile(`crash\.\d{8}-\d{6}\.log$`).MatchString(files[0]) {
+		t.Fatalf("crash files %q", files)
+	}
+	dump := readFile(t, files[0])
+	if !regexp.MustCompile(`^\d{4}-\d\d-\d\d .* FATAL .*crash_test\.go:\d+: crashed\n\n`).MatchString(dump) {
+		t.Errorf("crash file starts with %q", dump[:strings.IndexByte(dump, '\n')])
+	}
+	if n := strings.Count(dump, "TestCrashFile.func1"); n < 200 || len(dump) <= 4<<10 {
+		t.Errorf("crash file holds %d bytes and %d of the goroutines, want all of them", len(dump), n)
+	}
+
+	// the log only points at it
+	if got := out.String(); strings.Contains(got, "goroutine ") || !strings.Contains(got, files[0]) || strings.Count(got, "\n") != 1 {
+		t.Errorf("output = %q, want a single line naming the crash file", got)
+	}
+}
+
+func TestCrashFileFailing(t *testing.T) {
+	var out syncBuffer
+	var errs []error
+	l := New("", INFO, 0, 0, WithCrashFile(filepath.Join(t.TempDir(), "missing", "crash.log")))
+	l.SetOutput(&out)
+	l.SetFormat("${level} ${message}\n")
+	l.SetFatalBehavior(FatalLogOnly)
+	l.SetErrorHandler(func(err error) { errs = append(errs, err) })
+	l.Fatal("crashed")
+
+	// the stack goes to the entry as without a crash file
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Errorf("errors %v, want the missing directory", errs)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "FATAL crashed\ngoroutine ") {
+		t.Errorf("output = %q, want the stack", got)
+	}
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFormatDelta(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "+0"},
+		{-time.Second, "+0"},
+		{999, "+0µs"},
+		{1500 * time.Nanosecond, "+1µs"},
+		{999 * time.Microsecond, "+999µs"},
+		{12*time.Millisecond + 700*time.Microsecond, "+12ms"},
+		{time.Second, "+1.000s"},
+		{90*time.Second + 2*time.Millisecond, "+90.002s"},
+	}
+	for _, tt := range tests {
+		if got := formatDelta(tt.d); got != tt.want {
+			t.Errorf("formatDelta(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestDeltaTag(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${delta} ${message}\n")
+	l.Info("first")
+	time.Sleep(15 * time.Millisecond)
+	l.Info("second")
+	l.Debug("third")
+
+	lines := out.lines()
+	if len(lines) != 3 || lines[0] != "+0 first" {
+		t.Fatalf("lines = %q, want +0 first", lines)
+	}
+	m := regexp.MustCompile(`^\+(\d+)ms second$`).FindStringSubmatch(lines[1])
+	if m == nil {
+		t.Fatalf("line %q, want the delta in ms", lines[1])
+	}
+	if ms, _ := strconv.Atoi(m[1]); ms < 15 {
+		t.Errorf("line %q, want at least +15ms", lines[1])
+	}
+	if !regexp.MustCompile(`^\+\d+(µs|ms) third$`).MatchString(lines[2]) {
+		t.Errorf("line %q", lines[2])
+	}
+}
+
+func TestDeltaJSON(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.SetJSONConfig(JSONConfig{Delta: true})
+	l.Info("first")
+	time.Sleep(5 * time.Millisecond)
+	l.Info("second")
+
+	var deltas []float64
+	for _, line := range out.lines() {
+		var v struct {
+			DeltaMs *float64 `json:"delta_ms"`
+		}
+		if err := json.Unmarshal([]byte(line), &v); err != nil || v.DeltaMs == nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		deltas = append(deltas, *v.DeltaMs)
+	}
+	if len(deltas) != 2 || deltas[0] != 0 || deltas[1] < 5 {
+		t.Errorf("delta_ms = %v, want 0 then at least 5", deltas)
+	}
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const diagnosticsSize = 64
+
+// Diagnostic is an internal event of the logger itself: a rotation, a
+// reopen, an error handled on its own or a dropped entry.
+type Diagnostic struct {
+	Time    time.Time
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return d.Time.Format(timeLocal) + " " + d.Message
+}
+
+// diagnostics keeps the last diagnosticsSize events. It has its own lock
+// and never logs, so it can be recorded from anywhere, even with the
+// logger or the file locked.
+type diagnostics struct {
+	mutex  sync.Mutex
+	events [diagnosticsSize]Diagnostic
+	next   int
+	full   bool
+}
+
+func (d *diagnostics) add(msg string) {
+	d.mutex.Lock()
+	d.events[d.next] = Diagnostic{time.Now(), msg}
+	d.next++
+	if d.next == len(d.events) {
+		d.next = 0
+		d.full = true
+	}
+	d.mutex.Unlock()
+}
+
+// list returns the recorded events, oldest first.
+func (d *diagnostics) list() []Diagnostic {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var events []Diagnostic
+	if d.full {
+		events = append(events, d.events[d.next:]...)
+	}
+	return append(events, d.events[:d.next]...)
+}
+
+// diag records an internal event, it must never go through emit.
+func (l *Logger) diag(format string, args ...interface{}) {
+	l.diagnostics.add(fmt.Sprintf(format, args...))
+}
+
+// DumpDiagnostics writes the last internal events of the logger to w, one
+// per line, oldest first.
+func (l *Logger) DumpDiagnostics(w io.Writer) error {
+	for _, d := range l.diagnostics.list() {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func DumpDiagnostics(w io.Writer) error {
+	return global.DumpDiagnostics(w)
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsKeepTheLast(t *testing.T) {
+	var d diagnostics
+	if got := d.list(); len(got) != 0 {
+		t.Fatalf("list = %v, want none", got)
+	}
+	for i := 0; i < diagnosticsSize+10; i++ {
+		d.add("event " + strconv.Itoa(i))
+	}
+	got := d.list()
+	if len(got) != diagnosticsSize || got[0].Message != "event 10" || got[len(got)-1].Message != "event "+strconv.Itoa(diagnosticsSize+9) {
+		t.Errorf("list holds %d events, from %q to %q", len(got), got[0].Message, got[len(got)-1].Message)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Time.Before(got[i-1].Time) {
+			t.Fatalf("events out of order at %d", i)
+		}
+	}
+}
+
+func TestDiagnosticsOfALogger(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 5)
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.SetErrorHandler(func(error) {})
+	l.Info("m")
+	l.Rotate()
+	w := &failingWriter{}
+	w.fail(errors.New("disk full"))
+	l.AddOutputLevel(w, INFO)
+	l.Info("m")
+
+	var dump bytes.Buffer
+	if err := l.DumpDiagnostics(&dump); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(dump.String(), "\n"), "\n")
+	stamp := regexp.MustCompile(`^\d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? `)
+	var rotated, failed bool
+	for _, line := range lines {
+		if !stamp.MatchString(line) {
+			t.Errorf("line %q misses its time", line)
+		}
+		rotated = rotated || strings.Contains(line, "rotated "+name)
+		failed = failed || strings.Contains(line, "error: disk full")
+	}
+	if !rotated || !failed {
+		t.Errorf("diagnostics = %q, want the rotation and the error", lines)
+	}
+	if st := l.Stats(); len(st.Diagnostics) != len(lines) {
+		t.Errorf("Stats has %d diagnostics, the dump %d", len(st.Diagnostics), len(lines))
+	}
+
+	// never logged through the Logger
+	if got := readFile(t, name); strings.Contains(got, "disk full") {
+		t.Errorf("file = %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxDumpDepth = 10
+
+// Dump logs v at DEBUG as an indented tree prefixed with label. Nothing is
+// rendered when DEBUG is disabled, cycles and deep nesting are cut short.
+func (l *Logger) Dump(label string, v interface{}) {
+	if !l.dumpEnabled() {
+		return
+	}
+	l.emit(Entry{Level: DEBUG}, 2, "", []interface{}{label, ": ", dump(v)})
+}
+
+func Dump(label string, v interface{}) {
+	if !global.dumpEnabled() {
+		return
+	}
+	global.emit(Entry{Level: DEBUG}, 2, "", []interface{}{label, ": ", dump(v)})
+}
+
+func (l *Logger) dumpEnabled() bool {
+	return DEBUG >= l.Level() || (l.ring != nil && DEBUG >= l.ringLevel)
+}
+
+type dumper struct {
+	buf     bytes.Buffer
+	visited map[uintptr]bool
+}
+
+func dump(v interface{}) string {
+	d := &dumper{visited: make(map[uintptr]bool)}
+	d.dump(reflect.ValueOf(v), 0)
+	return d.buf.String()
+}
+
+func (d *dumper) indent(depth int) {
+	d.buf.WriteString(strings.Repeat("  ", depth))
+}
+
+func (d *dumper) dump(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		d.buf.WriteString("<nil>")
+		return
+	}
+	if depth > maxDumpDepth {
+		d.buf.WriteString("...")
+		return
+	}
+	if v.CanInterface() {
+		switch s := v.Interface().(type) {
+		case error:
+			if v.Kind() != reflect.Ptr || !v.IsNil() {
+				d.buf.WriteString(strconv.Quote(s.Error()))
+				return
+			}
+		case fmt.Stringer:
+			if v.Kind() != reflect.Ptr || !v.IsNil() {
+				d.buf.WriteString(s.String())
+				return
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			d.buf.WriteString("nil")
+			return
+		}
+		// only the current path is tracked, shared (non-cyclic) values are fine
+		ptr := v.Pointer()
+		if v.Kind() != reflect.Slice || v.Len() > 0 {
+			if d.visited[ptr] {
+				d.buf.WriteString("<cycle>")
+				return
+			}
+			d.visited[ptr] = true
+			defer delete(d.visited, ptr)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		d.buf.WriteByte('&')
+		d.dump(v.Elem(), depth)
+	case reflect.Interface:
+		d.dump(v.Elem(), depth)
+	case reflect.Struct:
+		d.buf.WriteString(v.Type().String())
+		d.buf.WriteString("{\n")
+		for i := 0; i < v.NumField(); i++ {
+			d.indent(depth + 1)
+			d.buf.WriteString(v.Type().Field(i).Name)
+			d.buf.WriteString(": ")
+			d.dump(v.Field(i), depth+1)
+			d.buf.WriteString(",\n")
+		}
+		d.indent(depth)
+		d.buf.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		d.buf.WriteString(v.Type().String())
+		if v.Len() == 0 {
+			d.buf.WriteString("{}")
+			return
+		}
+		d.buf.WriteString("{\n")
+		for i := 0; i < v.Len(); i++ {
+			d.indent(depth + 1)
+			d.dump(v.Index(i), depth+1)
+			d.buf.WriteString(",\n")
+		}
+		d.indent(depth)
+		d.buf.WriteByte('}')
+	case reflect.Map:
+		d.buf.WriteString(v.Type().String())
+		if v.Len() == 0 {
+			d.buf.WriteString("{}")
+			return
+		}
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			// k may come from an unexported field, it can't go through Interface
+			key := &dumper{visited: d.visited}
+			key.dump(k, depth+1)
+			names[i] = key.buf.String()
+		}
+		sort.Sort(byName{keys, names})
+		d.buf.WriteString("{\n")
+		for i, k := range keys {
+			d.indent(depth + 1)
+			d.buf.WriteString(names[i])
+			d.buf.WriteString(": ")
+			d.dump(v.MapIndex(k), depth+1)
+			d.buf.WriteString(",\n")
+		}
+		d.indent(depth)
+		d.buf.WriteByte('}')
+	case reflect.String:
+		d.buf.WriteString(strconv.Quote(v.String()))
+	case reflect.Bool:
+		d.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		d.buf.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		d.buf.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	default:
+		// chan, func, unsafe.Pointer
+		fmt.Fprintf(&d.buf, "%s(%#x)", v.Type(), v.Pointer())
+	}
+}
+
+type byName struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (s byName) Len() int           { return len(s.keys) }
+func (s byName) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s byName) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type hidden struct {
+	m     map[string]int
+	keyed map[point]string
+	err   error
+}
+
+type point struct{ x, y int }
+
+func TestDump(t *testing.T) {
+	cyclic := &node{Name: "a"}
+	cyclic.Next = &node{Name: "b", Next: cyclic}
+	shared := &node{Name: "shared"}
+	tests := []struct {
+		name string
+		v    interface{}
+		want []string
+	}{
+		{"nil", nil, []string{"<nil>"}},
+		{"scalars", []interface{}{1, "s", true, 1.5, uint8(2)}, []string{"1,", `"s",`, "true,", "1.5,", "2,"}},
+		{"sorted map", map[string]int{"b": 2, "a": 1}, []string{"map[string]int{\n  \"a\": 1,\n  \"b\": 2,\n}"}},
+		{"empty", map[string]int{}, []string{"map[string]int{}"}},
+		{"error", errors.New("boom"), []string{`"boom"`}},
+		{"cycle", cyclic, []string{`Name: "b"`, "Next: <cycle>"}},
+		{"shared", []*node{shared, shared}, []string{`&log.node{`}},
+		{"unexported", hidden{
+			m:     map[string]int{"a": 1},
+			keyed: map[point]string{{1, 2}: "p"},
+			err:   errors.New("inner"),
+		}, []string{`m: map[string]int{`, `"a": 1,`, `x: 1,`, `: "p"`, `err: &errors.errorString{`}},
+		{"unexported pointer", struct{ m *map[string]int }{&map[string]int{"k": 3}}, []string{`"k": 3`}},
+	}
+	for _, tt := range tests {
+		got := dump(tt.v)
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: dump = %s, want %q", tt.name, got, w)
+			}
+		}
+	}
+	if got := dump([]*node{shared, shared}); strings.Contains(got, "<cycle>") {
+		t.Errorf("shared values reported as a cycle: %s", got)
+	}
+}
+
+func TestDumpDepth(t *testing.T) {
+	var deep interface{} = "bottom"
+	for i := 0; i < maxDumpDepth+5; i++ {
+		deep = []interface{}{deep}
+	}
+	if got := dump(deep); !strings.Contains(got, "...") || strings.Contains(got, "bottom") {
+		t.Errorf("dump = %s, want the nesting cut", got)
+	}
+}
+
+func TestDumpOnlyAtDebug(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(INFO)
+	l.Dump("skipped", map[string]int{"a": 1})
+	l.SetLevel(DEBUG)
+	l.Dump("cfg", struct{ m map[string]int }{map[string]int{"a": 1}})
+	if got := out.String(); strings.Contains(got, "skipped") || !strings.HasPrefix(got, "DEBUG cfg: struct { m map[string]int }{") {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func logEmpty(l *Logger) {
+	l.Info()
+	l.Info(nil)
+	l.Infof("")
+	l.Info("\n")
+	l.Infof("done\n")
+	l.Info("kept\n\n")
+	l.Warn("text")
+}
+
+func TestEmptyMessagePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy int
+		want   []string
+	}{
+		{"emit", EmptyEmit, []string{"INFO ", "INFO <nil>", "INFO ", "INFO ", "INFO done", "INFO kept\n", "WARN text"}},
+		{"drop", EmptyDrop, []string{"INFO done", "INFO kept\n", "WARN text"}},
+		{"placeholder", EmptyPlaceholder, []string{"INFO (empty)", "INFO (empty)", "INFO (empty)", "INFO (empty)", "INFO done", "INFO kept\n", "WARN text"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			l.SetEmptyMessagePolicy(tt.policy)
+			logEmpty(l)
+
+			want := strings.Join(tt.want, "\n") + "\n"
+			if got := out.String(); got != want {
+				t.Errorf("output = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestEmptyMessageDropCountsAndKeepsFatal(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetExitFunc(func(int) {})
+	l.SetEmptyMessagePolicy(EmptyDrop)
+	l.SetStackPolicy(StackEntry)
+	l.Info()
+	l.Error(nil)
+	l.Fatal()
+
+	// a FATAL still says why the process exits
+	if lines := out.lines(); len(lines) < 2 || lines[0] != "FATAL (empty)" {
+		t.Errorf("lines = %q", lines)
+	}
+	if st := l.Stats().Suppressed; st.Empty != 2 {
+		t.Errorf("suppressed %+v, want 2 empty", st)
+	}
+}
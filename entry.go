@@ -0,0 +1,500 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fields are structured key/value pairs attached to an entry.
+type Fields map[string]interface{}
+
+// Entry carries a single log record through the pipeline, it's also the
+// chainable handle returned by Event and With. The typed fields added by
+// Str and the like are merged into Fields before filters and hooks see
+// the entry.
+type Entry struct {
+	Level   Level
+	Time    time.Time
+	Event   string // stable key for alerting, rendered by ${event}
+	File    string
+	Line    int
+	Message string
+	Fields  Fields
+	Stack   string // all goroutines, captured for FATAL
+	Forced  bool   // bypasses the level check, see Force
+
+	// where the entry was written, set for write hooks, see AddWriteHook
+	Filename string
+	Offset   int // bytes in the file before the entry, as ${offset}
+
+	logger      *Logger
+	pc          uintptr       // caller program counter, resolved lazily by ${func}
+	format      string        // format string before substitution
+	delta       time.Duration // since the previous entry of the logger
+	fingerprint string
+	stackOnly   bool   // the message is the stack, see StackEntry
+	goid        uint64 // see EnableGoroutineID
+	rendered    []byte // see Rendered
+	ctx         context.Context
+	fields      []field // typed, rendered after Fields, see Str
+	pooled      bool    // single use, see Logger.With
+	released    bool    // back to the pool, see checkPooled
+}
+
+// field kinds, see field
+const (
+	fieldAny = iota
+	fieldString
+	fieldInt
+	fieldInt64
+	fieldFloat64
+	fieldBool
+	fieldDuration
+	fieldTime
+	fieldError
+)
+
+// field is a typed field, the common types aren't boxed into an
+// interface until someone asks for Fields.
+type field struct {
+	key  string
+	kind uint8
+	str  string
+	num  int64       // ints, bool, float64 bits, duration, time UnixNano
+	any  interface{} // the value of fieldAny and fieldError, the time location
+}
+
+func (f *field) value() interface{} {
+	switch f.kind {
+	case fieldString:
+		return f.str
+	case fieldInt:
+		return int(f.num)
+	case fieldInt64:
+		return f.num
+	case fieldFloat64:
+		return math.Float64frombits(uint64(f.num))
+	case fieldBool:
+		return f.num != 0
+	case fieldDuration:
+		return time.Duration(f.num)
+	case fieldTime:
+		return time.Unix(0, f.num).In(f.any.(*time.Location))
+	}
+	return f.any
+}
+
+// entries recycles the entries of With, keeping their fields' capacity,
+// and emitted the ones emit works on, which share a With entry's fields
+// and so must not keep them.
+var (
+	entries = sync.Pool{
+		New: func() interface{} {
+			return new(Entry)
+		},
+	}
+	emitted = sync.Pool{
+		New: func() interface{} {
+			return new(Entry)
+		},
+	}
+)
+
+// With returns an entry from a pool for typed fields, so that e.g.
+// l.With().Str("user", id).Int("n", n).Info("saved") doesn't allocate.
+// Its adders change it in place rather than copying it, and it goes back
+// to the pool once logged: log it exactly once and don't keep it. Builds
+// with -race or the logdebug tag panic on a reuse.
+func (l *Logger) With() *Entry {
+	e := entries.Get().(*Entry)
+	e.logger, e.pooled = l, true
+	return e
+}
+
+func With() *Entry {
+	return global.With()
+}
+
+// mutable returns the entry the adders may change: e itself when pooled,
+// otherwise a copy, leaving e untouched.
+func (e *Entry) mutable() *Entry {
+	if e.pooled {
+		e.checkPooled()
+		return e
+	}
+	c := *e
+	c.fields = nil
+	if len(e.fields) > 0 {
+		c.fields = append(make([]field, 0, len(e.fields)+1), e.fields...)
+	}
+	return &c
+}
+
+// release returns a pooled entry to the pool once logged.
+func (e *Entry) release() {
+	if !e.pooled {
+		return
+	}
+	e.checkPooled()
+	if poolDebug {
+		// never reused, so that a second use is caught
+		e.released = true
+		return
+	}
+	fields := e.fields[:0]
+	*e = Entry{fields: fields}
+	entries.Put(e)
+}
+
+// checkPooled panics on the use of a pooled entry already logged, which
+// is only detected by debug builds.
+func (e *Entry) checkPooled() {
+	if e.released {
+		panic("log: entry from With used after it was logged")
+	}
+}
+
+// add sets f on the entry, replacing a field of the same key.
+func (e *Entry) add(f field) *Entry {
+	c := e.mutable()
+	for i := range c.fields {
+		if c.fields[i].key == f.key {
+			c.fields[i] = f
+			return c
+		}
+	}
+	c.fields = append(c.fields, f)
+	return c
+}
+
+// materialize merges the typed fields into Fields, in a new map since
+// Fields may be shared between entries.
+func (e *Entry) materialize() {
+	if len(e.fields) == 0 {
+		return
+	}
+	merged := make(Fields, len(e.Fields)+len(e.fields))
+	for k, v := range e.Fields {
+		merged[k] = v
+	}
+	for i := range e.fields {
+		merged[e.fields[i].key] = e.fields[i].value()
+	}
+	e.Fields, e.fields = merged, nil
+}
+
+// materialized is e once materialized, for the filters.
+func (e *Entry) materialized() *Entry {
+	e.materialize()
+	return e
+}
+
+// recycle puts back an entry emit worked on.
+func recycle(e *Entry) {
+	*e = Entry{}
+	emitted.Put(e)
+}
+
+// field returns the value of the field key, typed or not.
+func (e *Entry) field(key string) (interface{}, bool) {
+	for i := range e.fields {
+		if e.fields[i].key == key {
+			return e.fields[i].value(), true
+		}
+	}
+	v, ok := e.Fields[key]
+	return v, ok
+}
+
+// typed reports whether key is one of the typed fields, which win over
+// Fields.
+func (e *Entry) typed(key string) bool {
+	for i := range e.fields {
+		if e.fields[i].key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Event returns an entry tagged with a stable event key,
+// e.g. l.Event("user_login_failed").Warnf("login failed for %s", user).
+func (l *Logger) Event(key string) *Entry {
+	return &Entry{logger: l, Event: key}
+}
+
+func Event(key string) *Entry {
+	return global.Event(key)
+}
+
+// Force returns an entry which is written whatever the configured level,
+// e.g. l.Force().Info("audit: user deleted") for audit events.
+func (l *Logger) Force() *Entry {
+	return &Entry{logger: l, Forced: true}
+}
+
+func Force() *Entry {
+	return global.Force()
+}
+
+func (e *Entry) Force() *Entry {
+	c := e.mutable()
+	c.Forced = true
+	return c
+}
+
+func (l *Logger) WithField(key string, value interface{}) *Entry {
+	return &Entry{logger: l, Fields: Fields{key: value}}
+}
+
+func (l *Logger) WithFields(fields Fields) *Entry {
+	return (&Entry{logger: l}).WithFields(fields)
+}
+
+func WithField(key string, value interface{}) *Entry {
+	return global.WithField(key, value)
+}
+
+func WithFields(fields Fields) *Entry {
+	return global.WithFields(fields)
+}
+
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+// WithFields returns a copy of the entry with fields merged in, the
+// receiver is left untouched unless it comes from With.
+func (e *Entry) WithFields(fields Fields) *Entry {
+	c := e.mutable()
+	merged := make(Fields, len(c.Fields)+len(fields))
+	for k, v := range c.Fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	c.Fields = merged
+	// the later value wins over a typed field too
+	kept := c.fields[:0]
+	for _, f := range c.fields {
+		if _, ok := fields[f.key]; !ok {
+			kept = append(kept, f)
+		}
+	}
+	c.fields = kept
+	return c
+}
+
+// Str, Int, Int64, Float64, Bool, Dur, Timestamp, Err and Any add typed
+// fields, e.g. l.With().Str("user", id).Err(err).Error("save failed").
+// They are rendered after Fields, in the order they were added, without
+// boxing the common types. Like WithField they return a copy, unless the
+// entry comes from With.
+func (e *Entry) Str(key, value string) *Entry {
+	return e.add(field{key: key, kind: fieldString, str: value})
+}
+
+func (e *Entry) Int(key string, value int) *Entry {
+	return e.add(field{key: key, kind: fieldInt, num: int64(value)})
+}
+
+func (e *Entry) Int64(key string, value int64) *Entry {
+	return e.add(field{key: key, kind: fieldInt64, num: value})
+}
+
+func (e *Entry) Float64(key string, value float64) *Entry {
+	return e.add(field{key: key, kind: fieldFloat64, num: int64(math.Float64bits(value))})
+}
+
+func (e *Entry) Bool(key string, value bool) *Entry {
+	f := field{key: key, kind: fieldBool}
+	if value {
+		f.num = 1
+	}
+	return e.add(f)
+}
+
+func (e *Entry) Dur(key string, value time.Duration) *Entry {
+	return e.add(field{key: key, kind: fieldDuration, num: int64(value)})
+}
+
+// Timestamp adds a time field, unboxed within the years UnixNano covers.
+func (e *Entry) Timestamp(key string, value time.Time) *Entry {
+	if y := value.Year(); y <= 1678 || y >= 2262 {
+		return e.Any(key, value)
+	}
+	return e.add(field{key: key, kind: fieldTime, num: value.UnixNano(), any: value.Location()})
+}
+
+// Err adds err as the "error" field, nil is ignored.
+func (e *Entry) Err(err error) *Entry {
+	if err == nil {
+		return e
+	}
+	return e.add(field{key: "error", kind: fieldError, any: err})
+}
+
+func (e *Entry) Any(key string, value interface{}) *Entry {
+	return e.add(field{key: key, kind: fieldAny, any: value})
+}
+
+func (e *Entry) funcName() string {
+	if e.pc == 0 {
+		return ""
+	}
+	fn := runtime.FuncForPC(e.pc)
+	if fn == nil {
+		return ""
+	}
+	return path.Base(fn.Name())
+}
+
+// GoroutineID is the id of the goroutine which logged the entry, 0 unless
+// captured, see EnableGoroutineID.
+func (e *Entry) GoroutineID() uint64 {
+	return e.goid
+}
+
+// appendFields renders fields as " key=value" pairs sorted by key, then
+// the typed fields in order.
+func (e *Entry) appendFields(buf *bytes.Buffer) {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		if !e.typed(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	format := FormatField
+	if e.logger != nil && e.logger.fieldFormatter != nil {
+		format = e.logger.fieldFormatter
+	}
+	sanitize := e.logger != nil && e.logger.sanitize
+	write := func(k string, v interface{}) {
+		s := format(k, v)
+		if sanitize {
+			k, s = escapeControl(k), escapeControl(s)
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(s)
+	}
+	for _, k := range keys {
+		write(k, e.Fields[k])
+	}
+	for i := range e.fields {
+		write(e.fields[i].key, e.fields[i].value())
+	}
+}
+
+// SetFieldFormatter overrides how field values are rendered in text
+// formats, nil restores FormatField. JSON always marshals the values.
+func (l *Logger) SetFieldFormatter(fn func(key string, value interface{}) string) {
+	l.fieldFormatter = fn
+}
+
+func SetFieldFormatter(fn func(key string, value interface{}) string) {
+	global.SetFieldFormatter(fn)
+}
+
+// FormatField is the default field formatter: structs are rendered with
+// their field names (%+v), strings with spaces are quoted, times use the
+// log time format and nil is <nil>.
+func FormatField(key string, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\r\n\"") {
+			return strconv.Quote(v)
+		}
+		return v
+	case time.Time:
+		return v.Format(timeLocal)
+	case error, fmt.Stringer:
+		return fmt.Sprint(v)
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		return fmt.Sprintf("%+v", value)
+	}
+	return fmt.Sprint(value)
+}
+
+func (e *Entry) log(v Level, format string, args []interface{}) {
+	e.checkPooled()
+	c := *e
+	c.Level, c.pooled = v, false
+	e.logger.emit(c, 3, format, args)
+	e.release()
+}
+
+// Log is Logger.Log with the fields of the entry, and its caller when File
+// is set, for adapters. FATAL entries don't exit.
+func (e *Entry) Log(level Level, calldepth int, msg string) error {
+	e.checkPooled()
+	c := *e
+	c.Level, c.pooled = level, false
+	err := e.logger.emit(c, calldepth+1, "", []interface{}{msg})
+	e.release()
+	return err
+}
+
+func (e *Entry) Debug(i ...interface{}) {
+	e.log(DEBUG, "", i)
+}
+
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	e.log(DEBUG, format, args)
+}
+
+func (e *Entry) Info(i ...interface{}) {
+	e.log(INFO, "", i)
+}
+
+func (e *Entry) Infof(format string, args ...interface{}) {
+	e.log(INFO, format, args)
+}
+
+func (e *Entry) Warn(i ...interface{}) {
+	e.log(WARN, "", i)
+}
+
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	e.log(WARN, format, args)
+}
+
+func (e *Entry) Error(i ...interface{}) {
+	e.log(ERROR, "", i)
+}
+
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	e.log(ERROR, format, args)
+}
+
+func (e *Entry) Fatal(i ...interface{}) {
+	l := e.logger
+	e.log(FATAL, "", i)
+	l.exit()
+}
+
+func (e *Entry) Fatalf(format string, args ...interface{}) {
+	l := e.logger
+	e.log(FATAL, format, args)
+	l.exit()
+}
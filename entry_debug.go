@@ -0,0 +1,7 @@
+//go:build race || logdebug
+
+package log
+
+// poolDebug keeps the entries of With out of the pool once logged, so a
+// second use panics instead of corrupting another entry.
+const poolDebug = true
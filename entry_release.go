@@ -0,0 +1,5 @@
+//go:build !race && !logdebug
+
+package log
+
+const poolDebug = false
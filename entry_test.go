@@ -0,0 +1,150 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedFieldsJSON(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 5, time.UTC)
+	l.WithField("b", "map").Str("s", "x\"y").Int("i", -3).Int64("i64", 1<<40).
+		Float64("f", 1.5).Bool("ok", true).Dur("d", time.Second).
+		Timestamp("at", at).Err(errors.New("boom")).Any("any", []int{1}).
+		Str("msg", "shadowed").Info("hello")
+
+	line := out.String()
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &v); err != nil {
+		t.Fatalf("%q: %v", line, err)
+	}
+	want := map[string]interface{}{
+		"b": "map", "s": "x\"y", "i": -3.0, "i64": float64(1 << 40), "f": 1.5,
+		"ok": true, "d": float64(time.Second), "at": "2024-03-01T12:00:00.000000005Z",
+		"error": "boom", "fields.msg": "shadowed", "msg": "hello",
+	}
+	for k, w := range want {
+		if v[k] != w {
+			t.Errorf("%s = %#v, want %#v", k, v[k], w)
+		}
+	}
+	if !strings.Contains(line, `,"b":"map","s":`) {
+		t.Errorf("typed fields not after Fields, in order: %s", line)
+	}
+}
+
+func TestTypedFieldsText(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}${fields}\n")
+	l.With().Str("user", "alice").Int("n", 42).WithField("a", 1).Info("saved")
+
+	if got, want := out.String(), "saved a=1 user=alice n=42\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestTypedFieldsDuplicateKeys(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}${fields}\n")
+	l.With().Str("k", "1").Str("k", "2").Info("typed")
+	l.With().WithField("k", "map").Str("k", "typed").Info("typed wins")
+	l.With().Str("k", "typed").WithField("k", "map").Info("later wins")
+
+	want := []string{"typed k=2", "typed wins k=typed", "later wins k=map"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestTypedFieldsCopy(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}${fields}\n")
+	base := l.Event("save").Str("a", "1")
+	first := base.Str("b", "2")
+	second := base.Str("b", "3")
+	base.Info("base")
+	first.Info("first")
+	second.Info("second")
+	first.Info("again")
+
+	want := []string{"base a=1", "first a=1 b=2", "second a=1 b=3", "again a=1 b=2"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestTypedFieldsSeenAsFields(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	var filtered, messages, fired interface{}
+	l.AddFilter(func(e *Entry) bool {
+		filtered = e.Fields["user"]
+		return true
+	})
+	l.AddMessageFilter(func(e *Entry) bool {
+		messages = e.Fields["user"]
+		return true
+	})
+	l.AddHook(funcHook(func(e *Entry) error {
+		fired = e.Fields["user"]
+		return nil
+	}))
+	l.With().Str("user", "alice").Info("hello")
+
+	if filtered != "alice" || messages != "alice" || fired != "alice" {
+		t.Errorf("filter, message filter, hook saw %v, %v, %v", filtered, messages, fired)
+	}
+}
+
+func TestWithUsedTwice(t *testing.T) {
+	if !poolDebug {
+		t.Skip("only detected with -race or -tags logdebug")
+	}
+	l := New("", INFO, 0, 0)
+	l.SetOutput(ioutil.Discard)
+	e := l.With().Str("user", "alice")
+	e.Info("once")
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic logging an entry of With twice")
+		}
+	}()
+	e.Info("twice")
+}
+
+func TestJSONFiveFieldsAllocs(t *testing.T) {
+	if poolDebug {
+		t.Skip("debug builds don't reuse the entries of With")
+	}
+	l := New("", INFO, 0, 0)
+	l.SetOutput(ioutil.Discard)
+	l.EnableJSON()
+	err := errors.New("boom")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.With().Str("user", "alice").Int("n", 42).Dur("took", time.Millisecond).Str("path", "/tmp/x").Err(err).Info("saved")
+	})
+	if allocs > 2 {
+		t.Errorf("%v allocs per Info, want at most 2", allocs)
+	}
+}
+
+func BenchmarkJSONFiveFields(b *testing.B) {
+	l := New("", INFO, 0, 0)
+	l.SetOutput(ioutil.Discard)
+	l.EnableJSON()
+	err := errors.New("boom")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.With().Str("user", "alice").Int("n", 42).Dur("took", time.Millisecond).Str("path", "/tmp/x").Err(err).Info("saved")
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFatale(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	exits := 0
+	l.SetExitFunc(func(int) { exits++ })
+	l.SetStackPolicy(StackEntry)
+
+	if err := l.Fatale(nil, "nothing"); err != nil || exits != 0 || out.String() != "" {
+		t.Fatalf("Fatale(nil) = %v, %d exits, output %q", err, exits, out.String())
+	}
+	base := errors.New("corrupt index")
+	if err := l.Fatale(base, "open %s", "db"); err != base || exits != 1 {
+		t.Errorf("Fatale = %v after %d exits", err, exits)
+	}
+	if lines := out.lines(); lines[0] != "FATAL open db: corrupt index" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+// TestErrHelpersCaller checks the package functions, whose extra frame
+// mustn't show as the caller.
+func TestErrHelpersCaller(t *testing.T) {
+	old := GetLogger()
+	defer SetLogger(old)
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} ${short_file}:${line} ${message}\n")
+	l.SetFatalBehavior(FatalLogOnly)
+	l.SetStackPolicy(StackEntry)
+	SetLogger(l)
+
+	base := errors.New("boom")
+	var want []string
+	for _, tt := range []struct {
+		level string
+		log   func() (error, int)
+	}{
+		{"ERROR", func() (error, int) { return Err(base, "save"), here() }},
+		{"WARN", func() (error, int) { return Warne(base, "save"), here() }},
+		{"FATAL", func() (error, int) { return Fatale(base, "save"), here() }},
+	} {
+		err, line := tt.log()
+		if err != base {
+			t.Errorf("%s returned %v", tt.level, err)
+		}
+		want = append(want, tt.level+" errhelper_test.go:"+strconv.Itoa(line)+" save: boom")
+	}
+	if Err(nil) != nil || Warne(nil) != nil || Fatale(nil) != nil {
+		t.Error("a nil error isn't returned as is")
+	}
+
+	// the stack entry of the FATAL follows
+	got := out.lines()
+	if len(got) < len(want) || strings.Join(got[:len(want)], "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q first", got, want)
+	}
+}
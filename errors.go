@@ -0,0 +1,154 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Err logs err at ERROR and returns it, doing nothing for a nil err, so
+// that error paths collapse to return l.Err(err, "save user"). The
+// message is msgAndArgs followed by ": " and err, msgAndArgs being a
+// format and its arguments, or a single value.
+func (l *Logger) Err(err error, msgAndArgs ...interface{}) error {
+	return l.logErr(ERROR, err, msgAndArgs)
+}
+
+// Warne is Err at WARN.
+func (l *Logger) Warne(err error, msgAndArgs ...interface{}) error {
+	return l.logErr(WARN, err, msgAndArgs)
+}
+
+// Fatale is Err at FATAL, then runs the fatal behavior. It only returns
+// with FatalLogOnly.
+func (l *Logger) Fatale(err error, msgAndArgs ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	l.logErr(FATAL, err, msgAndArgs)
+	l.exit()
+	return err
+}
+
+func Err(err error, msgAndArgs ...interface{}) error {
+	return global.logErr(ERROR, err, msgAndArgs)
+}
+
+func Warne(err error, msgAndArgs ...interface{}) error {
+	return global.logErr(WARN, err, msgAndArgs)
+}
+
+func Fatale(err error, msgAndArgs ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	global.logErr(FATAL, err, msgAndArgs)
+	global.exit()
+	return err
+}
+
+// logErr keeps the format of the message, and not the error, as the
+// format of the entry, so its fingerprint doesn't change with the error.
+// Like log, the caller is 3 frames up.
+func (l *Logger) logErr(v Level, err error, msgAndArgs []interface{}) error {
+	if err == nil {
+		return nil
+	}
+	format, args := errFormat(err, msgAndArgs)
+	l.emit(Entry{Level: v}, 3, format, args)
+	return err
+}
+
+// errFormat returns the format and arguments of msgAndArgs followed by
+// ": " and err, or of msgAndArgs alone for a nil err.
+func errFormat(err error, msgAndArgs []interface{}) (string, []interface{}) {
+	format, args := "", []interface{}(nil)
+	if len(msgAndArgs) > 0 {
+		s, ok := msgAndArgs[0].(string)
+		switch {
+		case !ok:
+			format, args = "%v", []interface{}{fmt.Sprint(msgAndArgs...)}
+		case len(msgAndArgs) == 1:
+			format = strings.Replace(s, "%", "%%", -1)
+		default:
+			format, args = s, msgAndArgs[1:len(msgAndArgs):len(msgAndArgs)]
+		}
+	}
+	if err == nil {
+		return format, args
+	}
+	if format == "" {
+		return "%v", []interface{}{err}
+	}
+	return format + ": %v", append(args[:len(args):len(args)], err)
+}
+
+// ClassifyError adds a classifier choosing the level of Auto entries,
+// reporting false when it has no opinion. Classifiers run in the order
+// they were added, the first opinion wins, e.g. for a benign class:
+//
+//	l.ClassifyError(func(err error) (log.Level, bool) {
+//		return log.WARN, errors.Is(err, ErrNotFound)
+//	})
+func (l *Logger) ClassifyError(fn func(err error) (Level, bool)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	classifiers, _ := l.classifiers.Load().([]func(error) (Level, bool))
+	l.classifiers.Store(append(classifiers[:len(classifiers):len(classifiers)], fn))
+}
+
+// errorLevel is the level of an Auto entry: INFO without error, the one
+// of the first classifier with an opinion, WARN for a canceled context
+// and ERROR otherwise.
+func (l *Logger) errorLevel(err error) Level {
+	if err == nil {
+		return INFO
+	}
+	classifiers, _ := l.classifiers.Load().([]func(error) (Level, bool))
+	for _, classify := range classifiers {
+		if v, ok := classify(err); ok {
+			return v
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return WARN
+	}
+	return ERROR
+}
+
+// Auto logs the message with err like Err, or alone for a nil err, at the
+// level of the error class, see ClassifyError. It returns err.
+func (l *Logger) Auto(err error, msgAndArgs ...interface{}) error {
+	format, args := errFormat(err, msgAndArgs)
+	l.emit(Entry{Level: l.errorLevel(err)}, 2, format, args)
+	return err
+}
+
+// Autof is Auto with a format.
+func (l *Logger) Autof(err error, format string, args ...interface{}) error {
+	return l.autof(err, format, args)
+}
+
+func (l *Logger) autof(err error, format string, args []interface{}) error {
+	if err != nil {
+		format, args = format+": %v", append(args[:len(args):len(args)], err)
+	}
+	l.emit(Entry{Level: l.errorLevel(err)}, 3, format, args)
+	return err
+}
+
+func ClassifyError(fn func(err error) (Level, bool)) {
+	global.ClassifyError(fn)
+}
+
+func Auto(err error, msgAndArgs ...interface{}) error {
+	format, args := errFormat(err, msgAndArgs)
+	global.emit(Entry{Level: global.errorLevel(err)}, 2, format, args)
+	return err
+}
+
+func Autof(err error, format string, args ...interface{}) error {
+	return global.autof(err, format, args)
+}
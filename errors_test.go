@@ -0,0 +1,114 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var errNotFound = errors.New("not found")
+
+// joinedError wraps several errors, like errors.Join.
+type joinedError []error
+
+func (e joinedError) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
+
+func TestErr(t *testing.T) {
+	base := errors.New("disk full")
+	wrapped := fmt.Errorf("write: %w", base)
+	tests := []struct {
+		name string
+		log  func(l *Logger) error
+		err  error
+		want string
+	}{
+		{"nil", func(l *Logger) error { return l.Err(nil, "save") }, nil, ""},
+		{"nil warne", func(l *Logger) error { return l.Warne(nil) }, nil, ""},
+		{"alone", func(l *Logger) error { return l.Err(base) }, base, "ERROR disk full\n"},
+		{"wrapped", func(l *Logger) error { return l.Err(wrapped, "save %s", "user") }, wrapped, "ERROR save user: write: disk full\n"},
+		{"twice wrapped", func(l *Logger) error { return l.Warne(fmt.Errorf("sync: %w", wrapped), "100%") }, nil, "WARN 100%: sync: write: disk full\n"},
+		{"not a string", func(l *Logger) error { return l.Err(base, 42, "x") }, base, "ERROR 42x: disk full\n"},
+		{"joined", func(l *Logger) error { return l.Err(joinedError{base, errNotFound}, "batch") }, nil, "ERROR batch: disk full; not found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			err := tt.log(l)
+			if tt.err != nil && err != tt.err {
+				t.Errorf("returned %v, want the error as is", err)
+			}
+			if (tt.want == "") != (err == nil) {
+				t.Errorf("returned %v for output %q", err, tt.want)
+			}
+			if err != nil && !errors.Is(err, base) {
+				t.Errorf("returned %v, want it to still wrap %v", err, base)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuto(t *testing.T) {
+	wrappedCancel := fmt.Errorf("fetch: %w", context.Canceled)
+	tests := []struct {
+		name string
+		log  func(l *Logger) error
+		want string
+	}{
+		{"nil", func(l *Logger) error { return l.Auto(nil, "done") }, "INFO done\n"},
+		{"nil format", func(l *Logger) error { return l.Autof(nil, "done %d", 3) }, "INFO done 3\n"},
+		{"error", func(l *Logger) error { return l.Auto(errors.New("boom"), "run") }, "ERROR run: boom\n"},
+		{"canceled", func(l *Logger) error { return l.Auto(context.Canceled) }, "WARN context canceled\n"},
+		{"wrapped canceled", func(l *Logger) error { return l.Autof(wrappedCancel, "job %d", 7) }, "WARN job 7: fetch: context canceled\n"},
+		{"classified", func(l *Logger) error { return l.Auto(fmt.Errorf("get: %w", fmt.Errorf("row: %w", errNotFound))) }, "DEBUG get: row: not found\n"},
+		{"joined", func(l *Logger) error { return l.Auto(joinedError{errors.New("a"), errNotFound}) }, "DEBUG a; not found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			l.ClassifyError(func(err error) (Level, bool) {
+				return DEBUG, errors.Is(err, errNotFound)
+			})
+			// no opinion on anything else
+			l.ClassifyError(func(err error) (Level, bool) {
+				return FATAL, false
+			})
+			tt.log(l)
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorFirstOpinionWins(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.ClassifyError(func(err error) (Level, bool) { return WARN, errors.Is(err, errNotFound) })
+	l.ClassifyError(func(err error) (Level, bool) { return INFO, true })
+	l.Auto(fmt.Errorf("wrapped: %w", errNotFound))
+	l.Auto(errors.New("other"))
+	// a canceled context only gets WARN when no classifier has an opinion
+	l.Auto(context.Canceled)
+
+	want := []string{"WARN wrapped: not found", "INFO other", "INFO context canceled"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEventTag(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} [${event}] ${message}\n")
+	l.Event("user_login_failed").Warnf("login failed for %s", "alice")
+	l.Info("no key")
+	l.Event("saved").WithField("n", 1).Info("with fields")
+
+	want := []string{"WARN [user_login_failed] login failed for alice", "INFO [] no key", "INFO [saved] with fields"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.Event("user_login_failed").Warn("login failed")
+	l.Info("no key")
+
+	lines := out.lines()
+	var keyed, plain map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &keyed); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if keyed["event"] != "user_login_failed" {
+		t.Errorf("entry = %v, want the event key", keyed)
+	}
+	if _, ok := plain["event"]; ok {
+		t.Errorf("entry = %v, want no event key", plain)
+	}
+}
+
+func TestEventSeenByHooksAndFilters(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	var events []string
+	l.AddHook(funcHook(func(e *Entry) error {
+		events = append(events, e.Event)
+		return nil
+	}))
+	// sample on the key, whatever the formatted message
+	seen := map[string]bool{}
+	l.AddFilter(func(e *Entry) bool {
+		if e.Event == "" {
+			return true
+		}
+		first := !seen[e.Event]
+		seen[e.Event] = true
+		return first
+	})
+	for i := 0; i < 3; i++ {
+		l.Event("retry").Warnf("attempt %d", i)
+	}
+	l.Info("plain")
+
+	if got := strings.Join(events, ","); got != "retry," {
+		t.Errorf("hook saw events %q", got)
+	}
+	want := []string{"WARN attempt 0", "INFO plain"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
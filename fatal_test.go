@@ -0,0 +1,77 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFatalExit(t *testing.T) {
+	if name := os.Getenv("LOG_TEST_FATAL_FILE"); name != "" {
+		l := New(name, INFO, 0, 0)
+		l.Fatal("exiting")
+		l.Info("unreachable")
+		return
+	}
+	name := filepath.Join(t.TempDir(), "app.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalExit$")
+	cmd.Env = append(os.Environ(), "LOG_TEST_FATAL_FILE="+name)
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 1 {
+		t.Fatalf("process ended with %v, want exit status 1", err)
+	}
+	if got := readFile(t, name); !strings.Contains(got, "exiting\ngoroutine ") || strings.Contains(got, "unreachable") {
+		t.Errorf("file = %q", got)
+	}
+}
+
+func TestFatalPanic(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFatalBehavior(FatalPanic)
+	deferred := false
+	func() {
+		defer func() {
+			r := recover()
+			if r != "log: fatal: cleanup failed" {
+				t.Errorf("recovered %v", r)
+			}
+		}()
+		defer func() { deferred = true }()
+		l.Fatalf("cleanup %s", "failed")
+	}()
+	if !deferred || !strings.HasPrefix(out.String(), "FATAL cleanup failed\ngoroutine ") {
+		t.Errorf("deferred %v, output %q", deferred, out.String())
+	}
+}
+
+func TestFatalLogOnly(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFatalBehavior(FatalLogOnly)
+	l.SetStackPolicy(StackEntry)
+	l.Fatal("kept running")
+	l.Info("still logging")
+
+	lines := out.lines()
+	if len(lines) < 3 || lines[0] != "FATAL kept running" || !strings.HasPrefix(lines[1], "FATAL goroutine ") || lines[len(lines)-1] != "INFO still logging" {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestPackageSetFatalBehavior(t *testing.T) {
+	old := GetLogger()
+	defer SetLogger(old)
+	var out syncBuffer
+	SetLogger(newTestLogger(&out))
+	SetFatalBehavior(FatalLogOnly)
+	Fatal("logged only")
+	Info("after")
+	if got := out.lines(); got[len(got)-1] != "INFO after" {
+		t.Errorf("lines = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type coords struct {
+	X, Y int
+}
+
+func TestFormatField(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, "<nil>"},
+		{"plain", "plain"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{"tab\there", `"tab\there"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{42, "42"},
+		{coords{1, 2}, "{X:1 Y:2}"},
+		{&coords{3, 4}, "&{X:3 Y:4}"},
+		{tm, "2024-01-02 03:04:05.006"},
+		{errors.New("disk full"), "disk full"},
+		{time.Second, "1s"},
+		{[]int{1, 2}, "[1 2]"},
+	}
+	for _, tt := range tests {
+		if got := FormatField("k", tt.value); got != tt.want {
+			t.Errorf("FormatField(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSetFieldFormatter(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}${fields}\n")
+	l.WithFields(Fields{"p": coords{1, 2}, "s": "a b"}).Info("default")
+	l.SetFieldFormatter(func(key string, value interface{}) string {
+		if key == "secret" {
+			return "***"
+		}
+		return FormatField(key, value)
+	})
+	l.WithFields(Fields{"secret": "hunter2", "user": "alice"}).Info("custom")
+	l.SetFieldFormatter(nil)
+	l.WithField("secret", "hunter2").Info("restored")
+
+	want := []string{`default p={X:1 Y:2} s="a b"`, "custom secret=*** user=alice", "restored secret=hunter2"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFieldFallback(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	// unmarshalable values fall back to fmt.Sprint, the entry is kept
+	l.WithFields(Fields{"ch": make(chan int), "fn": func() {}, "p": coords{1, 2}}).Info("kept")
+
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(out.String()), &v); err != nil {
+		t.Fatalf("output %q: %v", out.String(), err)
+	}
+	p, _ := v["p"].(map[string]interface{})
+	ch, _ := v["ch"].(string)
+	if v["msg"] != "kept" || p["X"] != 1.0 || !strings.HasPrefix(ch, "0x") || v["fn"] == nil {
+		t.Errorf("entry = %v", v)
+	}
+}
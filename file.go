@@ -0,0 +1,153 @@
+package log
+
+import (
+	"hash"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// sharedFile is an open log file. Loggers writing to the same path share
+// it, and with it the lock, the size counter and the rotation, so they
+// rotate once instead of clobbering each other's archives. It's closed
+// when the last Logger releases it.
+type sharedFile struct {
+	mutex sync.Mutex // held while checking the size, rotating and writing
+	key   string     // cleaned absolute path, the registry key
+	name  string     // path as opened
+	refs  int
+	f     *os.File
+	size  int
+	lines int  // only counted when a user has SetMaxLines
+	fresh bool // nothing written since it was opened
+	sync  bool // opened with O_SYNC, see WithSyncWrites
+
+	hash    hash.Hash // content written so far, see WithIntegrityFooter
+	entries int       // lines written since the hash started
+
+	rotations int  // numbers the temporary files of rotations
+	recovered bool // see recoverRotations
+	maint     maintenance
+}
+
+var files = struct {
+	sync.Mutex
+	m map[string]*sharedFile
+}{m: make(map[string]*sharedFile)}
+
+func fileKey(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return filepath.Clean(name)
+}
+
+// acquireFile returns the shared file for name, opening it when nobody has
+// yet. truncate only applies to a file which isn't already open.
+func acquireFile(name string, truncate, sync bool, eol string, countLines bool) (*sharedFile, error) {
+	key := fileKey(name)
+	files.Lock()
+	defer files.Unlock()
+
+	if s, ok := files.m[key]; ok {
+		s.refs++
+		return s, nil
+	}
+	s := &sharedFile{key: key, refs: 1, sync: sync}
+	if err := s.openLocked(name, truncate, eol, countLines); err != nil {
+		return nil, err
+	}
+	files.m[key] = s
+	return s, nil
+}
+
+// openLocked (re)opens the file as name, s.mutex must be held unless s is
+// not shared yet.
+func (s *sharedFile) openLocked(name string, truncate bool, eol string, count bool) error {
+	flag := os.O_APPEND | os.O_WRONLY | os.O_CREATE
+	if truncate {
+		flag |= os.O_TRUNC
+	}
+	if s.sync {
+		flag |= os.O_SYNC
+	}
+	f, err := os.OpenFile(name, flag, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if s.f != nil {
+		s.f.Close()
+	}
+	s.f = f
+	s.name = name
+	s.fresh = true
+	s.size = int(fi.Size())
+	s.lines = 0
+	if count && s.size > 0 {
+		s.lines = countLines(name, eol)
+	}
+	if s.hash != nil {
+		s.hashExisting(eol)
+	}
+	return nil
+}
+
+// rename moves s to the registry key of its current name, after a
+// rotation switched to a new {date} file.
+func (s *sharedFile) rename() {
+	key := fileKey(s.name)
+	files.Lock()
+	defer files.Unlock()
+
+	if key == s.key {
+		return
+	}
+	if files.m[s.key] == s {
+		delete(files.m, s.key)
+	}
+	s.key = key
+	if _, ok := files.m[key]; !ok {
+		files.m[key] = s
+	}
+}
+
+// Write writes to the file, s.mutex must be held.
+func (s *sharedFile) Write(b []byte) (int, error) {
+	n, err := s.f.Write(b)
+	if s.hash != nil {
+		s.hash.Write(b[:n])
+	}
+	return n, err
+}
+
+func (s *sharedFile) Sync() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.f.Sync()
+}
+
+// Close releases one reference, the file is closed with the last one.
+func (s *sharedFile) Close() error {
+	files.Lock()
+	s.refs--
+	last := s.refs == 0
+	if last && files.m[s.key] == s {
+		delete(files.m, s.key)
+	}
+	files.Unlock()
+
+	if !last {
+		return nil
+	}
+	s.maint.stop()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.f.Close()
+}
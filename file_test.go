@@ -0,0 +1,81 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLoggersShareAFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	a := New(name, INFO, 0, 1000, WithMaxSize(2*KB))
+	b := New(filepath.Join(dir, ".", "app.log"), INFO, 0, 1000, WithMaxSize(2*KB))
+	a.SetFormat("${message}\n")
+	b.SetFormat("${message}\n")
+	a.Info("open")
+	b.Info("open")
+	if a.file != b.file {
+		t.Fatal("the loggers don't share the file")
+	}
+
+	const rounds = 300
+	var wg sync.WaitGroup
+	for _, l := range []*Logger{a, b} {
+		for g := 0; g < 2; g++ {
+			wg.Add(1)
+			go func(l *Logger, id string) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					l.Infof("#%s-%d", id, i)
+				}
+			}(l, fmt.Sprintf("%p-%d", l, g))
+		}
+	}
+	wg.Wait()
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !openFile(name) {
+		t.Fatal("closing one logger closed the file of the other")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(name + ".*")
+	if len(files) < 5 {
+		t.Fatalf("only %d archives, the test needs rotations", len(files))
+	}
+	// one rotation sequence: app.log.1 to app.log.N, none over the size
+	seen := make(map[string]int)
+	for i := 0; i <= len(files); i++ {
+		file := name
+		if i > 0 {
+			file = fmt.Sprintf("%s.%d", name, i)
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("archives aren't numbered in sequence: %v", err)
+		}
+		if fi.Size() > int64(2*KB) {
+			t.Errorf("%s is %d bytes, over the max size", file, fi.Size())
+		}
+		for _, line := range strings.Split(readFile(t, file), "\n") {
+			if strings.HasPrefix(line, "#") {
+				seen[line]++
+			}
+		}
+	}
+	if len(seen) != 4*rounds {
+		t.Errorf("%d distinct entries, want %d", len(seen), 4*rounds)
+	}
+	for line, n := range seen {
+		if n != 1 {
+			t.Errorf("%q written %d times", line, n)
+		}
+	}
+}
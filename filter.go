@@ -0,0 +1,56 @@
+package log
+
+// Filter decides whether an entry is written, false drops it. Forced
+// entries are never filtered.
+type Filter func(e *Entry) bool
+
+// AddFilter adds a filter run right after the level check, before the
+// caller lookup, the lazy fields and any formatting, so a dropped entry
+// costs about as little as one below the level. Only the level, the
+// event, the fields and the format string (Entry.Format) are known yet:
+// this is where samplers keyed on them belong.
+func (l *Logger) AddFilter(f Filter) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	filters, _ := l.filters.Load().([]Filter)
+	l.filters.Store(append(filters[:len(filters):len(filters)], f))
+}
+
+// AddMessageFilter adds a filter run once the message and the caller are
+// known, before the lazy fields, the template and the hooks, e.g. to drop
+// entries by content. Lazy fields are still LazyValue then.
+func (l *Logger) AddMessageFilter(f Filter) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	filters, _ := l.messageFilters.Load().([]Filter)
+	l.messageFilters.Store(append(filters[:len(filters):len(filters)], f))
+}
+
+func AddFilter(f Filter) {
+	global.AddFilter(f)
+}
+
+func AddMessageFilter(f Filter) {
+	global.AddMessageFilter(f)
+}
+
+// Format is the format string the entry was logged with, empty for the
+// unformatted calls.
+func (e *Entry) Format() string {
+	return e.format
+}
+
+// filtered reports whether one of filters drops e.
+func filtered(filters []Filter, e *Entry) bool {
+	if e.Forced {
+		return false
+	}
+	for _, f := range filters {
+		if !f(e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestFilterStages(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	var early, late Entry
+	l.AddFilter(func(e *Entry) bool {
+		early = *e
+		return true
+	})
+	l.AddMessageFilter(func(e *Entry) bool {
+		late = *e
+		return false
+	})
+	l.Infof("saved %d", 3)
+
+	// the caller lookup and the message wait for the cheap filters
+	if early.File != "" || early.Message != "" || early.Format() != "saved %d" {
+		t.Errorf("filter saw file %q, message %q, format %q", early.File, early.Message, early.Format())
+	}
+	if late.File == "" || late.Message != "saved 3" {
+		t.Errorf("message filter saw file %q, message %q", late.File, late.Message)
+	}
+	if out.String() != "" || l.Stats().Suppressed.Filter != 1 {
+		t.Errorf("output = %q, suppressed %+v", out.String(), l.Stats().Suppressed)
+	}
+}
+
+func benchmarkSuppressed(b *testing.B, setup func(l *Logger)) {
+	l := New("", INFO, 0, 0)
+	l.SetOutput(ioutil.Discard)
+	setup(l)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.WithField("user", "alice").Infof("saved %d items", i)
+	}
+}
+
+// BenchmarkSuppressedByLevel is the reference the entries dropped by
+// filters should stay close to.
+func BenchmarkSuppressedByLevel(b *testing.B) {
+	benchmarkSuppressed(b, func(l *Logger) { l.SetLevel(WARN) })
+}
+
+func BenchmarkSuppressedByFilter(b *testing.B) {
+	benchmarkSuppressed(b, func(l *Logger) {
+		l.AddFilter(func(e *Entry) bool { return e.Fields["user"] != "alice" })
+	})
+}
+
+func BenchmarkSuppressedByMessageFilter(b *testing.B) {
+	benchmarkSuppressed(b, func(l *Logger) {
+		l.AddMessageFilter(func(e *Entry) bool { return e.Message == "" })
+	})
+}
+
+func BenchmarkWritten(b *testing.B) {
+	benchmarkSuppressed(b, func(l *Logger) {})
+}
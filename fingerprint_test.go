@@ -0,0 +1,100 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fingerprints logs through log and returns the fingerprints seen by a
+// hook, checking they are the ones rendered by ${fingerprint}.
+func fingerprints(t *testing.T, setup func(l *Logger), log func(l *Logger)) []string {
+	t.Helper()
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${fingerprint}\n")
+	if setup != nil {
+		setup(l)
+	}
+	var seen []string
+	l.AddHook(funcHook(func(e *Entry) error {
+		seen = append(seen, e.Fingerprint())
+		return nil
+	}))
+	log(l)
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(seen, "\n") {
+		t.Errorf("rendered %q, hooks saw %q", got, seen)
+	}
+	return seen
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := fingerprints(t, nil, func(l *Logger) {
+		for i := 0; i < 2; i++ {
+			l.Errorf("user %d not found", i)
+			l.Warnf("user %d not found", i)
+			l.Err(errors.New([]string{"timeout", "reset"}[i]), "fetch %s", "x")
+			l.Event("login_failed").Warnf("bad password for %d", i)
+		}
+		l.Errorf("user %d not found", 3)
+		l.Event("login_failed").Warn("another line")
+	})
+
+	// the same call site, whatever the arguments or the error
+	for i := 0; i < 4; i++ {
+		if fp[i] != fp[i+4] {
+			t.Errorf("call %d: fingerprints %s and %s, want the same", i, fp[i], fp[i+4])
+		}
+	}
+	// another level, another line
+	seen := map[string]bool{}
+	for _, f := range append(fp[:4:4], fp[8], fp[9]) {
+		if seen[f] || f == "" {
+			t.Errorf("fingerprints %q, want them different", fp)
+			break
+		}
+		seen[f] = true
+	}
+}
+
+func TestSetFingerprinter(t *testing.T) {
+	fp := fingerprints(t, func(l *Logger) {
+		l.SetFingerprinter(func(e *Entry) string { return e.Event + "/" + e.Message })
+	}, func(l *Logger) {
+		l.Event("saved").Infof("%d rows", 3)
+		l.Info("plain")
+	})
+	if strings.Join(fp, " ") != "saved/3 rows /plain" {
+		t.Errorf("fingerprints = %q", fp)
+	}
+}
+
+func TestFingerprintJSON(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.Info("without")
+	l.SetJSONConfig(JSONConfig{Fingerprint: true})
+	var hooked string
+	l.AddHook(funcHook(func(e *Entry) error {
+		hooked = e.Fingerprint()
+		return nil
+	}))
+	l.Info("with")
+
+	lines := out.lines()
+	var without, with map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &without); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &with); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := without["fingerprint"]; ok {
+		t.Errorf("entry = %v, want no fingerprint by default", without)
+	}
+	if with["fingerprint"] != hooked || hooked == "" {
+		t.Errorf("entry = %v, want the fingerprint %q", with, hooked)
+	}
+}
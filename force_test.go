@@ -0,0 +1,62 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForceBypassesTheSuppression(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(WARN)
+	l.AddFilter(func(e *Entry) bool { return false })
+	l.AddMessageFilter(func(e *Entry) bool { return false })
+	l.DenyCallerPrefix("")
+
+	l.Info("dropped")
+	l.Force().Info("audit: user deleted")
+	l.WithField("user", "alice").Force().Debugf("audit: %s", "forced debug")
+	l.Warn("filtered")
+
+	want := []string{"INFO audit: user deleted", "DEBUG audit: forced debug"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if st := l.Stats().Suppressed; st.Level != 1 || st.Filter != 1 {
+		t.Errorf("suppressed %+v, want the INFO and the WARN", st)
+	}
+}
+
+func TestForceBypassesTheRingBuffer(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(WARN)
+	l.EnableRingBuffer(10, DEBUG)
+	l.Info("captured")
+	l.Force().Info("audit")
+	if got := out.String(); got != "INFO audit\n" {
+		t.Fatalf("output = %q, want the forced entry written right away", got)
+	}
+
+	// only the unforced entry went to the ring, the audit isn't replayed
+	l.Error("trigger")
+	want := []string{"INFO audit", "[replayed] INFO captured", "ERROR trigger"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestPackageForce(t *testing.T) {
+	old := GetLogger()
+	defer SetLogger(old)
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(ERROR)
+	SetLogger(l)
+
+	Info("dropped")
+	Force().Info("forced")
+	if got := out.String(); got != "INFO forced\n" {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -0,0 +1,269 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/valyala/fasttemplate"
+)
+
+// textFormat is a format string split into segments, pre-rendered per
+// level: tags which don't change between entries (prefix, pid, level...)
+// are baked into the static text, so only time, caller and message are
+// rendered for each entry. It's rebuilt whenever one of those changes.
+//
+// It's also the snapshot of the settings an entry is rendered with, the
+// Logger fields they come from are only read when rebuilding it, so an
+// entry never sees a mix of old and new settings.
+type textFormat struct {
+	src     string
+	levels  [FATAL + 1][]segment
+	tokens  [FATAL + 1]string // ${level}, padded and colored
+	theme   *Theme            // colors of the per entry tags, nil without color
+	line    *Theme            // colors the whole entry by level, see ColorFullLine
+	color   bool              // see ColorEnabled
+	goid    bool              // uses ${goid}
+	json    bool              // see EnableJSON, the template is unused then
+	prefix  string
+	maxMsg  int      // see SetMaxMessageLength
+	unknown []string // tags renderTag doesn't know, see SetUnknownTagPolicy
+}
+
+// segment is either static text or a tag rendered per entry.
+type segment struct {
+	static []byte
+	tag    string
+}
+
+// constantTags only depend on the logger configuration and the level.
+var constantTags = map[string]bool{
+	"prefix":            true,
+	"prefix_decorated":  true,
+	"pid":               true,
+	"level":             true,
+	"level_lower":       true,
+	"level_upper_plain": true,
+	"level_num":         true,
+}
+
+// mustTemplate compiles a format known to be valid: the default one, or
+// one that already compiled with other settings.
+func (l *Logger) mustTemplate(format string) *textFormat {
+	tf, err := l.newTemplate(format)
+	if err != nil {
+		panic(err)
+	}
+	return tf
+}
+
+// newTemplate compiles format, the error is about an unterminated tag.
+func (l *Logger) newTemplate(format string) (*textFormat, error) {
+	if _, err := fasttemplate.NewTemplate(format, "${", "}"); err != nil {
+		return nil, fmt.Errorf("log: invalid format %q: %v", format, err)
+	}
+
+	src := format
+	if format == defaultFormat {
+		src = l.defaultFormat()
+	}
+	var parts []segment
+	var unknown []string
+	goid := false
+	for s := src; s != ""; {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			parts = append(parts, segment{static: []byte(s)})
+			break
+		}
+		if i > 0 {
+			parts = append(parts, segment{static: []byte(s[:i])})
+		}
+		s = s[i+2:]
+		j := strings.Index(s, "}")
+		tag := s[:j]
+		s = s[j+1:]
+		if _, err := l.renderTag(ioutil.Discard, &textFormat{}, &Entry{}, tag); err == errUnknownTag {
+			unknown = append(unknown, tag)
+			if l.unknownTag != UnknownTagEmpty {
+				parts = append(parts, segment{static: []byte("[unknown tag " + tag + "]")})
+			}
+			continue
+		}
+		parts = append(parts, segment{tag: tag})
+		if tag == "goid" {
+			goid = true
+		}
+	}
+
+	tf := &textFormat{
+		src:     format,
+		color:   l.colorOn,
+		goid:    goid,
+		json:    l.json,
+		prefix:  l.prefix,
+		maxMsg:  l.maxMessage,
+		unknown: unknown,
+	}
+	theme := l.currentTheme()
+	for v, name := range levelNames {
+		switch l.levelStyle {
+		case LevelShort:
+			name = name[:1]
+		case LevelPadded:
+			name = fmt.Sprintf("%-5s", name)
+		}
+		// pad before coloring so the escape codes don't break the alignment,
+		// in full line mode the whole entry gets colored instead
+		if l.colorOn && l.colorScope == ColorLevelOnly {
+			name = colorize(theme.level(Level(v)), name)
+		}
+		tf.tokens[v] = name
+	}
+	if l.colorOn {
+		if l.colorScope == ColorFullLine {
+			tf.line = theme
+		} else {
+			tf.theme = theme
+		}
+	}
+	for v := range tf.levels {
+		tf.levels[v] = l.bake(tf, parts, Level(v))
+	}
+	return tf, nil
+}
+
+// bake renders the constant tags of parts for level v and merges the
+// adjacent static text.
+func (l *Logger) bake(tf *textFormat, parts []segment, v Level) []segment {
+	var segs []segment
+	var static bytes.Buffer
+	flush := func() {
+		if static.Len() > 0 {
+			segs = append(segs, segment{static: append([]byte(nil), static.Bytes()...)})
+			static.Reset()
+		}
+	}
+	for _, p := range parts {
+		switch {
+		case p.tag == "":
+			static.Write(p.static)
+		case constantTags[p.tag]:
+			l.renderTag(&static, tf, &Entry{Level: v}, p.tag)
+		default:
+			flush()
+			segs = append(segs, p)
+		}
+	}
+	flush()
+	return segs
+}
+
+// unknown tag policies, see SetUnknownTagPolicy
+const (
+	UnknownTagPlaceholder = iota // rendered as "[unknown tag x]"
+	UnknownTagEmpty              // rendered as nothing
+	UnknownTagError              // formats using one are rejected
+)
+
+var errUnknownTag = errors.New("log: unknown tag")
+
+// SetUnknownTagPolicy sets what becomes of the tags of a format which
+// don't exist, e.g. a typo or a tag of a newer version,
+// UnknownTagPlaceholder by default. With UnknownTagError, SetFormat and
+// ApplyConfig reject such formats; a format set before renders the
+// placeholder.
+func (l *Logger) SetUnknownTagPolicy(policy int) {
+	l.reformat(func() {
+		l.unknownTag = policy
+	})
+}
+
+func SetUnknownTagPolicy(policy int) {
+	global.SetUnknownTagPolicy(policy)
+}
+
+// checkTags returns the error of UnknownTagError for tf.
+func (l *Logger) checkTags(tf *textFormat) error {
+	if l.unknownTag != UnknownTagError || len(tf.unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("log: unknown tags in format: %s", strings.Join(tf.unknown, ", "))
+}
+
+// decoratePrefix renders p as "[p] ", nothing when empty, for
+// ${prefix_decorated}. A prefix which is already bracketed keeps its own.
+func decoratePrefix(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return ""
+	}
+	if strings.HasPrefix(p, "[") && strings.HasSuffix(p, "]") {
+		return p + " "
+	}
+	return "[" + p + "] "
+}
+
+// defaultFormat is defaultFormat without the parts hidden by ShowPID,
+// ShowPrefix and ShowCaller, separators included.
+func (l *Logger) defaultFormat() string {
+	format := defaultFormat
+	if l.hidePrefix {
+		format = strings.Replace(format, "${prefix_decorated}", "", 1)
+	}
+	if l.hidePID {
+		format = strings.Replace(format, "${pid}:", "", 1)
+	}
+	if l.hideCaller {
+		format = strings.Replace(format, "${mid_file}:${line}:", "", 1)
+	}
+	return format
+}
+
+// ShowPID includes the pid in the default format, true by default. Custom
+// formats are unaffected.
+func (l *Logger) ShowPID(show bool) {
+	l.reformat(func() {
+		l.hidePID = !show
+	})
+}
+
+// ShowPrefix includes the prefix in the default format, true by default.
+func (l *Logger) ShowPrefix(show bool) {
+	l.reformat(func() {
+		l.hidePrefix = !show
+	})
+}
+
+// ShowCaller includes the file and line in the default format, true by
+// default.
+func (l *Logger) ShowCaller(show bool) {
+	l.reformat(func() {
+		l.hideCaller = !show
+	})
+}
+
+func ShowPID(show bool) {
+	global.ShowPID(show)
+}
+
+func ShowPrefix(show bool) {
+	global.ShowPrefix(show)
+}
+
+func ShowCaller(show bool) {
+	global.ShowCaller(show)
+}
+
+// reformat applies change to the format settings and recompiles the
+// current format with them, serialized with the other changes.
+func (l *Logger) reformat(change func()) {
+	l.lazyInit()
+	l.reformats.Lock()
+	defer l.reformats.Unlock()
+
+	change()
+	l.template.Store(l.mustTemplate(l.template.Load().(*textFormat).src))
+}
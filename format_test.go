@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBakedTemplate(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${prefix}|${level}|${pid}|${message}\n")
+	l.SetPrefix("a")
+
+	for v, segs := range l.template.Load().(*textFormat).levels {
+		if len(segs) != 3 || segs[1].tag != "message" {
+			t.Fatalf("level %d: segments = %q, want the constant tags baked", v, segs)
+		}
+	}
+	pid := strconv.Itoa(os.Getpid())
+	if got, want := string(l.template.Load().(*textFormat).levels[WARN][0].static), "a|WARN|"+pid+"|"; got != want {
+		t.Errorf("baked WARN = %q, want %q", got, want)
+	}
+}
+
+func TestBakedTemplateFollowsTheConfig(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${prefix}|${level}|${message}\n")
+	l.SetPrefix("a")
+	l.Info("first")
+	l.SetPrefix("b")
+	l.Info("prefix")
+	l.SetLevelStyle(LevelShort)
+	l.Warn("style")
+	l.SetFormat("${level} ${prefix}: ${message}\n")
+	l.Error("format")
+	l.SetColorOverride(true)
+	l.Error("color")
+
+	want := []string{"a|INFO|first", "b|INFO|prefix", "b|W|style", "E b: format", colorize(defaultTheme.Error, "E") + " b: color"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestSetFormatUnterminatedTag(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	for _, f := range []string{"${message", "${level} ${", "${level}: ${message\n"} {
+		if err := l.SetFormat(f); err == nil || !strings.Contains(err.Error(), "invalid format") {
+			t.Errorf("SetFormat(%q) = %v, want an error", f, err)
+		}
+		if err := l.ApplyConfig(Config{Level: DEBUG, Format: f}); err == nil {
+			t.Errorf("ApplyConfig with %q = nil, want an error", f)
+		}
+	}
+	l.Info("kept")
+	if got := out.String(); got != "INFO kept\n" {
+		t.Errorf("output = %q, want the previous format kept", got)
+	}
+}
+
+func benchmarkFormatText(b *testing.B, baked bool) {
+	l := New("", INFO, 0, 0)
+	l.SetPrefix("app")
+	l.lazyInit()
+	tf := l.template.Load().(*textFormat)
+	if !baked {
+		// every tag rendered per entry, as before the constant ones were baked
+		unbaked := *tf
+		parts := []segment{{tag: "prefix_decorated"}, {tag: "time_local"}, {static: []byte(" ")}, {tag: "level"},
+			{static: []byte(":")}, {tag: "pid"}, {static: []byte(":")}, {tag: "mid_file"}, {static: []byte(":")},
+			{tag: "line"}, {static: []byte(": ")}, {tag: "message"}, {tag: "fields"}, {static: []byte("\n")}}
+		for v := range unbaked.levels {
+			unbaked.levels[v] = parts
+		}
+		tf = &unbaked
+	}
+	e := &Entry{Level: INFO, Time: time.Now(), File: "/src/app/main.go", Line: 42, Message: "saved", logger: l}
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		l.formatText(&buf, tf, e)
+	}
+}
+
+func BenchmarkFormatTextBaked(b *testing.B) {
+	benchmarkFormatText(b, true)
+}
+
+func BenchmarkFormatTextUnbaked(b *testing.B) {
+	benchmarkFormatText(b, false)
+}
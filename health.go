@@ -0,0 +1,72 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// SetErrorHandler receives the logger's internal errors (open, write and
+// rotate failures), by default they are printed to stderr. The handler may
+// be called with the logger's lock held and must not log through it.
+func (l *Logger) SetErrorHandler(handler func(err error)) {
+	l.errMutex.Lock()
+	defer l.errMutex.Unlock()
+
+	l.errorHandler = handler
+}
+
+// LastError returns the last internal error and when it happened, it's
+// cleared by the next successful write.
+func (l *Logger) LastError() (error, time.Time) {
+	l.errMutex.Lock()
+	defer l.errMutex.Unlock()
+
+	return l.lastErr, l.lastErrTime
+}
+
+// Healthy reports whether no internal error happened within window.
+func (l *Logger) Healthy(window time.Duration) bool {
+	err, at := l.LastError()
+	return err == nil || time.Since(at) > window
+}
+
+func (l *Logger) handleError(err error) {
+	l.diag("error: %v", err)
+	l.errMutex.Lock()
+	l.lastErr = err
+	l.lastErrTime = time.Now()
+	atomic.StoreInt32(&l.hasErr, 1)
+	handler := l.errorHandler
+	l.errMutex.Unlock()
+
+	if handler != nil {
+		handler(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "log: %v\n", err)
+}
+
+func (l *Logger) clearError() {
+	// cheap check first, this runs after every write
+	if atomic.LoadInt32(&l.hasErr) == 0 {
+		return
+	}
+	l.errMutex.Lock()
+	l.lastErr = nil
+	atomic.StoreInt32(&l.hasErr, 0)
+	l.errMutex.Unlock()
+}
+
+func SetErrorHandler(handler func(err error)) {
+	global.SetErrorHandler(handler)
+}
+
+func LastError() (error, time.Time) {
+	return global.LastError()
+}
+
+func Healthy(window time.Duration) bool {
+	return global.Healthy(window)
+}
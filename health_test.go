@@ -0,0 +1,66 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingWriter fails every write while err is set.
+type failingWriter struct {
+	mutex sync.Mutex
+	err   error
+	out   syncBuffer
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.out.Write(p)
+}
+
+func (w *failingWriter) fail(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.err = err
+}
+
+func TestLastError(t *testing.T) {
+	w := &failingWriter{}
+	l := New("", INFO, 0, 0)
+	l.SetOutput(w)
+	var handled []error
+	l.SetErrorHandler(func(err error) { handled = append(handled, err) })
+
+	if err, at := l.LastError(); err != nil || !at.IsZero() || !l.Healthy(time.Minute) {
+		t.Fatalf("LastError = %v, %v before any error", err, at)
+	}
+
+	diskFull := errors.New("disk full")
+	w.fail(diskFull)
+	before := time.Now()
+	l.Info("lost")
+	err, at := l.LastError()
+	if !errors.Is(err, diskFull) || at.Before(before) || len(handled) != 1 {
+		t.Fatalf("LastError = %v, %v, handled %v", err, at, handled)
+	}
+	if l.Healthy(time.Minute) {
+		t.Error("Healthy within the window of the error")
+	}
+	if !l.Healthy(0) {
+		t.Error("unhealthy once the window has passed")
+	}
+
+	// the next successful write clears it
+	w.fail(nil)
+	l.Info("written")
+	if err, _ := l.LastError(); err != nil || !l.Healthy(time.Minute) {
+		t.Errorf("LastError = %v after a successful write", err)
+	}
+}
@@ -0,0 +1,186 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// Hook is notified of every written entry at one of its levels,
+// e.g. to ship errors to an alerting service. Hooks of a Logger observe
+// the entries in the same order as they are written, one entry at a time,
+// so a slow hook slows down the logging of every goroutine. The entry is
+// recycled once the logging call returns: copy what outlives Fire.
+type Hook interface {
+	Levels() []Level
+	Fire(e *Entry) error
+}
+
+func (l *Logger) AddHook(h Hook) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	hooks, _ := l.hooks.Load().([]Hook)
+	l.hooks.Store(append(hooks[:len(hooks):len(hooks)], h))
+}
+
+func AddHook(h Hook) {
+	global.AddHook(h)
+}
+
+// AddWriteHook adds a hook fired after the entry was written successfully,
+// where AddHook hooks are fired before the write: shippers get at least
+// once delivery from the former and at most once from the latter. The
+// written bytes are available from Entry.Rendered.
+func (l *Logger) AddWriteHook(h Hook) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	hooks, _ := l.writeHooks.Load().([]Hook)
+	l.writeHooks.Store(append(hooks[:len(hooks):len(hooks)], h))
+}
+
+func AddWriteHook(h Hook) {
+	global.AddWriteHook(h)
+}
+
+// Rendered returns the bytes written for the entry, as is (colors, line
+// ending), within the Fire of a write hook, nil otherwise. They belong to
+// a pooled buffer: copy them to keep them after Fire returns.
+func (e *Entry) Rendered() []byte {
+	return e.rendered
+}
+
+func (l *Logger) fireWriteHooks(e *Entry, b []byte) {
+	hooks, _ := l.writeHooks.Load().([]Hook)
+	if len(hooks) == 0 {
+		return
+	}
+	e.rendered = b
+	l.guard(func() { l.runHooks(hooks, e) })
+	e.rendered = nil
+}
+
+func (l *Logger) hasHooks() bool {
+	hooks, _ := l.hooks.Load().([]Hook)
+	writeHooks, _ := l.writeHooks.Load().([]Hook)
+	return len(hooks) > 0 || len(writeHooks) > 0
+}
+
+func (l *Logger) fireHooks(e *Entry) {
+	hooks, _ := l.hooks.Load().([]Hook)
+	if len(hooks) == 0 {
+		return
+	}
+	l.guard(func() { l.runHooks(hooks, e) })
+}
+
+// guard marks the goroutine as running user code called by the logger
+// (hooks, the FATAL callback) so entries it logs don't recurse, see reentered.
+func (l *Logger) guard(fn func()) {
+	id := goid()
+	atomic.AddInt32(&l.hooksActive, 1)
+	l.inHooks.Store(id, struct{}{})
+	defer func() {
+		l.inHooks.Delete(id)
+		atomic.AddInt32(&l.hooksActive, -1)
+	}()
+	fn()
+}
+
+// reentered reports whether e was logged from within a hook on the same
+// goroutine, such entries would loop or deadlock so they only go to stderr.
+func (l *Logger) reentered(e *Entry) bool {
+	if _, ok := l.inHooks.Load(goid()); !ok {
+		return false
+	}
+	atomic.AddUint64(&l.nested, 1)
+	l.diag("dropped entry logged from a hook: %s %s:%d", levelName(e.Level), midFile(e.File), e.Line)
+	fmt.Fprintf(os.Stderr, "log: dropped entry logged from a hook: %s %s:%d: %s\n", levelName(e.Level), midFile(e.File), e.Line, e.Message)
+	return true
+}
+
+func (l *Logger) runHooks(hooks []Hook, e *Entry) {
+	for _, h := range hooks {
+		for _, v := range h.Levels() {
+			if v != e.Level {
+				continue
+			}
+			if err := h.Fire(e); err != nil {
+				l.handleError(err)
+			}
+			break
+		}
+	}
+}
+
+// SetFingerprinter overrides how entries are grouped, see Entry.Fingerprint.
+func (l *Logger) SetFingerprinter(fn func(e *Entry) string) {
+	l.fingerprinter = fn
+}
+
+func SetFingerprinter(fn func(e *Entry) string) {
+	global.SetFingerprinter(fn)
+}
+
+// Fingerprint identifies entries which are the same problem: by default a
+// hash of the level, the caller file:line and the event key, or the format
+// string (the message for unformatted calls) when there's no event.
+func (e *Entry) Fingerprint() string {
+	if e.fingerprint != "" {
+		return e.fingerprint
+	}
+	if e.logger != nil && e.logger.fingerprinter != nil {
+		e.materialize()
+		e.fingerprint = e.logger.fingerprinter(e)
+		return e.fingerprint
+	}
+
+	key := e.Event
+	if key == "" {
+		key = e.format
+	}
+	if key == "" {
+		key = e.Message
+	}
+	h := fnv.New64a()
+	h.Write([]byte(levelName(e.Level)))
+	h.Write([]byte{0})
+	h.Write([]byte(e.File))
+	h.Write([]byte(":" + strconv.Itoa(e.Line)))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	e.fingerprint = strconv.FormatUint(h.Sum64(), 16)
+	return e.fingerprint
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goid parses the current goroutine id from the stack header,
+// "goroutine 18 [running]:". It costs about a microsecond.
+func goid() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// EnableGoroutineID captures the id of the logging goroutine, rendered by
+// ${goid} and as "goid" in JSON. Go doesn't expose it, so it's parsed from
+// runtime.Stack for every entry, about a microsecond: it's opt-in, and
+// skipped when neither the format nor JSON uses it.
+func (l *Logger) EnableGoroutineID(enabled bool) {
+	l.goroutineID = enabled
+}
+
+func EnableGoroutineID(enabled bool) {
+	global.EnableGoroutineID(enabled)
+}
@@ -0,0 +1,186 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHookLoggingIsDropped(t *testing.T) {
+	stderr := redirect(t, &os.Stderr)
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.AddHook(funcHook(func(e *Entry) error {
+		l.Error("from the hook")
+		return nil
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Error("outer")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a hook logging through its logger deadlocked")
+	}
+
+	if got := out.String(); got != "ERROR outer\n" {
+		t.Errorf("output = %q, want only the outer entry", got)
+	}
+	if n := l.Stats().Nested; n != 1 {
+		t.Errorf("Nested = %d, want 1", n)
+	}
+	if got := stderr(); !strings.Contains(got, "dropped entry logged from a hook") || !strings.Contains(got, "from the hook") {
+		t.Errorf("stderr = %q, want a diagnostic", got)
+	}
+
+	// the guard is released after each entry
+	l.Error("again")
+	if n := l.Stats().Nested; n != 2 {
+		t.Errorf("Nested = %d, want 2", n)
+	}
+	if got := out.String(); !strings.HasSuffix(got, "ERROR again\n") {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${goid}|${message}\n")
+	l.Info("disabled")
+	l.EnableGoroutineID(true)
+	l.Info("enabled")
+	var other uint64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		other = goid()
+		l.Info("other")
+	}()
+	<-done
+
+	lines := out.lines()
+	id := strconv.FormatUint(goid(), 10)
+	if len(lines) != 3 || lines[0] != "|disabled" || lines[1] != id+"|enabled" {
+		t.Fatalf("lines = %q, want the id %s once enabled", lines, id)
+	}
+	if other == goid() || lines[2] != strconv.FormatUint(other, 10)+"|other" {
+		t.Errorf("lines = %q, want the other goroutine's id %d", lines, other)
+	}
+
+	var js syncBuffer
+	l = newTestLogger(&js)
+	l.EnableJSON()
+	l.Info("no id")
+	l.EnableGoroutineID(true)
+	l.Info("id")
+	lines = js.lines()
+	if strings.Contains(lines[0], `"goid"`) || !strings.Contains(lines[1], `"goid":`+id) {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func benchmarkGoroutineID(b *testing.B, enabled bool) {
+	l := New("", INFO, 0, 0)
+	l.SetOutput(ioutil.Discard)
+	l.SetFormat("${goid} ${level} ${message}\n")
+	l.EnableGoroutineID(enabled)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("saved")
+	}
+}
+
+// BenchmarkGoroutineID shows the cost of parsing the stack header per
+// entry, compare with BenchmarkGoroutineIDDisabled.
+func BenchmarkGoroutineID(b *testing.B) {
+	benchmarkGoroutineID(b, true)
+}
+
+func BenchmarkGoroutineIDDisabled(b *testing.B) {
+	benchmarkGoroutineID(b, false)
+}
+
+func TestHooksSeeTheFileOrder(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	l.SetFormat("${message}\n")
+	var fired []string
+	l.AddHook(funcHook(func(e *Entry) error {
+		fired = append(fired, e.Message)
+		return nil
+	}))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 300; i++ {
+				l.Infof("g%d-%d", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	written := strings.Split(strings.TrimSuffix(readFile(t, name), "\n"), "\n")
+	if len(written) != 8*300 || strings.Join(written, "\n") != strings.Join(fired, "\n") {
+		t.Errorf("hooks saw %d entries, %d written in another order", len(fired), len(written))
+	}
+}
+
+// TestWriteHookSeesTheWrittenBytes checks that Rendered holds exactly
+// what landed in the file, at the offset of the entry.
+func TestWriteHookSeesTheWrittenBytes(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, DEBUG, 0, 0)
+	l.SetExitFunc(func(int) {})
+	l.SetLineEnding("\r\n")
+	l.SetFormat("${offset} ${level} ${message}${fields}\n")
+	var rendered []byte
+	before := 0
+	l.AddHook(funcHook(func(e *Entry) error {
+		if e.Rendered() != nil {
+			t.Errorf("Rendered = %q before the write", e.Rendered())
+		}
+		before++
+		return nil
+	}))
+	l.AddWriteHook(funcHook(func(e *Entry) error {
+		if e.Offset != len(rendered) || e.Filename != name {
+			t.Errorf("entry at %s:%d, want %s:%d", e.Filename, e.Offset, name, len(rendered))
+		}
+		rendered = append(rendered, e.Rendered()...)
+		return nil
+	}))
+
+	l.Debug("debug")
+	l.WithField("k", "v w").Info("with fields")
+	l.Warnf("%d%%", 100)
+	l.Print("print")
+	l.EnableJSON()
+	l.Error("json\nmessage")
+	l.Fatal("fatal with a stack")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, name); string(rendered) != got {
+		t.Errorf("hooks saw %q\nfile holds %q", rendered, got)
+	}
+	if before != 6 {
+		t.Errorf("%d entries seen before the write, want 6", before)
+	}
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const footerMark = "# integrity "
+
+// WithIntegrityFooter ends every rotated file with a footer holding the
+// number of entries and the SHA-256 of the content before it, computed
+// as the entries are written. Check archives with VerifyLogFile.
+func WithIntegrityFooter(enabled bool) Option {
+	return func(l *Logger) {
+		l.integrity = enabled
+	}
+}
+
+// enableHash starts hashing the writes to s, including what the file
+// already holds, s.mutex must be held.
+func (s *sharedFile) enableHash(eol string) {
+	if s.hash != nil {
+		return
+	}
+	s.hash = sha256.New()
+	s.hashExisting(eol)
+}
+
+// hashExisting restarts the hash with the content of the file just opened.
+func (s *sharedFile) hashExisting(eol string) {
+	s.hash.Reset()
+	s.entries = 0
+	if s.size == 0 {
+		return
+	}
+	if f, err := os.Open(s.name); err == nil {
+		io.Copy(s.hash, f)
+		f.Close()
+	}
+	s.entries = countLines(s.name, eol)
+}
+
+// writeFooterLocked ends the file with the integrity footer, f.mutex must
+// be held. The footer itself isn't hashed.
+func (l *Logger) writeFooterLocked(f *sharedFile) {
+	if f.hash == nil {
+		return
+	}
+	footer := l.footer(f.entries, hex.EncodeToString(f.hash.Sum(nil)))
+	if _, err := writeFull(f.f, []byte(footer)); err != nil {
+		l.handleError(err)
+	}
+}
+
+func (l *Logger) footer(entries int, sum string) string {
+	if l.template.Load().(*textFormat).json {
+		return fmt.Sprintf(`{"integrity":{"entries":%d,"sha256":"%s"}}`, entries, sum) + l.eol()
+	}
+	return fmt.Sprintf("%sentries=%d sha256=%s", footerMark, entries, sum) + l.eol()
+}
+
+// footerSize is the size of the footer of f once b is written, which the
+// size limit must leave room for, 0 without a footer.
+func (l *Logger) footerSize(f *sharedFile, b ...[]byte) int {
+	if f.hash == nil {
+		return 0
+	}
+	entries := f.entries
+	for _, p := range b {
+		entries += bytes.Count(p, []byte(l.eol()))
+	}
+	return len(l.footer(entries, strings.Repeat("0", sha256.Size*2)))
+}
+
+// moved reports whether the name of s now points to another file, or none.
+func moved(s *sharedFile) bool {
+	fi, err := s.f.Stat()
+	if err != nil {
+		return false
+	}
+	cur, err := os.Stat(s.name)
+	return err != nil || !os.SameFile(fi, cur)
+}
+
+// VerifyLogFile checks the integrity footer of a file rotated by l with
+// WithIntegrityFooter, its lines ending as set by SetLineEnding. Archives
+// compressed by a registered Decompressor codec (gzip, zstd once its
+// package is imported) are read back.
+func (l *Logger) VerifyLogFile(path string) error {
+	return verifyLogFile(path, l.eol())
+}
+
+func VerifyLogFile(path string) error {
+	return global.VerifyLogFile(path)
+}
+
+func verifyLogFile(path, eol string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if _, ext := splitExtension(path); ext != "" {
+		d, ok := decompressor(ext)
+		if !ok {
+			return fmt.Errorf("log: no codec reads %s archives", ext)
+		}
+		rc, err := d.Decompress(f)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		r = rc
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// the footer is the last line
+	body := bytes.TrimSuffix(b, []byte(eol))
+	i := bytes.LastIndex(body, []byte(eol)) + len(eol)
+	if i < len(eol) {
+		i = 0
+	}
+	content, footer := b[:i], string(body[i:])
+	var entries int
+	var want string
+	if strings.HasPrefix(footer, "{") {
+		var v struct {
+			Integrity *struct {
+				Entries int    `json:"entries"`
+				Sha256  string `json:"sha256"`
+			} `json:"integrity"`
+		}
+		if json.Unmarshal([]byte(footer), &v) != nil || v.Integrity == nil {
+			return fmt.Errorf("log: %s has no integrity footer", path)
+		}
+		entries, want = v.Integrity.Entries, v.Integrity.Sha256
+	} else {
+		if !strings.HasPrefix(footer, footerMark) {
+			return fmt.Errorf("log: %s has no integrity footer", path)
+		}
+		for _, kv := range strings.Fields(footer[len(footerMark):]) {
+			switch {
+			case strings.HasPrefix(kv, "entries="):
+				entries, _ = strconv.Atoi(kv[len("entries="):])
+			case strings.HasPrefix(kv, "sha256="):
+				want = kv[len("sha256="):]
+			}
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != want {
+		return fmt.Errorf("log: %s doesn't match its integrity footer (%d entries)", path, entries)
+	}
+	if n := bytes.Count(content, []byte(eol)); n != entries {
+		return fmt.Errorf("log: %s holds %d entries, its integrity footer says %d", path, n, entries)
+	}
+	return nil
+}
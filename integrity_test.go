@@ -0,0 +1,149 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// rotatedArchive logs a few entries with the integrity footer, rotates
+// and returns the archive.
+func rotatedArchive(t *testing.T, setup func(l *Logger)) (*Logger, string) {
+	t.Helper()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, INFO, 0, 5, WithIntegrityFooter(true))
+	t.Cleanup(func() { l.Close() })
+	setup(l)
+	for i := 0; i < 3; i++ {
+		l.Infof("entry %d", i)
+	}
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	archives, _ := filepath.Glob(name + ".1*")
+	if len(archives) != 1 {
+		t.Fatalf("archives = %v", archives)
+	}
+	return l, archives[0]
+}
+
+func TestVerifyLogFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(l *Logger)
+	}{
+		{"text", func(l *Logger) {}},
+		{"json", func(l *Logger) { l.EnableJSON() }},
+		{"crlf", func(l *Logger) { l.SetLineEnding("\r\n") }},
+		{"nul", func(l *Logger) { l.SetLineEnding("\x00") }},
+		{"gzip", func(l *Logger) { l.SetCompression(Gzip) }},
+		{"gzip crlf", func(l *Logger) {
+			l.SetCompression(Gzip)
+			l.SetLineEnding("\r\n")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, archive := rotatedArchive(t, tt.setup)
+			if err := l.VerifyLogFile(archive); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestVerifyLogFileDetectsTampering(t *testing.T) {
+	l, archive := rotatedArchive(t, func(l *Logger) { l.SetLineEnding("\r\n") })
+	b, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(b), "entry 1", "entry 7", 1)
+	if err := ioutil.WriteFile(archive, []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.VerifyLogFile(archive); err == nil {
+		t.Error("a tampered archive verified")
+	}
+}
+
+func TestVerifyLogFileChecksTheEntryCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(l *Logger)
+	}{
+		{"text", func(l *Logger) {}},
+		{"json", func(l *Logger) { l.EnableJSON() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, archive := rotatedArchive(t, tt.setup)
+			b, err := ioutil.ReadFile(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the content and its hash are untouched, only the count lies
+			count := regexp.MustCompile(`entries"?[=:](\d+)`)
+			m := count.FindSubmatch(b)
+			if m == nil {
+				t.Fatalf("no entry count in %q", b)
+			}
+			n, _ := strconv.Atoi(string(m[1]))
+			tampered := count.ReplaceAll(b, []byte(strings.Replace(string(m[0]), string(m[1]), strconv.Itoa(n+1), 1)))
+			if err := ioutil.WriteFile(archive, tampered, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.VerifyLogFile(archive); err == nil || !strings.Contains(err.Error(), "entries") {
+				t.Errorf("VerifyLogFile = %v, want the count rejected", err)
+			}
+		})
+	}
+}
+
+// TestIntegrityFooterFitsTheMaxSize checks that rotated files, footer
+// included, stay under the size limit when every entry fits with it.
+func TestIntegrityFooterFitsTheMaxSize(t *testing.T) {
+	for _, json := range []bool{false, true} {
+		name := filepath.Join(t.TempDir(), "app.log")
+		l := New(name, INFO, 0, 1000, WithMaxSize(KB), WithIntegrityFooter(true))
+		if json {
+			l.EnableJSON()
+		}
+		for i := 0; i < 60; i++ {
+			l.Info(strings.Repeat("x", i*i%700))
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		archives, _ := filepath.Glob(name + ".*")
+		if len(archives) < 10 {
+			t.Fatalf("archives = %v, the test needs rotations", archives)
+		}
+		for _, archive := range archives {
+			fi, err := os.Stat(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() > int64(KB) {
+				t.Errorf("json %v: %s is %d bytes, over the max size", json, archive, fi.Size())
+			}
+			if err := l.VerifyLogFile(archive); err != nil {
+				t.Errorf("json %v: %v", json, err)
+			}
+		}
+	}
+}
+
+func TestVerifyLogFileWithoutFooter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1")
+	ioutil.WriteFile(path, []byte("no footer\n"), 0644)
+	if err := VerifyLogFile(path); err == nil || !strings.Contains(err.Error(), "no integrity footer") {
+		t.Errorf("VerifyLogFile = %v", err)
+	}
+}
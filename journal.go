@@ -0,0 +1,52 @@
+package log
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// sd-daemon priorities, see sd-daemon(3)
+var journalPriority = []string{
+	DEBUG: "<7>",
+	INFO:  "<6>",
+	WARN:  "<4>",
+	ERROR: "<3>",
+	FATAL: "<2>",
+}
+
+// EnableJournalPrefix prepends the sd-daemon "<N>" priority to every entry
+// when the output is stdout or stderr redirected away from a terminal, so
+// journald records the right priority when running under systemd.
+func (l *Logger) EnableJournalPrefix(enabled bool) {
+	l.journalPrefix = enabled
+}
+
+func EnableJournalPrefix(enabled bool) {
+	global.EnableJournalPrefix(enabled)
+}
+
+// journalTargeted reports whether the output of l is stdout or stderr away
+// from a terminal, the parent's output for a child writing through it.
+func (l *Logger) journalTargeted() bool {
+	// bounded like Level, a cycle of parents isn't a journal
+	for i := 0; i < maxLevelDepth; i++ {
+		switch atomic.LoadInt32(&l.journalTarget) {
+		case 1:
+			return true
+		case 2:
+			if l.parent == nil {
+				return false
+			}
+			l = l.parent()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func isStdStream(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && (f.Fd() == os.Stdout.Fd() || f.Fd() == os.Stderr.Fd())
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestJournalPrefix(t *testing.T) {
+	for _, std := range []**os.File{&os.Stdout, &os.Stderr} {
+		read := redirect(t, std)
+		l := New("", DEBUG, 0, 0)
+		l.SetOutput(*std)
+		l.SetFormat("${level} ${message}\n")
+		l.SetExitFunc(func(int) {})
+		l.SetStackPolicy(StackEntry)
+		l.EnableJournalPrefix(true)
+		l.Debug("d")
+		l.Info("i")
+		l.Warn("w")
+		l.Error("e")
+		l.Fatal("f")
+
+		lines := strings.Split(strings.TrimSuffix(read(), "\n"), "\n")
+		want := []string{"<7>DEBUG d", "<6>INFO i", "<4>WARN w", "<3>ERROR e", "<2>FATAL f"}
+		if len(lines) < len(want) || strings.Join(lines[:len(want)], "\n") != strings.Join(want, "\n") {
+			t.Errorf("lines = %q, want %q first", lines, want)
+		}
+	}
+}
+
+func TestJournalPrefixTargets(t *testing.T) {
+	// a wrapper exposing the descriptor of stdout
+	redirect(t, &os.Stdout)
+	w := &fdWriter{fd: os.Stdout.Fd()}
+	l := New("", INFO, 0, 0)
+	l.SetFormat("${message}\n")
+	l.SetOutput(w)
+	l.EnableJournalPrefix(true)
+	l.Info("wrapped")
+	l.EnableJournalPrefix(false)
+	l.Info("disabled")
+
+	// not a standard stream
+	var out syncBuffer
+	l.EnableJournalPrefix(true)
+	l.SetOutput(&out)
+	l.Info("buffered")
+
+	if got := w.String(); got != "<6>wrapped\ndisabled\n" {
+		t.Errorf("wrapped output = %q", got)
+	}
+	if got := out.String(); got != "buffered\n" {
+		t.Errorf("output = %q, want no priority", got)
+	}
+}
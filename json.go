@@ -0,0 +1,331 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var reservedKeys = map[string]bool{
+	"time": true, "level": true, "pid": true, "prefix": true, "caller": true,
+	"event": true, "fingerprint": true, "msg": true, "stack": true,
+	"level_num": true, "delta_ms": true, "v": true, "goid": true,
+}
+
+// JSONConfig tunes the JSON output, see EnableJSON.
+type JSONConfig struct {
+	Fingerprint bool // add the entry fingerprint as "fingerprint"
+	Delta       bool // add the time since the previous entry as "delta_ms"
+	LevelNum    bool // add the numeric level as "level_num"
+
+	// SchemaVersion is written as "v" in every entry when > 0.
+	SchemaVersion int
+	// EmitSchemaHeader writes a {"schema":{"version":..,"keys":[..]}} line
+	// listing the keys in use before the first entry of every file, after
+	// opening it and after each rotation.
+	EmitSchemaHeader bool
+}
+
+func (l *Logger) SetJSONConfig(c JSONConfig) {
+	l.jsonConfig = c
+}
+
+func SetJSONConfig(c JSONConfig) {
+	global.SetJSONConfig(c)
+}
+
+// formatJSON renders e as a single line JSON object terminated by '\n'.
+// Every string is escaped as encoding/json does, so newlines and control
+// characters in messages or stacks can never break the line.
+func (l *Logger) formatJSON(buf *bytes.Buffer, tf *textFormat, e *Entry) error {
+	buf.WriteByte('{')
+	if v := l.jsonConfig.SchemaVersion; v > 0 {
+		buf.WriteString(`"v":`)
+		buf.WriteString(strconv.Itoa(v))
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`"time":`)
+	writeJSONTime(buf, e.Time)
+	buf.WriteString(`,"level":`)
+	writeJSONString(buf, levelLower(e.Level))
+	if l.jsonConfig.LevelNum {
+		buf.WriteString(`,"level_num":`)
+		buf.WriteString(strconv.Itoa(int(e.Level)))
+	}
+	buf.WriteString(`,"pid":`)
+	buf.WriteString(pid)
+	if e.goid != 0 {
+		buf.WriteString(`,"goid":`)
+		buf.WriteString(strconv.FormatUint(e.goid, 10))
+	}
+	if p := tf.prefix; p != "" {
+		buf.WriteString(`,"prefix":`)
+		writeJSONString(buf, p)
+	}
+	buf.WriteString(`,"caller":"`)
+	writeJSONChars(buf, midFile(e.File))
+	buf.WriteByte(':')
+	writeJSONInt(buf, int64(e.Line))
+	buf.WriteByte('"')
+	if e.Event != "" {
+		buf.WriteString(`,"event":`)
+		writeJSONString(buf, e.Event)
+	}
+	if l.jsonConfig.Fingerprint {
+		buf.WriteString(`,"fingerprint":`)
+		writeJSONString(buf, e.Fingerprint())
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONString(buf, e.Message)
+
+	if len(e.Fields) > 0 {
+		keys := make([]string, 0, len(e.Fields))
+		for k := range e.Fields {
+			if !e.typed(k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeJSONKey(buf, k)
+			writeJSONValue(buf, e.Fields[k])
+		}
+	}
+	for i := range e.fields {
+		writeJSONKey(buf, e.fields[i].key)
+		writeJSONField(buf, &e.fields[i])
+	}
+
+	if l.jsonConfig.Delta {
+		buf.WriteString(`,"delta_ms":`)
+		buf.WriteString(strconv.FormatFloat(float64(e.delta)/float64(time.Millisecond), 'f', 3, 64))
+	}
+	if e.Stack != "" {
+		buf.WriteString(`,"stack":`)
+		writeJSONString(buf, e.Stack)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// writeJSONKey writes ,"key": for a field, prefixed with "fields." when
+// the key is one of the entry's own.
+func writeJSONKey(buf *bytes.Buffer, k string) {
+	buf.WriteString(`,"`)
+	if reservedKeys[k] {
+		buf.WriteString("fields.")
+	}
+	writeJSONChars(buf, k)
+	buf.WriteString(`":`)
+}
+
+// writeJSONField writes the value of a typed field as writeJSONValue
+// would, without boxing it.
+func writeJSONField(buf *bytes.Buffer, f *field) {
+	switch f.kind {
+	case fieldString:
+		writeJSONString(buf, f.str)
+	case fieldInt, fieldInt64, fieldDuration:
+		writeJSONInt(buf, f.num)
+	case fieldBool:
+		if f.num != 0 {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case fieldTime:
+		writeJSONTime(buf, f.value().(time.Time))
+	case fieldError:
+		writeJSONString(buf, f.any.(error).Error())
+	default:
+		writeJSONValue(buf, f.value())
+	}
+}
+
+func writeJSONInt(buf *bytes.Buffer, n int64) {
+	var a [20]byte
+	buf.Write(strconv.AppendInt(a[:0], n, 10))
+}
+
+// writeJSONTime writes t in RFC 3339 with nanoseconds, as encoding/json.
+func writeJSONTime(buf *bytes.Buffer, t time.Time) {
+	if y := t.Year(); y < 0 || y >= 10000 {
+		writeJSONValue(buf, t)
+		return
+	}
+	var a [64]byte
+	buf.WriteByte('"')
+	buf.Write(t.AppendFormat(a[:0], time.RFC3339Nano))
+	buf.WriteByte('"')
+}
+
+// writeJSONString quotes s as marshalJSON does, without allocating:
+// invalid UTF-8 becomes U+FFFD, and the C0 and C1 control characters,
+// U+2028 and U+2029 are escaped.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	writeJSONChars(buf, s)
+	buf.WriteByte('"')
+}
+
+// writeJSONChars is writeJSONString without the quotes.
+func writeJSONChars(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		r, n := rune(c), 1
+		if c >= utf8.RuneSelf {
+			r, n = utf8.DecodeRuneInString(s[i:])
+			if r > 0x9f && r != 0x2028 && r != 0x2029 && (r != utf8.RuneError || n > 1) {
+				i += n
+				continue
+			}
+		}
+		buf.WriteString(s[start:i])
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(byte(r))
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\b':
+			buf.WriteString(`\b`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == utf8.RuneError:
+			buf.WriteString("\ufffd")
+		default:
+			buf.WriteString(`\u`)
+			buf.WriteByte(hex[r>>12&0xf])
+			buf.WriteByte(hex[r>>8&0xf])
+			buf.WriteByte(hex[r>>4&0xf])
+			buf.WriteByte(hex[r&0xf])
+		}
+		i += n
+		start = i
+	}
+	buf.WriteString(s[start:])
+}
+
+// marshalJSON is json.Marshal without the HTML escaping of <, > and &.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return escapeJSONControl(bytes.TrimSuffix(b.Bytes(), []byte("\n"))), nil
+}
+
+// writeJSONValue marshals v, values encoding/json can't handle are
+// rendered with fmt instead of dropping the entry.
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	// the common types are written directly, as encoding/json would
+	switch v := v.(type) {
+	case error:
+		writeJSONString(buf, v.Error())
+		return
+	case string:
+		writeJSONString(buf, v)
+		return
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+		return
+	case int:
+		writeJSONInt(buf, int64(v))
+		return
+	case int64:
+		writeJSONInt(buf, v)
+		return
+	case time.Duration:
+		writeJSONInt(buf, int64(v))
+		return
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			writeJSONFloat(buf, v)
+			return
+		}
+	case time.Time:
+		if y := v.Year(); y >= 0 && y < 10000 {
+			writeJSONTime(buf, v)
+			return
+		}
+	}
+	b, err := marshalJSON(v)
+	if err != nil {
+		writeJSONString(buf, fmt.Sprint(v))
+		return
+	}
+	buf.Write(b)
+}
+
+// writeJSONFloat formats f like encoding/json: the shortest
+// representation, with an exponent only for very large or small values.
+func writeJSONFloat(buf *bytes.Buffer, f float64) {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	var a [32]byte
+	b := strconv.AppendFloat(a[:0], f, format, -1, 64)
+	// 1e-07 is written 1e-7
+	if n := len(b); format == 'e' && n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+		b[n-2] = b[n-1]
+		b = b[:n-1]
+	}
+	buf.Write(b)
+}
+
+// schemaHeader describes the keys formatJSON writes with the current
+// configuration, fields aside.
+func (l *Logger) schemaHeader() []byte {
+	c := l.jsonConfig
+	var keys []string
+	if c.SchemaVersion > 0 {
+		keys = append(keys, "v")
+	}
+	keys = append(keys, "time", "level")
+	if c.LevelNum {
+		keys = append(keys, "level_num")
+	}
+	keys = append(keys, "pid")
+	if l.goroutineID {
+		keys = append(keys, "goid")
+	}
+	if l.template.Load().(*textFormat).prefix != "" {
+		keys = append(keys, "prefix")
+	}
+	keys = append(keys, "caller", "event")
+	if c.Fingerprint {
+		keys = append(keys, "fingerprint")
+	}
+	keys = append(keys, "msg")
+	if c.Delta {
+		keys = append(keys, "delta_ms")
+	}
+	keys = append(keys, "stack")
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"schema":{"version":`)
+	buf.WriteString(strconv.Itoa(c.SchemaVersion))
+	buf.WriteString(`,"keys":`)
+	writeJSONValue(&buf, keys)
+	buf.WriteString("}}\n")
+	l.terminate(&buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestJSONFileIsNDJSON checks that every line of rotated JSON files decodes
+// as one object, whatever the messages, fields and FATAL stacks contain,
+// entries replayed from the ring included.
+func TestJSONFileIsNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, INFO, 0, 100, WithMaxSize(2*KB))
+	l.EnableJSON()
+	l.EnableRingBuffer(5, DEBUG)
+	l.SetExitFunc(func(int) {})
+	messages := []string{
+		"plain",
+		"two\nlines",
+		"carriage\r\nreturn",
+		"tab\tand nul\x00",
+		"line\u2028separator",
+		"bad utf-8 \xff",
+		`quote " and backslash \`,
+	}
+	for i := 0; i < 20; i++ {
+		for _, m := range messages {
+			l.WithField("raw", m).Info(m)
+			l.Debug(m)
+		}
+		l.Error("replays the ring")
+	}
+	l.Fatal("fatal\nwith a stack")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(name + "*")
+	if len(files) < 2 {
+		t.Fatalf("files = %v, the test needs rotations", files)
+	}
+	entries, replayed, stacks := 0, 0, 0
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var v map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+				t.Errorf("%s: line %q: %v", file, scanner.Text(), err)
+			}
+			if _, ok := v["msg"]; ok {
+				entries++
+			}
+			if v["replayed"] == true {
+				replayed++
+			}
+			if stack, ok := v["stack"].(string); ok && strings.Contains(stack, "goroutine") {
+				stacks++
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := 20*(len(messages)+1+5) + 1; entries < want {
+		t.Errorf("%d entries, want at least %d", entries, want)
+	}
+	if replayed != 20*5 || stacks != 1 {
+		t.Errorf("%d replayed entries, %d stacks, want %d and 1", replayed, stacks, 20*5)
+	}
+}
+
+func TestJSONEscapesMessages(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.Info("a\nb c")
+
+	line := out.String()
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+		t.Fatalf("output = %q, want a single line", line)
+	}
+	var v struct {
+		Msg string `json:"msg"`
+	}
+	if err := json.Unmarshal([]byte(line), &v); err != nil || v.Msg != "a\nb c" {
+		t.Errorf("msg = %q, %v", v.Msg, err)
+	}
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"fmt"
+)
+
+const badKey = "!BADKEY"
+
+// kvFields turns alternating key/value pairs into fields, a dangling value
+// is kept under "!BADKEY" instead of being dropped.
+func kvFields(kv []interface{}) Fields {
+	if len(kv) == 0 {
+		return nil
+	}
+	fields := make(Fields, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			fields[badKey] = kv[i]
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields[key] = kv[i+1]
+	}
+	return fields
+}
+
+func (l *Logger) logw(v Level, msg string, kv []interface{}) {
+	l.emit(Entry{Level: v, Fields: kvFields(kv)}, 3, "", []interface{}{msg})
+}
+
+// Debugw logs msg with kv as alternating key/value fields,
+// e.g. Infow("user login", "user", id, "ip", addr).
+func (l *Logger) Debugw(msg string, kv ...interface{}) {
+	l.logw(DEBUG, msg, kv)
+}
+
+func (l *Logger) Infow(msg string, kv ...interface{}) {
+	l.logw(INFO, msg, kv)
+}
+
+func (l *Logger) Warnw(msg string, kv ...interface{}) {
+	l.logw(WARN, msg, kv)
+}
+
+func (l *Logger) Errorw(msg string, kv ...interface{}) {
+	l.logw(ERROR, msg, kv)
+}
+
+func (l *Logger) Fatalw(msg string, kv ...interface{}) {
+	l.logw(FATAL, msg, kv)
+	l.exit()
+}
+
+func Debugw(msg string, kv ...interface{}) {
+	global.logw(DEBUG, msg, kv)
+}
+
+func Infow(msg string, kv ...interface{}) {
+	global.logw(INFO, msg, kv)
+}
+
+func Warnw(msg string, kv ...interface{}) {
+	global.logw(WARN, msg, kv)
+}
+
+func Errorw(msg string, kv ...interface{}) {
+	global.logw(ERROR, msg, kv)
+}
+
+func Fatalw(msg string, kv ...interface{}) {
+	global.logw(FATAL, msg, kv)
+	global.exit()
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKVFields(t *testing.T) {
+	tests := []struct {
+		name string
+		kv   []interface{}
+		want Fields
+	}{
+		{"none", nil, nil},
+		{"pairs", []interface{}{"user", "alice", "n", 3}, Fields{"user": "alice", "n": 3}},
+		{"dangling value", []interface{}{"user", "alice", "extra"}, Fields{"user": "alice", badKey: "extra"}},
+		{"single value", []interface{}{42}, Fields{badKey: 42}},
+		{"key not a string", []interface{}{7, "seven"}, Fields{"7": "seven"}},
+	}
+	for _, tt := range tests {
+		if got := kvFields(tt.kv); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: kvFields = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInfow(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.Infow("user login", "user", "alice", "ip", "10.0.0.1")
+	l.Errorw("odd", "code", 500, "dangling")
+	l.Debugw("none")
+
+	var entries []map[string]interface{}
+	for _, line := range out.lines() {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, v)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v", entries)
+	}
+	if e := entries[0]; e["msg"] != "user login" || e["user"] != "alice" || e["ip"] != "10.0.0.1" {
+		t.Errorf("entry = %v", e)
+	}
+	if e := entries[1]; e["level"] != "error" || e["msg"] != "odd" || e["code"] != 500.0 || e[badKey] != "dangling" {
+		t.Errorf("entry = %v", e)
+	}
+	if e := entries[2]; e["msg"] != "none" || len(e) != len(entries[0])-2 {
+		t.Errorf("entry = %v, want no fields", e)
+	}
+}
+
+func TestPackageInfow(t *testing.T) {
+	old := GetLogger()
+	defer SetLogger(old)
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} ${message}${fields}\n")
+	l.SetExitFunc(func(int) {})
+	SetLogger(l)
+
+	Debugw("d", "k", 1)
+	Infow("i", "k", 2)
+	Warnw("w", "k", 3)
+	Errorw("e", "k", 4)
+	Fatalw("f", "k", 5)
+
+	want := []string{"DEBUG d k=1", "INFO i k=2", "WARN w k=3", "ERROR e k=4", "FATAL f"}
+	got := out.lines()
+	if len(got) < len(want) {
+		t.Fatalf("lines = %q, want %q", got, want)
+	}
+	// the stack is part of the FATAL message, the fields follow it
+	if strings.Join(got[:len(want)], "\n") != strings.Join(want, "\n") || !strings.HasSuffix(got[len(got)-1], " k=5") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
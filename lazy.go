@@ -0,0 +1,71 @@
+package log
+
+import (
+	"fmt"
+)
+
+// LazyValue is a field value computed only when the entry is written,
+// see Lazy.
+type LazyValue func() interface{}
+
+// Lazy defers an expensive field value until the entry passed the level
+// check and every filter, then fn is called once for the entry and its result used by
+// every output and hook. A panic in fn is logged as an error value:
+//
+//	l.WithField("state", log.Lazy(func() interface{} { return dump() })).Debug("tick")
+func Lazy(fn func() interface{}) LazyValue {
+	return fn
+}
+
+func (fn LazyValue) resolve() (v interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = fmt.Errorf("log: lazy field panicked: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// resolveLazy replaces the lazy values of fields, in a copy when there
+// are any since fields may be shared between entries.
+func resolveLazy(fields Fields) Fields {
+	lazy := false
+	for _, v := range fields {
+		if _, ok := v.(LazyValue); ok {
+			lazy = true
+			break
+		}
+	}
+	if !lazy {
+		return fields
+	}
+	resolved := make(Fields, len(fields))
+	for k, v := range fields {
+		if fn, ok := v.(LazyValue); ok {
+			v = fn.resolve()
+		}
+		resolved[k] = v
+	}
+	return resolved
+}
+
+// resolveTyped is resolveLazy for the typed fields, see Entry.Any.
+func resolveTyped(fields []field) []field {
+	lazy := false
+	for i := range fields {
+		if _, ok := fields[i].any.(LazyValue); ok && fields[i].kind == fieldAny {
+			lazy = true
+			break
+		}
+	}
+	if !lazy {
+		return fields
+	}
+	resolved := append([]field(nil), fields...)
+	for i := range resolved {
+		if fn, ok := resolved[i].any.(LazyValue); ok && resolved[i].kind == fieldAny {
+			resolved[i].any = fn.resolve()
+		}
+	}
+	return resolved
+}
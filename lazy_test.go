@@ -0,0 +1,100 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// funcHook fires fn for every level.
+type funcHook func(e *Entry) error
+
+func (funcHook) Levels() []Level {
+	return []Level{DEBUG, INFO, WARN, ERROR, FATAL}
+}
+
+func (h funcHook) Fire(e *Entry) error {
+	return h(e)
+}
+
+func TestLazyNotResolvedWhenDropped(t *testing.T) {
+	var calls int32
+	lazy := Lazy(func() interface{} {
+		atomic.AddInt32(&calls, 1)
+		return "computed"
+	})
+	_, file, _, _ := runtime.Caller(0)
+
+	tests := []struct {
+		name  string
+		setup func(l *Logger)
+		log   func(l *Logger)
+	}{
+		{"level", func(l *Logger) { l.SetLevel(WARN) }, func(l *Logger) {
+			l.WithField("k", lazy).Info("hello")
+		}},
+		{"filter", func(l *Logger) { l.AddFilter(func(*Entry) bool { return false }) }, func(l *Logger) {
+			l.WithField("k", lazy).Info("hello")
+		}},
+		{"caller", func(l *Logger) { l.DenyCallerPrefix(file) }, func(l *Logger) {
+			l.WithField("k", lazy).Info("hello")
+		}},
+		{"empty", func(l *Logger) { l.SetEmptyMessagePolicy(EmptyDrop) }, func(l *Logger) {
+			l.WithField("k", lazy).Info("")
+		}},
+		{"message filter", func(l *Logger) {
+			l.AddMessageFilter(func(e *Entry) bool { return !strings.Contains(e.Message, "secret") })
+		}, func(l *Logger) {
+			l.WithField("k", lazy).Info("secret")
+		}},
+		{"reentry", func(l *Logger) {
+			l.AddHook(funcHook(func(e *Entry) error {
+				if e.Message == "outer" {
+					l.WithField("k", lazy).Info("inner")
+				}
+				return nil
+			}))
+		}, func(l *Logger) {
+			l.Info("outer")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			tt.setup(l)
+			atomic.StoreInt32(&calls, 0)
+			tt.log(l)
+			if n := atomic.LoadInt32(&calls); n != 0 {
+				t.Errorf("lazy field computed %d times for a dropped entry", n)
+			}
+		})
+	}
+}
+
+func TestLazyResolvedOnce(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} ${message}${fields}\n")
+	var calls int32
+	var seen interface{}
+	l.AddHook(funcHook(func(e *Entry) error {
+		seen = e.Fields["k"]
+		return nil
+	}))
+	l.WithField("k", Lazy(func() interface{} {
+		atomic.AddInt32(&calls, 1)
+		return "computed"
+	})).Info("hello")
+
+	if calls != 1 {
+		t.Errorf("lazy field computed %d times, want 1", calls)
+	}
+	if seen != "computed" {
+		t.Errorf("hook saw %v", seen)
+	}
+	if got := out.String(); !strings.Contains(got, "computed") {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLazyOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0, WithLazyOpen(true))
+	defer l.Close()
+	l.SetFormat("${message}\n")
+
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("Stat = %v, want no file before the first write", err)
+	}
+	if st := l.Stats(); st.Opened || st.Filename != name || st.Size != 0 {
+		t.Errorf("Stats = %+v before the first write", st)
+	}
+	if err := l.Reopen(); err != nil {
+		t.Errorf("Reopen = %v", err)
+	}
+	l.Debug("dropped")
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("Stat = %v, want no file after Reopen and a dropped entry", err)
+	}
+
+	l.Info("first")
+	if st := l.Stats(); !st.Opened || st.Size != len("first\n") {
+		t.Errorf("Stats = %+v after the first write", st)
+	}
+	if got := readFile(t, name); got != "first\n" {
+		t.Errorf("file = %q", got)
+	}
+}
+
+func TestLazyOpenError(t *testing.T) {
+	stderr := redirect(t, &os.Stderr)
+	name := filepath.Join(t.TempDir(), "missing", "app.log")
+	l := New(name, INFO, 0, 0, WithLazyOpen(true))
+	l.SetFormat("${message}\n")
+	var handled []error
+	l.SetErrorHandler(func(err error) { handled = append(handled, err) })
+
+	l.Info("fallback")
+	if len(handled) != 1 || !os.IsNotExist(handled[0]) {
+		t.Errorf("handled %v, want the open error", handled)
+	}
+	if got := stderr(); !strings.Contains(got, "fallback\n") {
+		t.Errorf("stderr = %q, want the entry", got)
+	}
+	if l.Stats().Opened {
+		t.Error("Opened after a failed open")
+	}
+}
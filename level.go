@@ -0,0 +1,132 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func (v Level) String() string {
+	if v == OFF {
+		return "OFF"
+	}
+	return levelName(v)
+}
+
+// ParseLevel returns the level named s, in any case, e.g. "warn" or "OFF".
+func ParseLevel(s string) (Level, error) {
+	for v := DEBUG; v <= OFF; v++ {
+		if strings.EqualFold(s, v.String()) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("log: unknown level %q", s)
+}
+
+// MarshalText renders the level in lowercase, e.g. "warn".
+func (v Level) MarshalText() ([]byte, error) {
+	if v < DEBUG || v > OFF {
+		return nil, fmt.Errorf("log: invalid level %d", int(v))
+	}
+	return []byte(strings.ToLower(v.String())), nil
+}
+
+func (v *Level) UnmarshalText(text []byte) error {
+	parsed, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts a name, as UnmarshalText, or a number.
+func (v *Level) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		if Level(n) < DEBUG || Level(n) > OFF {
+			return fmt.Errorf("log: invalid level %d", n)
+		}
+		*v = Level(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("log: invalid level %s", b)
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// levelScopes are the temporary levels of SetLevelFor still running.
+type levelScopes struct {
+	mutex  sync.Mutex
+	base   int32 // level before the first scope, possibly levelUnset
+	active []*levelScope
+}
+
+type levelScope struct {
+	level Level
+	file  string // where it was set, also the caller of the end entry
+	line  int
+}
+
+// SetLevelFor sets the level to v for d, or until cancel is called, then
+// restores it, logging an INFO entry when it starts and ends. Overlapping
+// scopes stack: the level is the one of the latest scope still running,
+// and the one from before the first is restored after the last, SetLevel
+// calls in between being overridden.
+func (l *Logger) SetLevelFor(v Level, d time.Duration) (cancel func()) {
+	return l.setLevelFor(v, d)
+}
+
+func SetLevelFor(v Level, d time.Duration) (cancel func()) {
+	return global.setLevelFor(v, d)
+}
+
+func (l *Logger) setLevelFor(v Level, d time.Duration) func() {
+	scope := &levelScope{level: v}
+	_, scope.file, scope.line, _ = runtime.Caller(2)
+	s := &l.levelScopes
+	s.mutex.Lock()
+	if len(s.active) == 0 {
+		s.base = atomic.LoadInt32(&l.level)
+	}
+	s.active = append(s.active, scope)
+	l.SetLevel(v)
+	s.mutex.Unlock()
+	l.emit(Entry{Level: INFO, Forced: true, File: scope.file, Line: scope.line}, 0, "log: level set to %s for %s", []interface{}{v, d})
+
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			l.endLevelScope(scope)
+		})
+	}
+	t := time.AfterFunc(d, end)
+	return func() {
+		t.Stop()
+		end()
+	}
+}
+
+func (l *Logger) endLevelScope(scope *levelScope) {
+	s := &l.levelScopes
+	s.mutex.Lock()
+	for i, a := range s.active {
+		if a == scope {
+			s.active = append(s.active[:i], s.active[i+1:]...)
+			break
+		}
+	}
+	if n := len(s.active); n > 0 {
+		l.SetLevel(s.active[n-1].level)
+	} else {
+		atomic.StoreInt32(&l.level, s.base)
+	}
+	s.mutex.Unlock()
+	l.emit(Entry{Level: INFO, Forced: true, File: scope.file, Line: scope.line}, 0, "log: temporary level %s ended, level back to %s", []interface{}{scope.level, l.Level()})
+}
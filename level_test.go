@@ -0,0 +1,66 @@
+package log
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		v       Level
+		name    string
+		valid   bool
+		set     Level // the level SetLevel(v) ends up with
+		clamped bool
+	}{
+		{math.MinInt32, "LEVEL(-2147483648)", false, DEBUG, true},
+		{-100, "LEVEL(-100)", false, DEBUG, true},
+		{-1, "LEVEL(-1)", false, DEBUG, true},
+		{DEBUG, "DEBUG", true, DEBUG, false},
+		{INFO, "INFO", true, INFO, false},
+		{WARN, "WARN", true, WARN, false},
+		{ERROR, "ERROR", true, ERROR, false},
+		{FATAL, "FATAL", true, FATAL, false},
+		{OFF, "LEVEL(5)", false, OFF, false},
+		{6, "LEVEL(6)", false, OFF, true},
+		{42, "LEVEL(42)", false, OFF, true},
+		{math.MaxInt32, "LEVEL(2147483647)", false, OFF, true},
+	}
+	for _, tt := range tests {
+		if got := validLevel(tt.v); got != tt.valid {
+			t.Errorf("validLevel(%d) = %v, want %v", tt.v, got, tt.valid)
+		}
+		if got := levelName(tt.v); got != tt.name {
+			t.Errorf("levelName(%d) = %q, want %q", tt.v, got, tt.name)
+		}
+		if got := levelLower(tt.v); got != strings.ToLower(tt.name) {
+			t.Errorf("levelLower(%d) = %q, want %q", tt.v, got, strings.ToLower(tt.name))
+		}
+
+		var out syncBuffer
+		l := newTestLogger(&out)
+		err := l.SetLevel(tt.v)
+		if (err != nil) != tt.clamped || l.Level() != tt.set {
+			t.Errorf("SetLevel(%d) = %v, level %d, want %d", tt.v, err, l.Level(), tt.set)
+		}
+		if !l.Healthy(time.Hour) {
+			t.Errorf("SetLevel(%d) marked the logger unhealthy", tt.v)
+		}
+
+		l.SetLevel(DEBUG)
+		err = l.Log(tt.v, 1, "m")
+		if perr := l.Plain(tt.v, "m"); (perr == nil) != (err == nil) {
+			t.Errorf("level %d: Log = %v, Plain = %v", tt.v, err, perr)
+		}
+		if tt.valid {
+			got := out.String()
+			if err != nil || !strings.HasPrefix(got, tt.name+" m\n") || !strings.HasSuffix(got, "\nm\n") {
+				t.Errorf("Log(%d) = %v, output = %q", tt.v, err, out.String())
+			}
+		} else if err == nil || out.String() != "" {
+			t.Errorf("Log(%d) = %v, output = %q, want it rejected", tt.v, err, out.String())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(WARN)
+	cancel := l.SetLevelFor(DEBUG, time.Hour)
+	l.Debug("verbose")
+	cancel()
+	cancel()
+	l.Debug("quiet again")
+
+	// the entries are written whatever the level
+	want := []string{
+		"INFO log: level set to DEBUG for 1h0m0s",
+		"DEBUG verbose",
+		"INFO log: temporary level DEBUG ended, level back to WARN",
+	}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestSetLevelForExpires(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(WARN)
+	l.SetLevelFor(DEBUG, 10*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Level() != WARN {
+		if time.Now().After(deadline) {
+			t.Fatal("the level is never restored")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for len(out.lines()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := out.lines(); len(got) != 2 || !strings.Contains(got[1], "ended, level back to WARN") {
+		t.Errorf("lines = %q", got)
+	}
+}
+
+func TestSetLevelForStacks(t *testing.T) {
+	l := newTestLogger(&syncBuffer{})
+	l.SetLevel(WARN)
+	first := l.SetLevelFor(INFO, time.Hour)
+	second := l.SetLevelFor(DEBUG, time.Hour)
+	l.SetLevel(ERROR)
+
+	// the first one ending, the latest still running wins
+	first()
+	if got := l.Level(); got != DEBUG {
+		t.Errorf("level = %s after the first ended, want DEBUG", got)
+	}
+	third := l.SetLevelFor(INFO, time.Hour)
+	third()
+	if got := l.Level(); got != DEBUG {
+		t.Errorf("level = %s after the third ended, want DEBUG", got)
+	}
+	// the level from before the first, not the SetLevel in between
+	second()
+	if got := l.Level(); got != WARN {
+		t.Errorf("level = %s after the last ended, want WARN", got)
+	}
+}
+
+func TestSetLevelForCaller(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${short_file} ${message}\n")
+	old := GetLogger()
+	defer SetLogger(old)
+	SetLogger(l)
+	SetLevelFor(DEBUG, time.Hour)()
+	l.SetLevelFor(DEBUG, time.Hour)()
+	for _, line := range out.lines() {
+		if !strings.HasPrefix(line, "levelfor_test.go ") {
+			t.Errorf("line %q, want the caller of SetLevelFor", line)
+		}
+	}
+	if n := len(out.lines()); n != 4 {
+		t.Errorf("%d lines, want 4", n)
+	}
+}
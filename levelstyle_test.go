@@ -0,0 +1,79 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func logEveryLevel(l *Logger) {
+	l.Debug("m")
+	l.Info("m")
+	l.Warn("m")
+	l.Error("m")
+}
+
+func TestLevelStyle(t *testing.T) {
+	tests := []struct {
+		style int
+		want  []string
+	}{
+		{LevelFull, []string{"DEBUG|m", "INFO|m", "WARN|m", "ERROR|m"}},
+		{LevelShort, []string{"D|m", "I|m", "W|m", "E|m"}},
+		{LevelPadded, []string{"DEBUG|m", "INFO |m", "WARN |m", "ERROR|m"}},
+	}
+	for _, tt := range tests {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.SetFormat("${level}|${message}\n")
+		l.SetLevelStyle(tt.style)
+		logEveryLevel(l)
+		if got := out.lines(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("style %d: lines = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestLevelStylePadsBeforeColoring(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level}|${message}\n")
+	l.SetLevelStyle(LevelPadded)
+	l.SetColorOverride(true)
+	logEveryLevel(l)
+
+	theme := defaultTheme
+	want := []string{
+		colorize(theme.Debug, "DEBUG") + "|m",
+		colorize(theme.Info, "INFO ") + "|m",
+		colorize(theme.Warn, "WARN ") + "|m",
+		colorize(theme.Error, "ERROR") + "|m",
+	}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestLevelStyleJSON(t *testing.T) {
+	for _, style := range []int{LevelFull, LevelShort, LevelPadded} {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.SetLevelStyle(style)
+		l.EnableJSON()
+		logEveryLevel(l)
+
+		var levels []string
+		for _, line := range out.lines() {
+			var v struct {
+				Level string `json:"level"`
+			}
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				t.Fatal(err)
+			}
+			levels = append(levels, v.Level)
+		}
+		if got := strings.Join(levels, " "); got != "debug info warn error" {
+			t.Errorf("style %d: levels = %q, want the lowercase full names", style, got)
+		}
+	}
+}
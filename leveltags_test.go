@@ -0,0 +1,54 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPlainLevelTags(t *testing.T) {
+	for _, color := range []bool{false, true} {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.SetFormat("${level_lower} ${level_upper_plain} ${level_num}\n")
+		l.SetLevelStyle(LevelShort)
+		l.SetColorOverride(color)
+		logEveryLevel(l)
+
+		// color-free, whatever the level style
+		want := []string{"debug DEBUG 0", "info INFO 1", "warn WARN 2", "error ERROR 3"}
+		if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("color %v: lines = %q, want %q", color, got, want)
+		}
+	}
+
+	// ${level} is left as is for display
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level}|${level_lower}\n")
+	l.SetColorOverride(true)
+	l.Warn("m")
+	if got, want := out.String(), colorize(defaultTheme.Warn, "WARN")+"|warn\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestJSONLevelNum(t *testing.T) {
+	for _, levelNum := range []bool{false, true} {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.EnableJSON()
+		l.SetJSONConfig(JSONConfig{LevelNum: levelNum})
+		l.SetColorOverride(true)
+		l.Error("m")
+
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(out.String()), &v); err != nil {
+			t.Fatal(err)
+		}
+		num, ok := v["level_num"]
+		if v["level"] != "error" || ok != levelNum || ok && num != float64(ERROR) {
+			t.Errorf("LevelNum %v: entry = %v", levelNum, v)
+		}
+	}
+}
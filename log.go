@@ -2,6 +2,7 @@ package log
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,34 +14,139 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/gommon/color"
-	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
-	"github.com/valyala/fasttemplate"
 )
 
 type (
+	// Logger is usually built with New, the zero value is usable too and
+	// logs everything to stdout with the default format.
+	//
+	// The logging methods are safe for concurrent use: every entry is
+	// written whole with a single write, never split or interleaved, and
+	// rotation is serialized with the writes, also across Loggers sharing
+	// a file. SetOutput, SetLevel, SetFormat and SetPrefix may be called
+	// while logging; the other setters are meant for setting the Logger
+	// up before it's shared between goroutines.
 	Logger struct {
+		counts     [FATAL + 1]uint64      // first for 64-bit alignment of the atomics
+		lastEntry  int64                  // monotonic nanoseconds since start, for ${delta}
+		nested     uint64                 // entries logged from within hooks, see reentered
+		dropped    uint64                 // entries lost to write errors, see WithRetry
+		suppressed [suppressCauses]uint64 // see Suppressed
+		traceUntil int64                  // UnixNano, see TraceSuppression
+		initOnce   sync.Once
 		prefix     string
-		level      int
+		level      int32          // atomic, see SetLevel, levelUnset follows parent
+		parent     func() *Logger // see Child
 		output     io.Writer
-		template   *fasttemplate.Template
-		levels     []string
+		template   atomic.Value // *textFormat, swapped by SetFormat
 		color      *color.Color
-		filename   string // filename
-		backups    int    // max backup
-		size       int    // current size
-		maxsize    int    // maxsize per file
+		filename   string        // filename, placeholders expanded
+		pattern    string        // filename as given, see expandFilename
+		file       *sharedFile   // shared with other Loggers on the same path
+		backups    int           // max backup
+		maxsize    int           // bytes per file, 0 for no limit
+		maxAge     time.Duration // of the archives, see WithMaxAge
 		bufferPool sync.Pool
 		mutex      sync.Mutex
-		callbacks  map[int]func(msg string)
+		order      sync.Mutex // serializes hooks with the writes, see AddHook
+		reformats  sync.Mutex // serializes the changes of the format settings
+		callbacks  map[Level]func(msg string)
+
+		ring        *ringBuffer
+		ringLevel   Level // lowest level captured into the ring
+		ringTrigger Level // level which replays the ring
+		levelStyle  int
+
+		colorOverride *bool // bypasses tty detection when set
+		buildInfoKeys []string
+		maxMessage    int // truncate messages longer than this, 0 means no limit
+
+		currentSymlink bool
+		lazyOpen       bool
+		truncate       bool
+		syncWrites     bool
+		integrity      bool
+		closeSummary   bool
+		archiveDir     string
+		archiveNaming  string // see SetArchiveNaming, empty for SuffixAfterExt
+		datedArchives  bool
+		retry          RetryPolicy
+		schedule       *Schedule     // see WithSchedule
+		scheduleStop   chan struct{} // closed by closeOutput
+		codec          Codec
+		maxLines       int
+		lines          int // lines in the active file, tracked when maxLines is set
+		lineEnding     string
+		exitFunc       func(code int)
+		fatalBehavior  int
+		stderr         bool         // console output goes to stderr instead of stdout
+		fatalMsg       atomic.Value // last FATAL message, for FatalPanic
+		journalPrefix  bool
+		journalTarget  int32        // 1 when output is stdout/stderr and not a tty, 2 for the parent's
+		hooks          atomic.Value // []Hook, copied on AddHook
+		writeHooks     atomic.Value // []Hook, see AddWriteHook
+		outputs        atomic.Value // []*levelOutput, see AddOutputLevel
+		callerRules    atomic.Value // []callerRule, longest prefix first
+		filters        atomic.Value // []Filter, see AddFilter
+		messageFilters atomic.Value // []Filter, see AddMessageFilter
+		classifiers    atomic.Value // []func(error) (Level, bool), see ClassifyError
+		callerLevel    Level        // caller rules apply below it
+		fingerprinter  func(e *Entry) string
+		ctxExtractor   func(ctx context.Context) Fields
+		levelMapper    func(scheme string, external int) Level
+		fieldFormatter func(key string, value interface{}) string
+		signalFunc     func(sig os.Signal)
+		theme          *Theme // nil for defaultTheme
+		sanitize       bool
+		stackPolicy    int
+		stackMarker    string
+		crashFile      string // see WithCrashFile
+		hidePID        bool   // see ShowPID, ShowPrefix and ShowCaller
+		hidePrefix     bool
+		hideCaller     bool
+		goroutineID    bool
+		emptyMessage   int
+		unknownTag     int
+		jsonConfig     JSONConfig
+		colorOn        bool
+		colorChange    func(enabled bool)
+		colorNotified  int32     // 1 when OnColorChange last saw color enabled
+		hooksActive    int32     // goroutines currently running hooks
+		inHooks        sync.Map  // goroutine id -> struct{}
+		start          time.Time // for ${uptime}
+		uptimeWidth    int
+		timedLevel     Level
+		json           bool
+		colorScope     int
+
+		errMutex     sync.Mutex
+		errorHandler func(err error)
+		lastErr      error
+		lastErrTime  time.Time
+		hasErr       int32
+		nilWarned    int32
+
+		diagnostics diagnostics // see DumpDiagnostics
+		levelScopes levelScopes // see SetLevelFor
+		timeCache   [cachedTimes]timeCache
 	}
 )
 
 const (
-	DEBUG = iota
+	levelUnset    = -1 // a child follows its parent's level, see Child
+	maxLevelDepth = 32 // parents consulted at most by Level
+)
+
+// Level is the severity of an entry, or the minimum of a logger.
+type Level int
+
+const (
+	DEBUG Level = iota
 	INFO
 	WARN
 	ERROR
@@ -48,46 +154,105 @@ const (
 	OFF
 )
 
+// level token styles
+const (
+	LevelFull   = iota // INFO, ERROR
+	LevelShort         // I, E
+	LevelPadded        // "INFO ", "ERROR"
+)
+
+// empty message policies, see SetEmptyMessagePolicy
+const (
+	EmptyEmit        = iota // written as is
+	EmptyDrop               // not written, except FATAL
+	EmptyPlaceholder        // written as "(empty)"
+)
+
+const emptyPlaceholder = "(empty)"
+
+// color scopes, see SetColorScope
+const (
+	ColorLevelOnly = iota
+	ColorFullLine
+)
+
 var (
-	global    = New("", INFO, 0, 0)
+	global    = newGlobal()
 	timeLocal = "2006-01-02 15:04:05.999"
 	//defaultFormat = "time=${time_rfc3339}, level=${level}, prefix=${prefix}, file=${short_file}, " +
 	//	"line=${line}, message=${message}\n"
-	defaultFormat = "${prefix}${time_local} ${level}:${pid}:${mid_file}:${line}: ${message}\n"
+	defaultFormat = "${prefix_decorated}${time_local} ${level}:${pid}:${mid_file}:${line}: ${message}${fields}\n"
 	pid           = ""
 	megabyte      = 1024 * 1024
+	timeApache    = "02/Jan/2006:15:04:05 -0700"
 )
 
+// ApacheCombined renders entries in the Apache/NCSA combined access log
+// format, request values are taken from the entry fields of the same name
+// and missing ones render as "-".
+const ApacheCombined = "${remote_addr} - ${remote_user} [${time_apache}] \"${request}\" ${status} ${body_bytes} \"${referer}\" \"${user_agent}\"\n"
+
 func init() {
 	pid = strconv.Itoa(os.Getpid())
 }
 
-func New(filename string, level, maxsize, backups int) (l *Logger) {
+// New returns a Logger writing to filename, stdout when empty, rotated
+// once it reaches maxsize megabytes keeping backups archives, 0 meaning
+// no size limit. Prefer WithMaxSize, which takes a Size: New(name, INFO,
+// 0, 7, WithMaxSize(100*MB)).
+func New(filename string, level Level, maxsize, backups int, opts ...Option) (l *Logger) {
 	l = &Logger{
-		level:    level,
+		level:    int32(level),
 		prefix:   "",
-		filename: filename,
+		pattern:  filename,
+		filename: expandFilename(filename, time.Now()),
 		maxsize:  maxsize * megabyte,
 		backups:  backups,
-		template: l.newTemplate(defaultFormat),
-		color:    color.New(),
-		bufferPool: sync.Pool{
-			New: func() interface{} {
-				return bytes.NewBuffer(make([]byte, 256))
-			},
-		},
-	}
-	l.callbacks = make(map[int]func(msg string))
-	l.initLevels()
-	l.DisableColor()
-	if l.filename != "" {
+	}
+	l.lazyInit()
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.filename == "" {
+		l.SetOutput(l.console())
+	} else if !l.lazyOpen {
 		l.open()
 	} else {
-		l.SetOutput(colorable.NewColorableStdout())
+		// decided as for the file now, the first write doesn't change it
+		l.detectColor(nil)
+	}
+	if l.schedule != nil && l.filename != "" {
+		l.startSchedule()
 	}
 	return
 }
 
+// lazyInit sets up the internal state, for loggers not created by New
+// it runs on first use.
+func (l *Logger) lazyInit() {
+	l.initOnce.Do(func() {
+		l.start = time.Now()
+		l.color = color.New()
+		l.ringTrigger = ERROR
+		l.timedLevel = INFO
+		l.callerLevel = ERROR
+		l.bufferPool.New = func() interface{} {
+			return bytes.NewBuffer(make([]byte, 256))
+		}
+		if l.template.Load() == nil {
+			l.template.Store(l.mustTemplate(defaultFormat))
+		}
+		if l.callbacks == nil {
+			l.callbacks = make(map[Level]func(msg string))
+		}
+		l.color.Disable()
+		if l.output == nil && l.filename == "" {
+			l.output = l.console()
+			l.setJournalTarget(l.output)
+		}
+	})
+}
+
 func SetLogger(l *Logger) {
 	global = l
 }
@@ -96,161 +261,500 @@ func GetLogger() *Logger {
 	return global
 }
 
-func SetCallback(level int, callback func(msg string)) {
+func SetFile(filename string) {
+	global.SetFile(filename)
+}
+
+func SetCallback(level Level, callback func(msg string)) {
 	global.SetCallback(level, callback)
 }
 
-func (l *Logger) SetCallback(level int, callback func(msg string)) {
+func (l *Logger) SetCallback(level Level, callback func(msg string)) {
+	l.lazyInit()
 	l.callbacks[level] = callback
 }
 
-func (l *Logger) open() {
-	f, err := os.OpenFile(l.filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, os.ModePerm)
-	if err != nil {
-		l.Error(err)
+// SetFile switches the output to filename, placeholders are expanded as
+// in New. An empty filename goes back to stdout.
+func (l *Logger) SetFile(filename string) {
+	defer l.notifyColor()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.pattern = filename
+	l.filename = expandFilename(filename, time.Now())
+	if l.filename != "" {
+		if !l.lazyOpen || l.file != nil {
+			l.open()
+		}
 		return
 	}
-	fi, err := os.Stat(l.filename)
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	l.setOutputLocked(l.console())
+}
+
+func (l *Logger) open() error {
+	var err error
+	if s := l.file; s != nil && s.key == fileKey(l.filename) {
+		s.mutex.Lock()
+		err = s.openLocked(l.filename, false, l.eol(), l.maxLines > 0)
+		s.mutex.Unlock()
+	} else {
+		s, err = acquireFile(l.filename, l.truncate, l.syncWrites, l.eol(), l.maxLines > 0)
+		if err == nil {
+			if l.file != nil {
+				l.file.Close()
+			}
+			l.file = s
+		}
+	}
 	if err != nil {
-		l.Error(err)
-		return
+		l.handleError(err)
+		return err
 	}
-	l.size = int(fi.Size())
-	l.SetOutput(f)
+	l.diag("opened %s", l.filename)
+	l.recoverRotations(l.file)
+	if l.integrity {
+		l.file.mutex.Lock()
+		l.file.enableHash(l.eol())
+		l.file.mutex.Unlock()
+	}
+	// only the first open truncates, reopens and rotations append
+	l.truncate = false
+	l.setOutputLocked(l.file)
+	if l.currentSymlink {
+		l.linkCurrent()
+	}
+	return nil
+}
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// validLevel reports whether entries can be logged at v.
+func validLevel(v Level) bool {
+	return v >= DEBUG && v <= FATAL
 }
 
-func (l *Logger) initLevels() {
-	l.levels = []string{
-		l.color.Blue("DEBUG"),
-		l.color.Green("INFO"),
-		l.color.Yellow("WARN"),
-		l.color.Red("ERROR"),
-		l.color.RedBg("FATAL"),
+var levelLowerNames = []string{"debug", "info", "warn", "error", "fatal"}
+
+// levelLower is levelName in lower case, without allocating.
+func levelLower(v Level) string {
+	if !validLevel(v) {
+		return strings.ToLower(levelName(v))
 	}
+	return levelLowerNames[v]
 }
 
-func (l *Logger) newTemplate(format string) *fasttemplate.Template {
-	return fasttemplate.New(format, "${", "}")
+// levelName is the name of v, with a fallback for invalid levels.
+func levelName(v Level) string {
+	if !validLevel(v) {
+		return "LEVEL(" + strconv.Itoa(int(v)) + ")"
+	}
+	return levelNames[v]
 }
 
 func (l *Logger) DisableColor() {
-	l.color.Disable()
-	l.initLevels()
+	l.setColor(false)
+	l.notifyColor()
 }
 
 func (l *Logger) EnableColor() {
-	l.color.Enable()
-	l.initLevels()
+	l.setColor(true)
+	l.notifyColor()
+}
+
+// setColor only records the change, notifyColor tells the callback once
+// l.mutex is released.
+func (l *Logger) setColor(on bool) {
+	l.reformat(func() {
+		l.colorOn = on
+		if on {
+			l.color.Enable()
+		} else {
+			l.color.Disable()
+		}
+	})
+}
+
+// notifyColor calls the OnColorChange callback if color was enabled or
+// disabled since it last did. l.mutex must not be held, the callback may
+// log.
+func (l *Logger) notifyColor() {
+	on := l.ColorEnabled()
+	var state int32
+	if on {
+		state = 1
+	}
+	if atomic.SwapInt32(&l.colorNotified, state) == state {
+		return
+	}
+	if fn := l.colorChange; fn != nil {
+		fn(on)
+	}
+}
+
+// ColorEnabled reports whether entries are colored, after the terminal
+// detection of SetOutput and SetColorOverride.
+func (l *Logger) ColorEnabled() bool {
+	l.lazyInit()
+	return l.template.Load().(*textFormat).color
+}
+
+// OnColorChange calls fn whenever color gets enabled or disabled, e.g. to
+// keep other output of the program consistent with the logger.
+func (l *Logger) OnColorChange(fn func(enabled bool)) {
+	l.colorChange = fn
+}
+
+func (l *Logger) SetLevelStyle(style int) {
+	l.reformat(func() {
+		l.levelStyle = style
+	})
+}
+
+// EnableJSON switches the output to one JSON object per line (NDJSON),
+// the format template is ignored while enabled.
+func (l *Logger) EnableJSON() {
+	l.reformat(func() {
+		l.json = true
+	})
+}
+
+func (l *Logger) DisableJSON() {
+	l.reformat(func() {
+		l.json = false
+	})
+}
+
+// SetColorScope selects whether only the level token or the whole
+// entry line is colored by severity.
+func (l *Logger) SetColorScope(scope int) {
+	l.reformat(func() {
+		l.colorScope = scope
+	})
 }
 
 func (l *Logger) Prefix() string {
-	return l.prefix
+	l.lazyInit()
+	return l.template.Load().(*textFormat).prefix
 }
 
 func (l *Logger) SetPrefix(p string) {
-	l.prefix = p
+	l.reformat(func() {
+		l.prefix = p
+	})
+}
+
+// Level returns the effective level, the parent's for a child which
+// inherits it, see Child.
+func (l *Logger) Level() Level {
+	// bounded, a cycle of parents falls back to INFO
+	for i := 0; i < maxLevelDepth; i++ {
+		v := atomic.LoadInt32(&l.level)
+		if v != levelUnset {
+			return Level(v)
+		}
+		if l.parent == nil {
+			break
+		}
+		l = l.parent()
+	}
+	return INFO
+}
+
+// InheritLevel makes a child follow the level of its parent again after
+// SetLevel.
+func (l *Logger) InheritLevel() {
+	if l.parent != nil {
+		atomic.StoreInt32(&l.level, levelUnset)
+	}
+}
+
+// SetLevel clamps v to DEBUG..OFF rather than silently disabling the
+// logger, the error says which bound an out of range value got. It isn't
+// a logging failure, so it leaves the health state alone.
+func (l *Logger) SetLevel(v Level) error {
+	var err error
+	switch {
+	case v < DEBUG:
+		err = fmt.Errorf("log: invalid level %d, using DEBUG", v)
+		v = DEBUG
+	case v > OFF:
+		err = fmt.Errorf("log: invalid level %d, using OFF", v)
+		v = OFF
+	}
+	atomic.StoreInt32(&l.level, int32(v))
+	return err
 }
 
-func (l *Logger) Level() int {
-	return l.level
+// SetEmptyMessagePolicy sets what becomes of entries with an empty
+// message, e.g. Info() or Info(nil), EmptyEmit by default.
+func (l *Logger) SetEmptyMessagePolicy(policy int) {
+	l.emptyMessage = policy
 }
 
-func (l *Logger) SetLevel(v int) {
-	l.level = v
+// SetMaxMessageLength truncates longer messages, n <= 0 disables the limit.
+func (l *Logger) SetMaxMessageLength(n int) {
+	l.reformat(func() {
+		l.maxMessage = n
+	})
+}
+
+// SetUptimeWidth pads ${uptime} to at least n characters so it lines up.
+func (l *Logger) SetUptimeWidth(n int) {
+	l.uptimeWidth = n
 }
 
 func (l *Logger) Output() io.Writer {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
 	return l.output
 }
 
-func (l *Logger) SetFormat(f string) {
-	l.template = l.newTemplate(f)
+// SetFormat is safe to call while other goroutines are logging, each entry
+// is rendered entirely with either the old or the new format. The error is
+// about an unterminated tag, or unknown tags, see SetUnknownTagPolicy, the
+// current format is kept then.
+func (l *Logger) SetFormat(f string) error {
+	l.reformats.Lock()
+	defer l.reformats.Unlock()
+
+	tf, err := l.newTemplate(f)
+	if err != nil {
+		return err
+	}
+	if err := l.checkTags(tf); err != nil {
+		return err
+	}
+	l.template.Store(tf)
+	return nil
 }
 
+// SetOutput sets the destination, a nil writer discards everything.
+// SetOutput may be called while logging, entries go entirely to either the
+// old or the new writer. The file the Logger was writing is closed, use
+// SetFile to go back to one.
 func (l *Logger) SetOutput(w io.Writer) {
+	l.lazyInit()
+	defer l.notifyColor()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if f := l.file; f != nil && w != io.Writer(f) {
+		f.Close()
+		l.file = nil
+		l.pattern, l.filename = "", ""
+	}
+	l.setOutputLocked(w)
+}
+
+func (l *Logger) setOutputLocked(w io.Writer) {
+	if w == nil {
+		w = ioutil.Discard
+	}
 	l.output = w
-	if w, ok := w.(*os.File); !ok || !isatty.IsTerminal(w.Fd()) {
-		l.DisableColor()
+	l.setJournalTarget(w)
+	l.detectColor(w)
+}
+
+// detectColor disables color unless w is a terminal or there's an
+// override.
+func (l *Logger) detectColor(w io.Writer) {
+	if l.colorOverride != nil {
+		l.applyColorOverride()
+		return
+	}
+	if !isTerminal(w) {
+		l.setColor(false)
 	}
 }
 
-func (l *Logger) Print(i ...interface{}) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
+// SetColorOverride forces color on or off regardless of the output,
+// ClearColorOverride goes back to detection on the next SetOutput.
+func (l *Logger) SetColorOverride(enabled bool) {
+	l.colorOverride = &enabled
+	l.applyColorOverride()
+	l.notifyColor()
+}
 
-	n, _ := fmt.Fprintln(l.output, i...)
-	if l.filename != "" {
-		l.size += n
-		if l.size >= l.maxsize {
-			l.rotate()
+func (l *Logger) ClearColorOverride() {
+	l.colorOverride = nil
+}
+
+func (l *Logger) setJournalTarget(w io.Writer) {
+	var target int32
+	if _, ok := w.(parentOutput); ok {
+		target = 2
+	} else if isStdStream(w) && !isTTY(w) {
+		target = 1
+	}
+	atomic.StoreInt32(&l.journalTarget, target)
+}
+
+func (l *Logger) applyColorOverride() {
+	l.setColor(*l.colorOverride)
+}
+
+// isTerminal reports whether w is a tty, either an *os.File or any wrapper
+// exposing its descriptor. FORCE_COLOR and CLICOLOR_FORCE skip the check.
+func isTerminal(w io.Writer) bool {
+	return forceColor() || isTTY(w)
+}
+
+func isTTY(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+func forceColor() bool {
+	for _, key := range []string{"FORCE_COLOR", "CLICOLOR_FORCE"} {
+		if v := os.Getenv(key); v != "" && v != "0" {
+			return true
 		}
 	}
+	return false
 }
 
-func (l *Logger) Printf(format string, args ...interface{}) {
+// Print logs i like Println, as an INFO entry which is written whatever
+// the level.
+func (l *Logger) Print(i ...interface{}) {
+	l.print("", i)
+}
+
+// Plain writes msg verbatim with no template, e.g. a marker line for
+// another tool to parse. It's still subject to the level, and counts
+// towards the size and rotation.
+func (l *Logger) Plain(level Level, msg string) error {
+	l.lazyInit()
+	if !validLevel(level) {
+		return fmt.Errorf("log: invalid level %d", level)
+	}
+	toMain := level >= l.Level() || level == FATAL
+	toOutputs := l.outputsAccept(level)
+	if !toMain && !toOutputs {
+		return nil
+	}
+	buf := l.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer l.bufferPool.Put(buf)
+
+	buf.WriteString(msg)
+	buf.WriteByte('\n')
+	l.terminate(buf)
+
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
+	var err error
+	if toMain {
+		err = l.writeLocked(buf.Bytes())
+	}
+	if toOutputs {
+		l.writeOutputsLocked(level, buf.Bytes())
+	}
+	atomic.AddUint64(&l.counts[level], 1)
+	return err
+}
 
-	f := fmt.Sprintf("%s\n", format)
-	n, _ := fmt.Fprintf(l.output, f, args...)
-	if l.filename != "" {
-		l.size += n
-		if l.size >= l.maxsize {
-			l.rotate()
-		}
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.print(format, args)
+}
+
+func (l *Logger) print(format string, args []interface{}) {
+	if format == "" {
+		args = []interface{}{strings.TrimSuffix(fmt.Sprintln(args...), "\n")}
 	}
+	l.emit(Entry{Level: INFO, Forced: true}, 3, format, args)
 }
 
 func (l *Logger) Debug(i ...interface{}) {
-	l.log(DEBUG, "", i...)
+	l.log(DEBUG, "", i)
 }
 
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	l.log(DEBUG, format, args...)
+	l.log(DEBUG, format, args)
 }
 
 func (l *Logger) Info(i ...interface{}) {
-	l.log(INFO, "", i...)
+	l.log(INFO, "", i)
 }
 
 func (l *Logger) Infof(format string, args ...interface{}) {
-	l.log(INFO, format, args...)
+	l.log(INFO, format, args)
 }
 
 func (l *Logger) Warn(i ...interface{}) {
-	l.log(WARN, "", i...)
+	l.log(WARN, "", i)
 }
 
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	l.log(WARN, format, args...)
+	l.log(WARN, format, args)
 }
 
 func (l *Logger) Error(i ...interface{}) {
-	l.log(ERROR, "", i...)
+	l.log(ERROR, "", i)
 }
 
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	l.log(ERROR, format, args...)
+	l.log(ERROR, format, args)
 }
 
 func (l *Logger) Fatal(i ...interface{}) {
-	l.log(FATAL, "", i...)
-	os.Exit(1)
+	l.log(FATAL, "", i)
+	l.exit()
 }
 
 func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.log(FATAL, format, args...)
-	os.Exit(1)
+	l.log(FATAL, format, args)
+	l.exit()
 }
 
 func DisableColor() {
 	global.DisableColor()
 }
 
+func ColorEnabled() bool {
+	return global.ColorEnabled()
+}
+
+func OnColorChange(fn func(enabled bool)) {
+	global.OnColorChange(fn)
+}
+
 func EnableColor() {
 	global.EnableColor()
 }
 
+func SetLevelStyle(style int) {
+	global.SetLevelStyle(style)
+}
+
+func SetColorOverride(enabled bool) {
+	global.SetColorOverride(enabled)
+}
+
+func ClearColorOverride() {
+	global.ClearColorOverride()
+}
+
+func EnableJSON() {
+	global.EnableJSON()
+}
+
+func DisableJSON() {
+	global.DisableJSON()
+}
+
+func SetColorScope(scope int) {
+	global.SetColorScope(scope)
+}
+
 func Prefix() string {
 	return global.Prefix()
 }
@@ -259,12 +763,25 @@ func SetPrefix(p string) {
 	global.SetPrefix(p)
 }
 
-func Level() int {
+// GetLevel returns the level of the global logger.
+func GetLevel() Level {
 	return global.Level()
 }
 
-func SetLevel(v int) {
-	global.SetLevel(v)
+func SetLevel(v Level) error {
+	return global.SetLevel(v)
+}
+
+func SetEmptyMessagePolicy(policy int) {
+	global.SetEmptyMessagePolicy(policy)
+}
+
+func SetMaxMessageLength(n int) {
+	global.SetMaxMessageLength(n)
+}
+
+func SetUptimeWidth(n int) {
+	global.SetUptimeWidth(n)
 }
 
 func Output() io.Writer {
@@ -275,177 +792,759 @@ func SetOutput(w io.Writer) {
 	global.SetOutput(w)
 }
 
-func SetFormat(f string) {
-	global.SetFormat(f)
+func SetFormat(f string) error {
+	return global.SetFormat(f)
 }
 
 func Print(i ...interface{}) {
-	global.Print(i...)
+	global.print("", i)
 }
 
 func Printf(format string, args ...interface{}) {
-	global.Printf(format, args...)
+	global.print(format, args)
+}
+
+func Plain(level Level, msg string) error {
+	return global.Plain(level, msg)
 }
 
 func Debug(i ...interface{}) {
-	global.Debug(i...)
+	global.log(DEBUG, "", i)
 }
 
 func Debugf(format string, args ...interface{}) {
-	global.Debugf(format, args...)
+	global.log(DEBUG, format, args)
 }
 
 func Info(i ...interface{}) {
-	global.Info(i...)
+	global.log(INFO, "", i)
 }
 
 func Infof(format string, args ...interface{}) {
-	global.Infof(format, args...)
+	global.log(INFO, format, args)
 }
 
 func Warn(i ...interface{}) {
-	global.Warn(i...)
+	global.log(WARN, "", i)
 }
 
 func Warnf(format string, args ...interface{}) {
-	global.Warnf(format, args...)
+	global.log(WARN, format, args)
 }
 
 func Error(i ...interface{}) {
-	global.Error(i...)
+	global.log(ERROR, "", i)
 }
 
 func Errorf(format string, args ...interface{}) {
-	global.Errorf(format, args...)
+	global.log(ERROR, format, args)
 }
 
 func Fatal(i ...interface{}) {
-	global.Fatal(i...)
+	global.log(FATAL, "", i)
+	global.exit()
 }
 
 func Fatalf(format string, args ...interface{}) {
-	global.Fatalf(format, args...)
+	global.log(FATAL, format, args)
+	global.exit()
 }
 
-func (l *Logger) log(v int, format string, args ...interface{}) {
-	if v < l.level {
-		return
+func Log(level Level, calldepth int, msg string) error {
+	return global.Log(level, calldepth+1, msg)
+}
+
+// log is called by both the methods and the package functions, so the
+// caller is always 3 frames up.
+func (l *Logger) log(v Level, format string, args []interface{}) {
+	l.emit(Entry{Level: v}, 3, format, args)
+}
+
+// Log is the low-level entry point for adapters: msg is already assembled
+// and calldepth counts the frames to skip for the caller, 1 being the
+// caller of Log. Entries below the level are dropped without error,
+// otherwise the write error is returned. FATAL entries don't exit.
+func (l *Logger) Log(level Level, calldepth int, msg string) error {
+	return l.emit(Entry{Level: level}, calldepth+1, "", []interface{}{msg})
+}
+
+// emit formats the entry into a pooled buffer without holding the mutex,
+// only the final write and the size bookkeeping are serialized.
+func (l *Logger) emit(entry Entry, calldepth int, format string, args []interface{}) error {
+	l.lazyInit()
+	// worked on from the pool, hooks and filters would move entry to the heap
+	e := emitted.Get().(*Entry)
+	*e = entry
+	defer recycle(e)
+	v := e.Level
+	if !validLevel(v) {
+		return fmt.Errorf("log: invalid level %d", v)
+	}
+	// the format settings of the whole entry, see reformat
+	tf := l.template.Load().(*textFormat)
+	ring := l.ring
+	// a FATAL is always written, even at OFF, so the exit is never silent,
+	// forced entries skip every suppression step
+	toMain := v >= l.Level() || v == FATAL || e.Forced
+	toOutputs := l.outputsAccept(v)
+	captured := !toMain && !toOutputs
+	e.logger, e.format = l, format
+	if captured && (ring == nil || v < l.ringLevel) {
+		l.suppress(suppressedLevel, e, calldepth)
+		return nil
+	}
+	if e.ctx != nil {
+		e.materialize()
+		e.Fields = l.contextFields(e.ctx, e.Fields)
+	}
+	if filters, _ := l.filters.Load().([]Filter); len(filters) > 0 && filtered(filters, e.materialized()) {
+		l.suppress(suppressedFilter, e, calldepth)
+		return nil
 	}
 
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
 	buf := l.bufferPool.Get().(*bytes.Buffer)
 	buf.Reset()
 	defer l.bufferPool.Put(buf)
-	_, file, line, _ := runtime.Caller(3)
+	file, line := e.File, e.Line
+	if file == "" {
+		e.pc, file, line = caller(calldepth)
+	}
+	if !e.Forced && l.hasCallerRules(v) && l.callerDenied(file) {
+		e.File, e.Line = file, line
+		l.suppress(suppressedCaller, e, calldepth)
+		return nil
+	}
+	now := time.Now()
 
 	message := ""
-	if format == "" {
-		message = fmt.Sprint(args...)
+	if format != "" {
+		message = fmt.Sprintf(wrapVerbs(format), args...)
+	} else if s, ok := soleString(args); ok {
+		message = s
 	} else {
-		message = fmt.Sprintf(format, args...)
+		message = fmt.Sprint(args...)
+	}
+	message = strings.TrimSuffix(message, "\n")
+	if message == "" || format == "" && len(args) == 1 && args[0] == nil {
+		switch l.emptyMessage {
+		case EmptyDrop:
+			// a FATAL still says why the process exits
+			if v != FATAL {
+				e.File, e.Line = file, line
+				l.suppress(suppressedEmpty, e, calldepth)
+				return nil
+			}
+			message = emptyPlaceholder
+		case EmptyPlaceholder:
+			message = emptyPlaceholder
+		}
+	}
+	if tf.maxMsg > 0 && len(message) > tf.maxMsg {
+		message = message[:tf.maxMsg] + "...(truncated)"
+	}
+	e.Time, e.File, e.Line, e.Message = now, file, line, message
+	if since := int64(now.Sub(l.start)); since > 0 {
+		if prev := atomic.SwapInt64(&l.lastEntry, since); prev > 0 && since > prev {
+			e.delta = time.Duration(since - prev)
+		}
 	}
 	if v == FATAL {
-		stack := make([]byte, 4<<10)
-		length := runtime.Stack(stack, true)
-		message = message + "\n" + string(stack[:length])
+		l.fatalMsg.Store(message)
+		if !l.dumpCrash(e) {
+			stack := make([]byte, 4<<10)
+			length := runtime.Stack(stack, true)
+			e.Stack = string(stack[:length])
+		}
+	}
+
+	if filters, _ := l.messageFilters.Load().([]Filter); len(filters) > 0 && filtered(filters, e.materialized()) {
+		l.suppress(suppressedFilter, e, calldepth)
+		return nil
+	}
+
+	if l.goroutineID && (tf.json || tf.goid) {
+		e.goid = goid()
+	}
+	if atomic.LoadInt32(&l.hooksActive) > 0 && l.reentered(e) {
+		return nil
+	}
+	// past the last drop, a dropped entry never computes its lazy fields
+	e.Fields, e.fields = resolveLazy(e.Fields), resolveTyped(e.fields)
+	if !captured && l.hasHooks() {
+		e.materialize()
+		// hooks see the entries in the order they are written
+		l.order.Lock()
+		defer l.order.Unlock()
+	}
+	if !captured {
+		l.fireHooks(e)
+		if !validLevel(e.Level) {
+			e.Level = v
+		}
 	}
 
 	callback := l.callbacks[v]
-	if callback != nil {
-		msg := fmt.Sprintf("%s %s:%s:%s:%d: %s\n", time.Now().Format(timeLocal), l.levels[v], pid, filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file), line, message)
+	if callback != nil && !captured {
+		var fb bytes.Buffer
+		e.appendFields(&fb)
+		msg := fmt.Sprintf("%s %s:%s:%s:%d: %s%s\n", now.Format(timeLocal), tf.tokens[v], pid, midFile(file), line, l.messageText(e), fb.String())
 		if v == FATAL {
 			// wait callback
-			callback(msg)
+			l.guard(func() { callback(msg) })
 		} else {
 			go callback(msg)
 		}
 	}
 
-	_, err := l.template.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
-		switch tag {
-		case "time_local":
-			return w.Write([]byte(time.Now().Format(timeLocal)))
-		case "time_rfc3339":
-			return w.Write([]byte(time.Now().Format(time.RFC3339)))
-		case "level":
-			return w.Write([]byte(l.levels[v]))
-		case "pid":
-			return w.Write([]byte(pid))
-		case "prefix":
-			return w.Write([]byte(l.prefix))
-		case "long_file":
-			return w.Write([]byte(file))
-		case "short_file":
-			return w.Write([]byte(path.Base(file)))
-		case "mid_file":
-			return w.Write([]byte(filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file)))
-		case "line":
-			return w.Write([]byte(strconv.Itoa(line)))
-		case "message":
-			return w.Write([]byte(message))
-		default:
-			return w.Write([]byte(fmt.Sprintf("[unknown tag %s]", tag)))
+	if l.journalPrefix && l.journalTargeted() {
+		buf.WriteString(journalPriority[v])
+	}
+	start := buf.Len()
+	var err error
+	if tf.json {
+		err = l.formatJSON(buf, tf, e)
+	} else {
+		if err = l.formatText(buf, tf, e); err != nil {
+			// don't lose the message because of a broken template
+			l.handleError(err)
+			buf.Truncate(start)
+			fmt.Fprintf(buf, "%s %s %s:%d: %s\n", e.Time.Format(timeLocal), levelName(v), midFile(e.File), e.Line, l.messageText(e))
+			err = nil
+		} else if s, ok := l.stackEntry(e); ok {
+			l.formatText(buf, tf, &s)
 		}
-	})
+		if tf.line != nil {
+			line := string(buf.Bytes()[start:])
+			nl := strings.HasSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\n")
+			if colored := colorize(tf.line.level(v), line); len(colored) != len(line) {
+				buf.Truncate(start)
+				buf.WriteString(colored)
+				if nl {
+					buf.WriteByte('\n')
+				}
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	l.terminate(buf)
+	if captured {
+		ring.add(buf.Bytes())
+		return nil
+	}
+
+	l.mutex.Lock()
+	out := buf.Bytes()
+	if toMain {
+		if v >= l.ringTrigger {
+			l.replayRing()
+		}
+		out, e.Filename, e.Offset, err = l.writeEntryLocked(out)
+	} else {
+		out = fillOffset(out, 0)
+	}
+	if toOutputs {
+		l.writeOutputsLocked(v, out)
+	}
+	l.mutex.Unlock()
+	atomic.AddUint64(&l.counts[v], 1)
+	if err == nil {
+		l.fireWriteHooks(e, out)
+	}
+	return err
+}
+
+func (l *Logger) formatText(buf *bytes.Buffer, tf *textFormat, e *Entry) error {
+	v := e.Level
+	if !validLevel(v) {
+		v = INFO
+	}
+	for _, seg := range tf.levels[v] {
+		if seg.tag == "" {
+			buf.Write(seg.static)
+			continue
+		}
+		code := ""
+		if tf.theme != nil {
+			code = tf.theme.tag(seg.tag)
+		}
+		if code != "" {
+			buf.WriteString("\x1b[" + code + "m")
+		}
+		if _, err := l.renderTag(buf, tf, e, seg.tag); err != nil {
+			return err
+		}
+		if code != "" {
+			buf.WriteString("\x1b[0m")
+		}
+	}
+	return nil
+}
+
+func (l *Logger) renderTag(w io.Writer, tf *textFormat, e *Entry, tag string) (int, error) {
+	switch tag {
+	case "time_local":
+		return io.WriteString(w, l.timeCache[cacheTimeLocal].format(e.Time, timeLocal, time.Millisecond))
+	case "time_rfc3339":
+		return io.WriteString(w, l.timeCache[cacheTimeRFC3339].format(e.Time, time.RFC3339, time.Second))
+	case "time_apache":
+		return io.WriteString(w, l.timeCache[cacheTimeApache].format(e.Time, timeApache, time.Second))
+	case "uptime":
+		return w.Write([]byte(fmt.Sprintf("%*s", l.uptimeWidth, fmt.Sprintf("+%.3fs", e.Time.Sub(l.start).Seconds()))))
+	case "delta":
+		return w.Write([]byte(formatDelta(e.delta)))
+	case "uptime_ms":
+		return w.Write([]byte(strconv.FormatInt(int64(e.Time.Sub(l.start)/time.Millisecond), 10)))
+	case "level":
+		if !validLevel(e.Level) || tf.tokens[e.Level] == "" {
+			return w.Write([]byte(levelName(e.Level)))
+		}
+		return w.Write([]byte(tf.tokens[e.Level]))
+	case "level_lower":
+		return w.Write([]byte(strings.ToLower(levelName(e.Level))))
+	case "level_upper_plain":
+		return w.Write([]byte(levelName(e.Level)))
+	case "level_num":
+		return w.Write([]byte(strconv.Itoa(int(e.Level))))
+	case "pid":
+		return w.Write([]byte(pid))
+	case "offset":
+		// filled in by writeEntryLocked
+		return w.Write(offsetMark)
+	case "prefix":
+		return w.Write([]byte(tf.prefix))
+	case "prefix_decorated":
+		return w.Write([]byte(decoratePrefix(tf.prefix)))
+	case "long_file":
+		return w.Write([]byte(e.File))
+	case "short_file":
+		return w.Write([]byte(shortFile(e.File)))
+	case "mid_file":
+		return w.Write([]byte(midFile(e.File)))
+	case "rel_file":
+		return w.Write([]byte(relFile(e.File, workDir)))
+	case "line":
+		return w.Write([]byte(strconv.Itoa(e.Line)))
+	case "func":
+		return w.Write([]byte(e.funcName()))
+	case "message":
+		return w.Write([]byte(l.messageText(e)))
+	case "event":
+		if l.sanitize {
+			return w.Write([]byte(escapeControl(e.Event)))
+		}
+		return w.Write([]byte(e.Event))
+	case "fingerprint":
+		return w.Write([]byte(e.Fingerprint()))
+	case "goid":
+		if e.goid == 0 {
+			return 0, nil
+		}
+		return w.Write([]byte(strconv.FormatUint(e.goid, 10)))
+	case "fields":
+		if len(e.Fields) == 0 && len(e.fields) == 0 {
+			return 0, nil
+		}
+		var fb bytes.Buffer
+		e.appendFields(&fb)
+		return w.Write(fb.Bytes())
+	case "remote_addr", "remote_user", "request", "status", "body_bytes", "referer", "user_agent":
+		if v, ok := e.field(tag); ok && v != nil {
+			if s := fmt.Sprint(v); s != "" {
+				return w.Write([]byte(s))
+			}
+		}
+		return w.Write([]byte("-"))
+	default:
+		// ${time:2006/01/02} and ${time_utc:15:04:05.000000}
+		if strings.HasPrefix(tag, "time:") {
+			return io.WriteString(w, e.Time.Format(tag[len("time:"):]))
+		}
+		if strings.HasPrefix(tag, "time_utc:") {
+			return io.WriteString(w, e.Time.UTC().Format(tag[len("time_utc:"):]))
+		}
+		// compiled away by newTemplate
+		return 0, errUnknownTag
+	}
+}
+
+// midFile is the file name with its parent directory, e.g. log/log.go.
+func midFile(file string) string {
+	file = slashPath(file)
+	// a substring for the usual clean paths
+	if i := strings.LastIndexByte(file, '/'); i > 0 && i < len(file)-1 {
+		j := strings.LastIndexByte(file[:i], '/')
+		if dir := file[j+1 : i]; dir != "" && dir != "." && dir != ".." {
+			return file[j+1:]
+		}
+	}
+	return path.Base(path.Dir(file)) + "/" + path.Base(file)
+}
+
+func shortFile(file string) string {
+	return path.Base(slashPath(file))
+}
+
+// workDir is the directory of the process at start, see relFile.
+var workDir, _ = os.Getwd()
+
+// relFile is file relative to dir, e.g. pkg/log/log.go from the module
+// root, slash-separated whatever the OS, or the whole file when it's
+// outside dir. Drive letters are matched ignoring their case.
+func relFile(file, dir string) string {
+	file = slashPath(file)
+	clean := path.Clean(file)
+	if dir = path.Clean(slashPath(dir)); dir == "." {
+		return file
+	}
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	if len(clean) <= len(prefix) {
+		return file
+	}
+	if head := clean[:len(prefix)]; head == prefix || len(prefix) > 1 && prefix[1] == ':' && strings.EqualFold(head, prefix) {
+		return clean[len(prefix):]
+	}
+	return file
+}
+
+// slashPath makes the caller tags the same on every OS: runtime.Caller
+// reports slash-separated paths, but files set on the Entry by adapters
+// may come with backslashes, e.g. from Windows.
+func slashPath(file string) string {
+	return strings.Replace(file, "\\", "/", -1)
+}
 
+// writeLocked rotates before an entry which would overflow the file, so
+// files never exceed maxsize and an entry is never split across files.
+// An entry larger than maxsize still goes to a fresh file of its own.
+func (l *Logger) writeLocked(b []byte) error {
+	_, _, _, err := l.writeEntryLocked(b)
+	return err
+}
+
+// writeEntryLocked writes b as writeLocked, returning the bytes actually
+// written, with ${offset} filled in, and where in which file they went.
+func (l *Logger) writeEntryLocked(b []byte) (written []byte, name string, offset int, err error) {
+	if p, ok := l.output.(parentOutput); ok && l.file == nil {
+		return p.writeEntry(b)
+	}
+	if l.lazyOpen && l.filename != "" && l.file == nil {
+		if err := l.open(); err != nil {
+			os.Stderr.Write(b)
+			return b, "", 0, err
+		}
+	}
+	lines := 0
+	if l.maxLines > 0 {
+		lines = bytes.Count(b, []byte(l.eol()))
+	}
+	f := l.file
+	out := fillOffset(b, 0)
+	if f != nil {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		out = fillOffset(b, f.size)
+		if f.size > 0 && l.maxsize > 0 && f.size+len(out)+l.footerSize(f, out) > l.maxsize {
+			l.rotate("size", out, nil)
+		} else if f.size > 0 && l.maxLines > 0 && f.lines+lines > l.maxLines {
+			l.rotate("lines", out, nil)
+		}
+		l.writeHeaderLocked(f)
+		out, name, offset = fillOffset(b, f.size), f.name, f.size
+	}
+	w := l.output
+	if w == nil {
+		if atomic.CompareAndSwapInt32(&l.nilWarned, 0, 1) {
+			l.diag("no output configured, writing to stderr")
+			fmt.Fprintln(os.Stderr, "log: no output configured, writing to stderr")
+		}
+		w = os.Stderr
+	}
+	var n int
+	n, err = l.writeRetry(w, out)
+	if f != nil {
+		f.size += n
+		f.lines += lines
+		if f.hash != nil {
+			f.entries += bytes.Count(out[:n], []byte(l.eol()))
+		}
+		// the file was opened without O_SYNC by another Logger
+		if err == nil && l.syncWrites && !f.sync {
+			err = f.f.Sync()
+		}
+	}
 	if err != nil {
+		l.handleError(err)
+	} else {
+		l.clearError()
+	}
+	return out, name, offset, err
+}
+
+var offsetMark = []byte("\x00offset\x00")
+
+// fillOffset replaces the ${offset} of an entry, which is only known once
+// it's about to be written.
+func fillOffset(b []byte, offset int) []byte {
+	if bytes.IndexByte(b, 0) < 0 || !bytes.Contains(b, offsetMark) {
+		return b
+	}
+	return bytes.Replace(b, offsetMark, []byte(strconv.Itoa(offset)), -1)
+}
+
+// formatDelta renders d with adaptive units: +0, +850µs, +12ms, +1.204s.
+func formatDelta(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "+0"
+	case d < time.Millisecond:
+		return "+" + strconv.FormatInt(int64(d/time.Microsecond), 10) + "µs"
+	case d < time.Second:
+		return "+" + strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+	default:
+		return fmt.Sprintf("+%.3fs", d.Seconds())
+	}
+}
+
+// SetLineEnding replaces the "\n" terminating every entry, e.g. "\r\n" or
+// "\x00" for NUL delimited records. Formats must still end with "\n".
+func (l *Logger) SetLineEnding(s string) {
+	l.lineEnding = s
+}
+
+func SetLineEnding(s string) {
+	global.SetLineEnding(s)
+}
+
+func (l *Logger) eol() string {
+	if l.lineEnding == "" {
+		return "\n"
+	}
+	return l.lineEnding
+}
+
+// terminate swaps the trailing "\n" of a formatted entry for the line ending.
+func (l *Logger) terminate(buf *bytes.Buffer) {
+	if l.lineEnding == "" || l.lineEnding == "\n" {
 		return
 	}
-	l.output.Write(buf.Bytes())
-	if l.filename != "" {
-		l.size += len(buf.Bytes())
-		if l.size >= l.maxsize {
-			l.rotate()
+	if b := buf.Bytes(); len(b) > 0 && b[len(b)-1] == '\n' {
+		buf.Truncate(len(b) - 1)
+		buf.WriteString(l.lineEnding)
+	}
+}
+
+func countLines(filename, eol string) int {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	// counting the last byte of the ending is enough and works across chunks
+	sep := []byte{eol[len(eol)-1]}
+	n := 0
+	buf := make([]byte, 32*1024)
+	for {
+		c, err := f.Read(buf)
+		n += bytes.Count(buf[:c], sep)
+		if err != nil {
+			return n
 		}
 	}
 }
 
-func (l *Logger) rotate() {
-	backupFile := fmt.Sprintf("%s.tmp", l.filename)
-	os.Remove(backupFile)
-	if err := os.Rename(l.filename, backupFile); err != nil {
-		l.Error(err)
-		return
+const maxWriteRetries = 3
+
+// writeFull retries short writes until b is entirely written, giving up
+// after maxWriteRetries attempts without progress. n is what was written.
+func writeFull(w io.Writer, b []byte) (int, error) {
+	written, tries := 0, 0
+	for {
+		n, err := w.Write(b[written:])
+		written += n
+		if written >= len(b) {
+			return written, nil
+		}
+		if n > 0 {
+			tries = 0
+			continue
+		}
+		if tries++; tries >= maxWriteRetries {
+			if err == nil {
+				err = io.ErrShortWrite
+			}
+			return written, err
+		}
 	}
+}
 
-	l.open()
+// rotate switches to a new file, starting with a marker entry saying why,
+// and queues the archival of the old one on the maintenance goroutine,
+// which reports its result to done when not nil. next is the entry about
+// to be written, the marker only goes first if both fit.
+// l.file.mutex must be held. Each rotation gets its own temporary name,
+// so neither a pending one nor one left by a crash is ever overwritten.
+func (l *Logger) rotate(reason string, next []byte, done chan<- error) error {
+	f := l.file
+	name, size := f.name, f.size
+	var backupFile string
+	for {
+		f.rotations++
+		backupFile = fmt.Sprintf("%s.%d.tmp", name, f.rotations)
+		if _, err := os.Lstat(backupFile); os.IsNotExist(err) {
+			break
+		}
+	}
+	if err := os.Rename(name, backupFile); err != nil {
+		l.handleError(err)
+		if done != nil {
+			done <- err
+		}
+		return err
+	}
+	// still open on the renamed file
+	l.writeFooterLocked(f)
 
-	go func() {
-		dir := filepath.Dir(l.filename)
-		base := filepath.Base(l.filename)
-		list, err := ioutil.ReadDir(dir)
+	// a new {date} starts a new file, the old one keeps its own backups
+	l.filename = expandFilename(l.pattern, time.Now())
+	err := f.openLocked(l.filename, false, l.eol(), false)
+	if err != nil {
+		l.handleError(err)
+	}
+	f.rename()
+	if l.currentSymlink {
+		l.linkCurrent()
+	}
+	l.diag("rotated %s to %s", name, backupFile)
+
+	path, archive := l.archiver(name, backupFile, time.Now())
+	if err == nil {
+		l.writeMarkerLocked(f, reason, path, size, next)
+	}
+	f.maint.push(func() {
+		err := archive()
+		if err != nil {
+			l.handleError(err)
+		}
+		if done != nil {
+			done <- err
+		}
+	})
+	return err
+}
+
+// archiver returns the archival of backupFile, the content of the file
+// name until its rotation at the given time, into the backup sequence, and
+// the path of the archive it creates.
+func (l *Logger) archiver(name, backupFile string, at time.Time) (path string, archive func() error) {
+	dir, naming := l.archiveLayout(name)
+	codec, backups, retention := l.codec, l.backups, l.retention()
+	root, dated := dir, l.datedArchives
+	if dated {
+		dir = filepath.Join(root, at.Format(datedLayout))
+	}
+	base := filepath.Base(name)
+	newFile := filepath.Join(dir, archiveName(naming, base, 1, at))
+	path = newFile
+	if codec != nil {
+		path += codec.Extension()
+	}
+	return path, func() error {
+		// on disk before it goes by another name, which might be a copy
+		if err := syncFile(backupFile); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		archives, err := listArchives(dir, base, naming)
 		if err != nil {
-			l.Error(err)
-			return
+			return err
 		}
 
-		var archives []int
-		for _, file := range list {
-			if file.IsDir() || !strings.HasPrefix(file.Name(), base) {
+		sort.Slice(archives, func(i, j int) bool {
+			return archives[i].idx > archives[j].idx
+		})
+		for _, a := range archives {
+			if a.idx+1 >= backups {
+				os.Remove(a.path)
 				continue
 			}
 
-			idxStr := strings.TrimPrefix(file.Name(), base+".")
-			idx, _ := strconv.Atoi(idxStr)
-			if idx != 0 {
-				archives = append(archives, idx)
+			if a.time.IsZero() {
+				// named without its time, the last write is the closest
+				if fi, err := os.Stat(a.path); err == nil {
+					a.time = fi.ModTime()
+				}
 			}
+			newFile := filepath.Join(dir, archiveName(naming, base, a.idx+1, a.time)+a.ext)
+			os.Rename(a.path, newFile)
 		}
 
-		sort.Sort(sort.Reverse(sort.IntSlice(archives)))
-		for _, i := range archives {
-			filename := fmt.Sprintf("%s.%d", l.filename, i)
-			if i+1 >= l.backups {
-				os.Remove(filename)
-				continue
+		if err := moveFile(backupFile, newFile); err != nil {
+			return err
+		}
+		if codec != nil {
+			if err := compressArchive(codec, newFile); err != nil {
+				return err
+			}
+		}
+		l.diag("archived %s as %s", backupFile, newFile)
+		if dated {
+			if err := pruneDated(root, base, naming, backups); err != nil {
+				return err
 			}
-
-			newFile := fmt.Sprintf("%s.%d", l.filename, i+1)
-			os.Rename(filename, newFile)
 		}
+		if retention != nil {
+			return l.pruneExpired(root, base, naming, dated, retention)
+		}
+		return nil
+	}
+}
+
+// soleString returns the message of a call with a single string, as
+// fmt.Sprint would but without a copy.
+func soleString(args []interface{}) (string, bool) {
+	if len(args) != 1 {
+		return "", false
+	}
+	s, ok := args[0].(string)
+	return s, ok
+}
+
+// wrapVerbs rewrites %w verbs to %v, fmt.Sprintf only understands %w in
+// fmt.Errorf and would otherwise render %!w(...).
+func wrapVerbs(format string) string {
+	if !strings.Contains(format, "w") {
+		return format
+	}
 
-		newFile := fmt.Sprintf("%s.%d", l.filename, 1)
-		os.Rename(backupFile, newFile)
-	}()
+	var b []byte
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// skip flags, width, precision and explicit argument indexes
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			break
+		}
+		if format[j] == 'w' {
+			if b == nil {
+				b = []byte(format)
+			}
+			b[j] = 'v'
+		}
+		i = j
+	}
+	if b == nil {
+		return format
+	}
+	return string(b)
 }
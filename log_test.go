@@ -0,0 +1,576 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buf.String()
+}
+
+func (b *syncBuffer) lines() []string {
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func newTestLogger(w *syncBuffer) *Logger {
+	l := New("", DEBUG, 0, 0)
+	l.SetOutput(w)
+	l.SetFormat("${level} ${message}\n")
+	return l
+}
+
+func TestColorChangesWhileLogging(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetColorScope(ColorFullLine)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			l.SetColorOverride(true)
+			l.SetOutput(&out)
+			l.ClearColorOverride()
+			l.SetOutput(&out)
+		}
+	}()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				l.Error("boom")
+			}
+		}()
+	}
+	wg.Wait()
+	<-done
+
+	colored := colorize(defaultTheme.Error, "ERROR boom")
+	for _, line := range out.lines() {
+		if line != "ERROR boom" && line != colored {
+			t.Fatalf("line %q mixes color settings", line)
+		}
+	}
+}
+
+func TestOnColorChangeMayLog(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	var changes []bool
+	l.OnColorChange(func(enabled bool) {
+		changes = append(changes, enabled)
+		l.Infof("color enabled: %v", enabled)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.SetColorOverride(true)
+		l.ClearColorOverride()
+		l.SetOutput(&out)
+		l.SetOutput(&out)
+		l.EnableColor()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a callback logging from SetOutput deadlocked")
+	}
+
+	if want := []bool{true, false, true}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if !l.ColorEnabled() {
+		t.Error("ColorEnabled() = false after EnableColor")
+	}
+	if got := out.String(); !strings.Contains(got, "color enabled: false") {
+		t.Errorf("output = %q", got)
+	}
+}
+
+// TestConcurrentIntegrity logs through every entry point from several
+// goroutines while tiny files rotate, and checks each line lands exactly
+// once, whole, in one of the files. Run it with -race.
+func TestConcurrentIntegrity(t *testing.T) {
+	const goroutines, rounds = 8, 200
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, DEBUG, 0, 1000, WithMaxSize(4*KB))
+	l.SetFormat("${message}${fields}\n")
+
+	want := make(map[string]int)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		for i := 0; i < rounds; i++ {
+			id := fmt.Sprintf("#g%d-i%d", g, i)
+			for _, line := range []string{
+				id + " info", id + " infof", id + " print", id + " printf",
+				id + " plain", id + " raw", id + " log",
+				id + " field k=v", id + " typed user=alice n=42",
+			} {
+				want[line] = 0
+			}
+		}
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				id := fmt.Sprintf("#g%d-i%d", g, i)
+				l.Info(id + " info")
+				l.Infof("%s infof", id)
+				l.Print(id + " print")
+				l.Printf("%s printf", id)
+				l.Plain(INFO, id+" plain")
+				l.Writer().Write([]byte(id + " raw\n"))
+				l.Log(WARN, 0, id+" log")
+				l.WithField("k", "v").Warn(id + " field")
+				l.With().Str("user", "alice").Int("n", 42).Error(id + " typed")
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(name + "*")
+	if len(files) < 10 {
+		t.Fatalf("only %d files, the test needs rotations: %v", len(files), files)
+	}
+	for _, file := range files {
+		for _, line := range strings.Split(readFile(t, file), "\n") {
+			if n, ok := want[line]; ok {
+				want[line] = n + 1
+			} else if strings.Contains(line, "#g") {
+				t.Errorf("%s: corrupted line %q", file, line)
+			}
+		}
+	}
+	for line, n := range want {
+		if n != 1 {
+			t.Errorf("%q written %d times", line, n)
+		}
+	}
+}
+
+// BenchmarkInfoParallel measures the contention of goroutines logging at
+// once, formatting happens outside the mutex.
+func BenchmarkInfoParallel(b *testing.B) {
+	l := New("", INFO, 0, 0)
+	l.SetOutput(ioutil.Discard)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Infof("user %s saved %d items", "alice", 42)
+		}
+	})
+}
+
+func BenchmarkInfoParallelFile(b *testing.B) {
+	l := New(filepath.Join(b.TempDir(), "app.log"), INFO, 0, 0)
+	defer l.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Infof("user %s saved %d items", "alice", 42)
+		}
+	})
+}
+
+func TestWrapVerbs(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"failed: %w", "failed: %v"},
+		{"%w and %w", "%v and %v"},
+		{"%s: %w (%d)", "%s: %v (%d)"},
+		{"%+w %-10w %[1]w", "%+v %-10v %[1]v"},
+		{"100%% wrong", "100%% wrong"},
+		{"no verbs", "no verbs"},
+		{"trailing %", "trailing %"},
+	}
+	for _, tt := range tests {
+		if got := wrapVerbs(tt.in); got != tt.want {
+			t.Errorf("wrapVerbs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestErrorfWrapVerbs(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}\n")
+	err := errors.New("disk full")
+	l.Errorf("save %s: %w, retry: %w (%d)", "a.txt", err, err, 3)
+
+	if got, want := out.String(), "save a.txt: disk full, retry: disk full (3)\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// fdWriter wraps a writer exposing a descriptor, like a wrapped stdout.
+type fdWriter struct {
+	syncBuffer
+	fd uintptr
+}
+
+func (w *fdWriter) Fd() uintptr {
+	return w.fd
+}
+
+func TestColorDetection(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	f, err := os.Create(filepath.Join(t.TempDir(), "not-a-tty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := &fdWriter{fd: f.Fd()}
+	if isTerminal(w) {
+		t.Error("a regular file's descriptor is a terminal")
+	}
+	if isTerminal(&syncBuffer{}) {
+		t.Error("a writer without a descriptor is a terminal")
+	}
+	for _, env := range []string{"FORCE_COLOR", "CLICOLOR_FORCE"} {
+		t.Setenv(env, "1")
+		if !isTerminal(w) {
+			t.Errorf("%s=1 doesn't force color", env)
+		}
+		t.Setenv(env, "0")
+		if isTerminal(w) {
+			t.Errorf("%s=0 forces color", env)
+		}
+	}
+
+	l := New("", DEBUG, 0, 0)
+	l.EnableColor()
+	l.SetOutput(w)
+	if l.ColorEnabled() {
+		t.Error("color kept on a writer which isn't a terminal")
+	}
+	l.SetColorOverride(true)
+	l.SetOutput(w)
+	if !l.ColorEnabled() {
+		t.Error("SetOutput ignored the color override")
+	}
+	l.ClearColorOverride()
+	l.SetOutput(w)
+	if l.ColorEnabled() {
+		t.Error("color kept after ClearColorOverride")
+	}
+}
+
+func TestSetFormatWhileLogging(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	const first, second = "A ${level} ${message}\n", "B|${message}|${level}\n"
+	l.SetFormat(first)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			l.SetFormat(first)
+			l.SetFormat(second)
+		}
+	}()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				l.Warn("hello")
+			}
+		}()
+	}
+	wg.Wait()
+	<-done
+
+	for _, line := range out.lines() {
+		if line != "A WARN hello" && line != "B|hello|WARN" {
+			t.Fatalf("line %q mixes formats", line)
+		}
+	}
+}
+
+// redirect points *std (os.Stdout or os.Stderr) to a file for the rest of
+// the test and returns a function reading what was written to it.
+func redirect(t *testing.T, std **os.File) func() string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "std"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *std
+	*std = f
+	t.Cleanup(func() {
+		*std = saved
+		f.Close()
+	})
+	return func() string {
+		return readFile(t, f.Name())
+	}
+}
+
+func TestSetOutputNil(t *testing.T) {
+	l := New("", INFO, 0, 0)
+	l.SetOutput(nil)
+	l.Info("discarded")
+	if l.output != ioutil.Discard {
+		t.Errorf("output = %T, want ioutil.Discard", l.output)
+	}
+}
+
+func TestZeroLogger(t *testing.T) {
+	stdout := redirect(t, &os.Stdout)
+	var l Logger
+	l.Info("from the zero value")
+	l.Print("printed")
+	if got := stdout(); !strings.Contains(got, "from the zero value") || !strings.Contains(got, "printed") {
+		t.Errorf("stdout = %q", got)
+	}
+}
+
+func TestNilOutputGoesToStderr(t *testing.T) {
+	stderr := redirect(t, &os.Stderr)
+	l := New("", INFO, 0, 0)
+	l.lazyInit()
+	l.output = nil
+	l.Info("first")
+	l.Info("second")
+
+	got := stderr()
+	if strings.Count(got, "no output configured") != 1 {
+		t.Errorf("stderr = %q, want a single warning", got)
+	}
+	if !strings.Contains(got, "first") || !strings.Contains(got, "second") {
+		t.Errorf("stderr = %q, want both entries", got)
+	}
+}
+
+// shortWriter writes at most max bytes per call, then nothing once
+// stall bytes were written.
+type shortWriter struct {
+	syncBuffer
+	max, stall int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if w.stall > 0 && len(w.String()) >= w.stall {
+		return 0, nil
+	}
+	if len(p) > w.max {
+		p = p[:w.max]
+	}
+	return w.syncBuffer.Write(p)
+}
+
+func TestShortWrites(t *testing.T) {
+	w := &shortWriter{max: 3}
+	l := New("", INFO, 0, 0)
+	l.SetOutput(w)
+	l.SetFormat("${level} ${message}\n")
+	l.Info("written three bytes at a time")
+	l.Warn("and whole")
+	if got, want := w.String(), "INFO written three bytes at a time\nWARN and whole\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestStalledWriter(t *testing.T) {
+	l := New("", INFO, 0, 0)
+	w := &shortWriter{max: 4, stall: 8}
+	l.SetOutput(w)
+	l.SetFormat("${message}\n")
+	var reported []error
+	l.SetErrorHandler(func(err error) { reported = append(reported, err) })
+
+	l.Info("never fully written")
+	if len(reported) == 0 || !errors.Is(reported[0], io.ErrShortWrite) {
+		t.Errorf("reported %v, want io.ErrShortWrite", reported)
+	}
+	if n, err := writeFull(w, []byte("more")); n != 0 || err != io.ErrShortWrite {
+		t.Errorf("writeFull = %d, %v", n, err)
+	}
+}
+
+func TestTemplateErrorKeepsTheMessage(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	var reported error
+	l.SetErrorHandler(func(err error) { reported = err })
+	// a tag newTemplate would have compiled away, renderTag fails on it
+	tf := *l.template.Load().(*textFormat)
+	tf.levels[WARN] = []segment{{tag: "nope"}, {static: []byte(" ")}, {tag: "message"}}
+	l.template.Store(&tf)
+	l.Warn("not lost")
+
+	if got := out.String(); !strings.Contains(got, "WARN") || !strings.HasSuffix(got, ": not lost\n") {
+		t.Errorf("output = %q, want the fallback line", got)
+	}
+	if reported != errUnknownTag {
+		t.Errorf("reported %v, want errUnknownTag", reported)
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	for _, eol := range []string{"", "\n", "\r\n", "\x00"} {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.SetLineEnding(eol)
+		l.Info("text")
+		l.Print("print")
+		l.Plain(INFO, "plain")
+		l.EnableJSON()
+		l.SetFormat("${message}\n")
+		l.Info("json")
+
+		want := eol
+		if want == "" {
+			want = "\n"
+		}
+		got := strings.Split(out.String(), want)
+		if len(got) != 5 || got[4] != "" {
+			t.Errorf("%q: output = %q, want 4 terminated entries", eol, out.String())
+			continue
+		}
+		for i, e := range []string{"INFO text", "INFO print", "plain", `"msg":"json"`} {
+			if !strings.Contains(got[i], e) || strings.ContainsAny(got[i], "\r\n\x00") {
+				t.Errorf("%q: entry %d = %q", eol, i, got[i])
+			}
+		}
+	}
+}
+
+func TestLineEndingCountsTowardsTheSize(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+	l.SetLineEnding("\r\n")
+	l.SetFormat("${message}\n")
+	l.Info("one")
+	l.Info("two")
+	l.Sync()
+
+	if got := readFile(t, name); got != "one\r\ntwo\r\n" {
+		t.Errorf("file = %q", got)
+	}
+	l.mutex.Lock()
+	size := l.file.size
+	l.mutex.Unlock()
+	if size != len("one\r\ntwo\r\n") {
+		t.Errorf("size = %d, want %d", size, len("one\r\ntwo\r\n"))
+	}
+}
+
+func TestCallerPaths(t *testing.T) {
+	tests := []struct {
+		file, mid, short string
+	}{
+		{"/home/u/src/app/main.go", "app/main.go", "main.go"},
+		{`C:\Users\u\src\app\main.go`, "app/main.go", "main.go"},
+		{`C:/Users/u/src\app/main.go`, "app/main.go", "main.go"},
+		{`\\server\share\app\main.go`, "app/main.go", "main.go"},
+		{"app/main.go", "app/main.go", "main.go"},
+		{"main.go", "./main.go", "main.go"},
+		{"/main.go", "//main.go", "main.go"},
+		{"./main.go", "./main.go", "main.go"},
+		{"../main.go", "../main.go", "main.go"},
+		{"/src//app/main.go", "app/main.go", "main.go"},
+		{"/src/app/../main.go", "src/main.go", "main.go"},
+	}
+	for _, tt := range tests {
+		if got := midFile(tt.file); got != tt.mid {
+			t.Errorf("midFile(%q) = %q, want %q", tt.file, got, tt.mid)
+		}
+		if got := shortFile(tt.file); got != tt.short {
+			t.Errorf("shortFile(%q) = %q, want %q", tt.file, got, tt.short)
+		}
+	}
+}
+
+func TestRelFile(t *testing.T) {
+	tests := []struct {
+		file, dir, want string
+	}{
+		{"/home/u/src/app/pkg/main.go", "/home/u/src/app", "pkg/main.go"},
+		{"/home/u/src/app/pkg/main.go", "/home/u/src/app/", "pkg/main.go"},
+		{"/home/u/src/app/main.go", "/home/u/src/app", "main.go"},
+		{"/home/u/src/other/main.go", "/home/u/src/app", "/home/u/src/other/main.go"},
+		{"/home/u/src/application/main.go", "/home/u/src/app", "/home/u/src/application/main.go"},
+		{"/home/u/src/app", "/home/u/src/app", "/home/u/src/app"},
+		{"/src/app/../lib/x.go", "/src/lib", "x.go"},
+		{"/main.go", "/", "main.go"},
+		{`C:\Users\u\app\pkg\main.go`, `C:\Users\u\app`, "pkg/main.go"},
+		{`C:/Users/u/app/pkg\main.go`, `C:\Users\u\app`, "pkg/main.go"},
+		{`c:\users\u\app\main.go`, `C:\Users\U\App`, "main.go"},
+		{`D:\app\main.go`, `C:\app`, "D:/app/main.go"},
+		{`\\server\share\app\main.go`, `\\server\share`, "app/main.go"},
+		{"app/pkg/main.go", "app", "pkg/main.go"},
+		{"main.go", "", "main.go"},
+		{"/Home/app/main.go", "/home/app", "/Home/app/main.go"},
+	}
+	for _, tt := range tests {
+		if got := relFile(tt.file, tt.dir); got != tt.want {
+			t.Errorf("relFile(%q, %q) = %q, want %q", tt.file, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestRelFileTag(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${rel_file}:${line}\n")
+	l.Info("from here")
+	e := &Entry{logger: l, File: filepath.Join(workDir, "pkg", "main.go"), Line: 7}
+	e.Log(INFO, 0, "from an adapter")
+	lines := out.lines()
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "log_test.go:") || lines[1] != "pkg/main.go:7" {
+		t.Errorf("lines = %q, want the files relative to the working directory", lines)
+	}
+}
+
+func TestCallerTagsFromAdapters(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${mid_file}:${line} ${short_file}\n")
+	e := &Entry{logger: l, File: `C:\src\app\main.go`, Line: 7}
+	e.Log(INFO, 0, "from an adapter")
+	if got, want := out.String(), "app/main.go:7 main.go\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
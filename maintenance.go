@@ -0,0 +1,113 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// closeDrainTimeout bounds how long Close waits for pending rotations.
+const closeDrainTimeout = 5 * time.Second
+
+// maintenance runs the archival work of rotations (renames, pruning,
+// compression) one job at a time, in order, on a single goroutine started
+// with the first job, so writers never wait for the filesystem.
+type maintenance struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	jobs    []func()
+	running bool          // a job is being processed
+	idle    chan struct{} // closed once the queue drains, see wait
+	started bool
+	stopped bool // the goroutine exits once the queue is empty
+}
+
+func (m *maintenance) push(job func()) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cond == nil {
+		m.cond = sync.NewCond(&m.mutex)
+	}
+	if len(m.jobs) == 0 && !m.running {
+		m.idle = make(chan struct{})
+	}
+	m.jobs = append(m.jobs, job)
+	m.stopped = false
+	if !m.started {
+		m.started = true
+		go m.loop()
+	}
+	m.cond.Broadcast()
+}
+
+func (m *maintenance) loop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for {
+		for len(m.jobs) == 0 {
+			if m.stopped {
+				m.started = false
+				return
+			}
+			m.cond.Wait()
+		}
+		job := m.jobs[0]
+		m.jobs[0] = nil
+		m.jobs = m.jobs[1:]
+		m.running = true
+		m.mutex.Unlock()
+
+		job()
+
+		m.mutex.Lock()
+		m.running = false
+		if len(m.jobs) == 0 {
+			close(m.idle)
+		}
+	}
+}
+
+// stop ends the goroutine after the queued jobs.
+func (m *maintenance) stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stopped = true
+	if m.cond != nil {
+		m.cond.Broadcast()
+	}
+}
+
+// pending returns the number of jobs queued or running.
+func (m *maintenance) pending() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	n := len(m.jobs)
+	if m.running {
+		n++
+	}
+	return n
+}
+
+// wait blocks until every job is done or timeout, reporting whether the
+// queue was drained.
+func (m *maintenance) wait(timeout time.Duration) bool {
+	m.mutex.Lock()
+	if len(m.jobs) == 0 && !m.running {
+		m.mutex.Unlock()
+		return true
+	}
+	idle := m.idle
+	m.mutex.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
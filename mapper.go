@@ -0,0 +1,96 @@
+package log
+
+import "sort"
+
+// level schemes of other logging libraries, see SetLevelMapper
+const (
+	SchemeSyslog = "syslog" // priorities 0 (emerg) to 7 (debug)
+	SchemeSlog   = "slog"   // log/slog levels, -4 (debug) to 8 (error)
+	SchemeLogr   = "logr"   // logr verbosity, V(0) being info
+	SchemeZap    = "zap"    // zapcore levels, -1 (debug) to 5 (fatal)
+)
+
+// LevelTable maps the levels of a scheme, a level between two keys
+// taking the value of the lower key and one below every key the value of
+// the lowest.
+type LevelTable map[int]Level
+
+// Lookup returns the level external maps to, INFO for an empty table.
+func (t LevelTable) Lookup(external int) Level {
+	keys := make([]int, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return INFO
+	}
+	sort.Ints(keys)
+	i := sort.SearchInts(keys, external+1) - 1
+	if i < 0 {
+		i = 0
+	}
+	return t[keys[i]]
+}
+
+// The default tables, copy one to change a mapping:
+//
+//	table := log.LevelTable{}
+//	for k, v := range log.SyslogLevels {
+//		table[k] = v
+//	}
+//	table[5] = log.WARN // NOTICE
+var (
+	SyslogLevels = LevelTable{0: ERROR, 4: WARN, 5: INFO, 7: DEBUG}
+	SlogLevels   = LevelTable{-4: DEBUG, 0: INFO, 4: WARN, 8: ERROR}
+	LogrLevels   = LevelTable{0: INFO, 1: DEBUG}
+	ZapLevels    = LevelTable{-1: DEBUG, 0: INFO, 1: WARN, 2: ERROR, 5: FATAL}
+)
+
+// DefaultLevelMapper maps with SyslogLevels, SlogLevels, LogrLevels and
+// ZapLevels, the levels of an unknown scheme to INFO.
+func DefaultLevelMapper(scheme string, external int) Level {
+	switch scheme {
+	case SchemeSyslog:
+		return SyslogLevels.Lookup(external)
+	case SchemeSlog:
+		return SlogLevels.Lookup(external)
+	case SchemeLogr:
+		return LogrLevels.Lookup(external)
+	case SchemeZap:
+		return ZapLevels.Lookup(external)
+	}
+	return INFO
+}
+
+// SetLevelMapper overrides how the levels of other logging libraries are
+// mapped by MapLevel and LogExternal, nil restores DefaultLevelMapper.
+func (l *Logger) SetLevelMapper(fn func(scheme string, external int) Level) {
+	l.levelMapper = fn
+}
+
+func SetLevelMapper(fn func(scheme string, external int) Level) {
+	global.SetLevelMapper(fn)
+}
+
+// MapLevel returns the level external of scheme maps to, for adapters
+// bridging other logging libraries.
+func (l *Logger) MapLevel(scheme string, external int) Level {
+	if l.levelMapper != nil {
+		return l.levelMapper(scheme, external)
+	}
+	return DefaultLevelMapper(scheme, external)
+}
+
+// LogExternal is Log with the level of another library, e.g. from a slog
+// handler: l.LogExternal(log.SchemeSlog, int(r.Level), 1, r.Message).
+func (l *Logger) LogExternal(scheme string, external int, calldepth int, msg string) error {
+	return l.emit(Entry{Level: l.MapLevel(scheme, external)}, calldepth+1, "", []interface{}{msg})
+}
+
+func MapLevel(scheme string, external int) Level {
+	return global.MapLevel(scheme, external)
+}
+
+func LogExternal(scheme string, external int, calldepth int, msg string) error {
+	return global.LogExternal(scheme, external, calldepth+1, msg)
+}
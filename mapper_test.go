@@ -0,0 +1,85 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDefaultLevelMapper(t *testing.T) {
+	tests := []struct {
+		scheme   string
+		external int
+		want     Level
+	}{
+		{SchemeSyslog, 0, ERROR}, // emerg
+		{SchemeSyslog, 3, ERROR}, // err
+		{SchemeSyslog, 4, WARN},
+		{SchemeSyslog, 5, INFO}, // notice
+		{SchemeSyslog, 6, INFO},
+		{SchemeSyslog, 7, DEBUG},
+		{SchemeSlog, -8, DEBUG},
+		{SchemeSlog, -4, DEBUG},
+		{SchemeSlog, 2, INFO},
+		{SchemeSlog, 4, WARN},
+		{SchemeSlog, 12, ERROR},
+		{SchemeLogr, 0, INFO},
+		{SchemeLogr, 4, DEBUG},
+		{SchemeZap, -1, DEBUG},
+		{SchemeZap, 2, ERROR},
+		{SchemeZap, 3, ERROR}, // dpanic
+		{SchemeZap, 5, FATAL},
+		{"unknown", 42, INFO},
+	}
+	for _, tt := range tests {
+		if got := DefaultLevelMapper(tt.scheme, tt.external); got != tt.want {
+			t.Errorf("DefaultLevelMapper(%s, %d) = %s, want %s", tt.scheme, tt.external, got, tt.want)
+		}
+	}
+	if got := (LevelTable{}).Lookup(3); got != INFO {
+		t.Errorf("empty table Lookup = %s, want INFO", got)
+	}
+}
+
+func TestSetLevelMapper(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	table := LevelTable{}
+	for k, v := range SyslogLevels {
+		table[k] = v
+	}
+	table[5] = WARN
+	l.SetLevelMapper(func(scheme string, external int) Level {
+		if scheme == SchemeSyslog {
+			return table.Lookup(external)
+		}
+		return DefaultLevelMapper(scheme, external)
+	})
+	if got := SyslogLevels.Lookup(5); got != INFO {
+		t.Errorf("the default table changed, NOTICE maps to %s", got)
+	}
+
+	// inherited by the children
+	c := l.Child("child")
+	l.LogExternal(SchemeSyslog, 5, 1, "notice")
+	c.LogExternal(SchemeSyslog, 5, 1, "child notice")
+	l.LogExternal(SchemeSlog, -4, 1, "debug")
+	l.SetLevelMapper(nil)
+	l.LogExternal(SchemeSyslog, 5, 1, "default")
+
+	want := []string{"WARN notice", "WARN child notice", "DEBUG debug", "INFO default"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestLogExternalCaller(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${short_file}:${line}\n")
+	l.LogExternal(SchemeSlog, 0, 1, "m")
+	line := here()
+	if want := "mapper_test.go:" + strconv.Itoa(line-1); out.String() != want+"\n" {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// linesOf returns the lines of every file of name, the active one last.
+func linesOf(t *testing.T, name string) (archives [][]string, active []string) {
+	t.Helper()
+	files, _ := filepath.Glob(name + ".*")
+	for _, file := range files {
+		archives = append(archives, strings.Split(strings.TrimSuffix(readFile(t, file), "\n"), "\n"))
+	}
+	return archives, strings.Split(strings.TrimSuffix(readFile(t, name), "\n"), "\n")
+}
+
+func TestMaxLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 1000)
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.SetMaxLines(5)
+	for i := 0; i < 23; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+	}
+	if err := l.Barrier(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, active := linesOf(t, name)
+	if len(archives) < 4 {
+		t.Fatalf("%d archives, want a rotation every 5 lines", len(archives))
+	}
+	entries := 0
+	for _, lines := range append(archives, active) {
+		if len(lines) > 5 {
+			t.Errorf("file of %d lines: %q", len(lines), lines)
+		}
+		for _, line := range lines {
+			if strings.HasPrefix(line, "entry ") {
+				entries++
+			}
+		}
+	}
+	if entries != 23 {
+		t.Errorf("%d entries written, want 23", entries)
+	}
+	if st := l.Stats(); st.Lines != len(active) {
+		t.Errorf("Stats().Lines = %d, the active file has %d", st.Lines, len(active))
+	}
+}
+
+func TestMaxLinesAndMaxSize(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 1000, WithMaxSize(KB))
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.SetMaxLines(10)
+	// short entries hit the line limit, long ones the size limit
+	for i := 0; i < 12; i++ {
+		l.Info("short")
+	}
+	for i := 0; i < 4; i++ {
+		l.Info(strings.Repeat("x", 400))
+	}
+	if err := l.Barrier(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, _ := linesOf(t, name)
+	if len(archives) < 2 {
+		t.Fatalf("%d archives, want a rotation by lines and one by size", len(archives))
+	}
+	byLines, bySize := false, false
+	for _, lines := range archives {
+		size := len(strings.Join(lines, "\n")) + 1
+		byLines = byLines || len(lines) == 10
+		bySize = bySize || len(lines) < 10 && size > int(KB)/2
+		if len(lines) > 10 || size > int(KB) {
+			t.Errorf("archive of %d lines, %d bytes", len(lines), size)
+		}
+	}
+	if !byLines || !bySize {
+		t.Errorf("rotated by lines %v, by size %v, want both", byLines, bySize)
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkOffsets checks that every "offset message" line of name starts at
+// its offset.
+func checkOffsets(t *testing.T, name string) int {
+	t.Helper()
+	content := readFile(t, name)
+	n := 0
+	for pos := 0; pos < len(content); {
+		end := strings.IndexByte(content[pos:], '\n') + pos
+		line := content[pos:end]
+		if i := strings.IndexByte(line, ' '); i > 0 {
+			if off, err := strconv.Atoi(line[:i]); err == nil {
+				n++
+				if off != pos {
+					t.Errorf("%q is at %d", line, pos)
+				}
+			}
+		}
+		pos = end + 1
+	}
+	return n
+}
+
+func TestOffsetTag(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 5)
+	defer l.Close()
+	l.SetFormat("${offset} ${message}\n")
+	for i := 0; i < 20; i++ {
+		l.Info(strings.Repeat("x", i))
+	}
+	if n := checkOffsets(t, name); n != 20 {
+		t.Errorf("%d entries checked, want 20", n)
+	}
+
+	// a rotation starts over with the marker
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after the rotation")
+	if n := checkOffsets(t, name); n != 2 {
+		t.Errorf("%d entries checked, want the marker and the entry", n)
+	}
+	if got := readFile(t, name); strings.HasPrefix(got, "0 after") {
+		t.Errorf("file = %q, want the entry after the marker", got)
+	}
+}
+
+func TestOffsetAfterReopen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 5)
+	defer l.Close()
+	l.SetFormat("${offset} ${message}\n")
+	l.Info("first")
+
+	// appended to by someone else, the reopen takes the actual size
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("external line\n")
+	f.Close()
+	if err := l.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("second")
+	if n := checkOffsets(t, name); n != 3 {
+		t.Errorf("%d entries checked, want the two entries and the marker", n)
+	}
+}
+
+func TestOffsetWithoutAFile(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${offset} ${message}\n")
+	l.Info("a")
+	l.Info("b")
+	if got := out.String(); got != "0 a\n0 b\n" {
+		t.Errorf("output = %q, want 0 off a file", got)
+	}
+}
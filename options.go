@@ -0,0 +1,178 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-colorable"
+)
+
+// Option configures a Logger at construction time, see New.
+type Option func(l *Logger)
+
+// WithCurrentSymlink maintains <filename>.current pointing at the active
+// log file, recreated after every rotation. Where symlinks can't be created
+// a small pointer file holding the active filename is written instead.
+func WithCurrentSymlink(enabled bool) Option {
+	return func(l *Logger) {
+		l.currentSymlink = enabled
+	}
+}
+
+// WithLazyOpen delays creating the file until the first entry is written,
+// if that fails the entry goes to stderr and the error to the error handler.
+func WithLazyOpen(enabled bool) Option {
+	return func(l *Logger) {
+		l.lazyOpen = enabled
+	}
+}
+
+// WithCloseSummary makes Close log a final INFO line with the number of
+// entries written per level.
+func WithCloseSummary(enabled bool) Option {
+	return func(l *Logger) {
+		l.closeSummary = enabled
+	}
+}
+
+// WithTruncateOnOpen truncates the file when it's first opened instead of
+// appending to it, so the file only holds the current run. Later reopens
+// and rotations append as usual.
+func WithTruncateOnOpen(enabled bool) Option {
+	return func(l *Logger) {
+		l.truncate = enabled
+	}
+}
+
+// WithSyncWrites opens the file with O_SYNC, so every entry is on stable
+// storage when the logging call returns, as audit logs may require. It
+// costs a disk flush per entry, unlike calling Sync now and then: keep it
+// for a dedicated Logger and route the entries to it, e.g. with
+// l.AddOutputLevel(audit.Writer(), WARN).
+func WithSyncWrites(enabled bool) Option {
+	return func(l *Logger) {
+		l.syncWrites = enabled
+	}
+}
+
+func (l *Logger) linkCurrent() {
+	link := l.filename + ".current"
+	tmp := fmt.Sprintf("%s.%s.tmp", link, pid)
+	os.Remove(tmp)
+
+	// swap the link in with a rename so readers never see it missing
+	err := os.Symlink(filepath.Base(l.filename), tmp)
+	if err == nil {
+		if err = os.Rename(tmp, link); err == nil {
+			return
+		}
+		os.Remove(tmp)
+	}
+
+	abs, aerr := filepath.Abs(l.filename)
+	if aerr != nil {
+		abs = l.filename
+	}
+	if err := ioutil.WriteFile(tmp, []byte(abs+"\n"), 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// expandFilename replaces {hostname}, {pid} and {date} (2006-01-02) in
+// pattern. It's called on every open, so rotation after midnight moves
+// on to a new {date} file while backups stay numbered per expanded name,
+// e.g. app-2024-01-15.log.1.
+func expandFilename(pattern string, now time.Time) string {
+	if !strings.Contains(pattern, "{") {
+		return pattern
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return strings.NewReplacer(
+		"{hostname}", hostname,
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{date}", now.Format("2006-01-02"),
+	).Replace(pattern)
+}
+
+// WithStderr logs to stderr instead of stdout when there's no file,
+// leaving stdout to the program's own output.
+func WithStderr() Option {
+	return func(l *Logger) {
+		l.stderr = true
+	}
+}
+
+// NewStderr returns a console logger writing to stderr.
+func NewStderr(level Level) *Logger {
+	return New("", level, 0, 0, WithStderr())
+}
+
+// console is the writer used when there's no file.
+func (l *Logger) console() io.Writer {
+	if l.stderr {
+		return colorable.NewColorableStderr()
+	}
+	return colorable.NewColorableStdout()
+}
+
+// targets of the package level logger, see SetGlobalTarget
+const (
+	TargetStdout = "stdout"
+	TargetStderr = "stderr"
+	TargetFile   = "file"
+)
+
+// SetGlobalTarget replaces the package level logger by a new one writing
+// to target, as LOG_TARGET does, filename being the file of TargetFile.
+// Call it first thing in main, the settings of the previous logger are
+// not carried over, and its file is closed.
+func SetGlobalTarget(target, filename string) error {
+	l, err := newTarget(target, filename)
+	if err != nil {
+		return err
+	}
+	old := global
+	global = l
+	if old.filename != "" {
+		old.Close()
+	}
+	return nil
+}
+
+// newGlobal builds the package level logger, LOG_TARGET=stderr|stdout|file
+// picks its output (file takes the path from LOG_FILE).
+func newGlobal() *Logger {
+	l, err := newTarget(os.Getenv("LOG_TARGET"), os.Getenv("LOG_FILE"))
+	if err != nil {
+		return New("", INFO, 0, 0)
+	}
+	return l
+}
+
+func newTarget(target, filename string) (*Logger, error) {
+	switch target {
+	case TargetStdout, "":
+		return New("", INFO, 0, 0), nil
+	case TargetStderr:
+		return New("", INFO, 0, 0, WithStderr()), nil
+	case TargetFile:
+		if filename == "" {
+			return nil, errors.New("log: the file target needs a filename")
+		}
+		return New(filename, INFO, 100, 10, WithLazyOpen(true)), nil
+	}
+	return nil, fmt.Errorf("log: unknown target %q", target)
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetGlobalTarget(t *testing.T) {
+	saved := global
+	defer func() { global = saved }()
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	if err := SetGlobalTarget(TargetFile, name); err != nil {
+		t.Fatal(err)
+	}
+	Info("to the global file")
+	Sync()
+	if got := readFile(t, name); !strings.Contains(got, "to the global file") {
+		t.Errorf("file = %q", got)
+	}
+
+	// the file of the replaced logger is released
+	if err := SetGlobalTarget(TargetStderr, ""); err != nil {
+		t.Fatal(err)
+	}
+	if openFile(name) {
+		t.Error("the previous global file is still open")
+	}
+	if !global.stderr || global.filename != "" {
+		t.Errorf("global is not a stderr logger")
+	}
+}
+
+func TestSetGlobalTargetErrors(t *testing.T) {
+	saved := global
+	defer func() { global = saved }()
+
+	for _, tt := range []struct{ target, filename string }{
+		{TargetFile, ""},
+		{"syslog", ""},
+	} {
+		if err := SetGlobalTarget(tt.target, tt.filename); err == nil {
+			t.Errorf("SetGlobalTarget(%q, %q) succeeded", tt.target, tt.filename)
+		}
+		if global != saved {
+			t.Errorf("SetGlobalTarget(%q, %q) replaced the global logger on error", tt.target, tt.filename)
+		}
+	}
+}
+
+func TestExpandFilename(t *testing.T) {
+	hostname, _ := os.Hostname()
+	pid := strconv.Itoa(os.Getpid())
+	now := time.Date(2024, 1, 15, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		in, want string
+	}{
+		{"/logs/app.log", "/logs/app.log"},
+		{"/logs/app-{hostname}-{pid}.log", "/logs/app-" + hostname + "-" + pid + ".log"},
+		{"/logs/{date}/app-{date}.log", "/logs/2024-01-15/app-2024-01-15.log"},
+		{"/logs/app-{unknown}.log", "/logs/app-{unknown}.log"},
+	}
+	for _, tt := range tests {
+		if got := expandFilename(tt.in, now); got != tt.want {
+			t.Errorf("expandFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilenamePlaceholdersAndBackups(t *testing.T) {
+	dir := t.TempDir()
+	l := New(filepath.Join(dir, "app-{pid}.log"), INFO, 0, 3)
+	defer l.Close()
+	name := filepath.Join(dir, "app-"+strconv.Itoa(os.Getpid())+".log")
+	l.Info("first")
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("second")
+	l.Sync()
+
+	if got := readFile(t, name+".1"); !strings.Contains(got, "first") {
+		t.Errorf("backup = %q", got)
+	}
+	if got := readFile(t, name); !strings.Contains(got, "second") {
+		t.Errorf("active file = %q", got)
+	}
+	if l.filename != name {
+		t.Errorf("filename = %q, want %q", l.filename, name)
+	}
+}
+
+func TestSyncWritesForAudit(t *testing.T) {
+	dir := t.TempDir()
+	audit := New(filepath.Join(dir, "audit.log"), INFO, 0, 0, WithSyncWrites(true))
+	defer audit.Close()
+	app := New(filepath.Join(dir, "app.log"), INFO, 0, 0)
+	defer app.Close()
+	app.AddOutputLevel(audit.Writer(), WARN)
+	app.Info("routine")
+	app.Warn("audited")
+
+	if audit.file == nil || !audit.file.sync {
+		t.Error("the audit file isn't opened with O_SYNC")
+	}
+	if app.file == nil || app.file.sync {
+		t.Error("the main file is opened with O_SYNC")
+	}
+	if got := readFile(t, filepath.Join(dir, "audit.log")); !strings.Contains(got, "audited") || strings.Contains(got, "routine") {
+		t.Errorf("audit file = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "app.log")); !strings.Contains(got, "audited") || !strings.Contains(got, "routine") {
+		t.Errorf("main file = %q", got)
+	}
+}
+
+func benchmarkFileWrites(b *testing.B, opts ...Option) {
+	l := New(filepath.Join(b.TempDir(), "app.log"), INFO, 0, 0, opts...)
+	defer l.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("audit: user deleted")
+	}
+}
+
+// BenchmarkSyncWrites is the cost of a disk flush per entry, compare with
+// BenchmarkFileWrites.
+func BenchmarkSyncWrites(b *testing.B) {
+	benchmarkFileWrites(b, WithSyncWrites(true))
+}
+
+func BenchmarkFileWrites(b *testing.B) {
+	benchmarkFileWrites(b)
+}
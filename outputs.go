@@ -0,0 +1,69 @@
+package log
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// levelOutput is a writer added with AddOutputLevel.
+type levelOutput struct {
+	bytes uint64 // first for 64-bit alignment
+	w     io.Writer
+	level Level
+}
+
+// AddOutputLevel also writes the entries at minLevel and above to w, e.g.
+// everything to the file and only WARN and above to the console. Entries
+// are formatted once for all outputs; the level of the Logger still
+// applies to its main output only.
+func (l *Logger) AddOutputLevel(w io.Writer, minLevel Level) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	outputs, _ := l.outputs.Load().([]*levelOutput)
+	l.outputs.Store(append(outputs[:len(outputs):len(outputs)], &levelOutput{w: w, level: minLevel}))
+}
+
+func AddOutputLevel(w io.Writer, minLevel Level) {
+	global.AddOutputLevel(w, minLevel)
+}
+
+// outputsAccept reports whether an added output takes entries at v.
+func (l *Logger) outputsAccept(v Level) bool {
+	outputs, _ := l.outputs.Load().([]*levelOutput)
+	for _, o := range outputs {
+		if v >= o.level {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOutputsLocked writes b to the added outputs taking entries at v,
+// l.mutex must be held.
+func (l *Logger) writeOutputsLocked(v Level, b []byte) {
+	outputs, _ := l.outputs.Load().([]*levelOutput)
+	for _, o := range outputs {
+		if v < o.level {
+			continue
+		}
+		n, err := l.writeRetry(o.w, b)
+		atomic.AddUint64(&o.bytes, uint64(n))
+		if err != nil {
+			l.handleError(err)
+		}
+	}
+}
+
+// outputBytes returns the bytes written to each added output.
+func (l *Logger) outputBytes() []uint64 {
+	outputs, _ := l.outputs.Load().([]*levelOutput)
+	if len(outputs) == 0 {
+		return nil
+	}
+	n := make([]uint64, len(outputs))
+	for i, o := range outputs {
+		n[i] = atomic.LoadUint64(&o.bytes)
+	}
+	return n
+}
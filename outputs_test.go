@@ -0,0 +1,73 @@
+package log
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddOutputLevel(t *testing.T) {
+	var main, console, verbose syncBuffer
+	l := newTestLogger(&main)
+	l.SetLevel(INFO)
+	l.AddOutputLevel(&console, WARN)
+	l.AddOutputLevel(&verbose, DEBUG)
+	l.Debug("d")
+	l.Info("i")
+	l.Warn("w")
+	l.Error("e")
+
+	tests := []struct {
+		name string
+		out  *syncBuffer
+		want []string
+	}{
+		{"main", &main, []string{"INFO i", "WARN w", "ERROR e"}},
+		{"console", &console, []string{"WARN w", "ERROR e"}},
+		// below the level of the Logger, still taken by an output
+		{"verbose", &verbose, []string{"DEBUG d", "INFO i", "WARN w", "ERROR e"}},
+	}
+	for _, tt := range tests {
+		if got := tt.out.lines(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: lines = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	want := []uint64{uint64(len(console.String())), uint64(len(verbose.String()))}
+	if got := l.Stats().OutputBytes; !reflect.DeepEqual(got, want) {
+		t.Errorf("OutputBytes = %v, want %v", got, want)
+	}
+	if st := l.Stats().Suppressed; st.Level != 0 {
+		t.Errorf("suppressed %+v, the DEBUG went to an output", st)
+	}
+}
+
+func TestAddOutputLevelEarlyExit(t *testing.T) {
+	var main, console syncBuffer
+	l := newTestLogger(&main)
+	l.SetLevel(WARN)
+	l.AddOutputLevel(&console, ERROR)
+	l.Info("dropped")
+	if main.String() != "" || console.String() != "" || l.Stats().Suppressed.Level != 1 {
+		t.Errorf("main %q, console %q, suppressed %+v", main.String(), console.String(), l.Stats().Suppressed)
+	}
+}
+
+func TestAddOutputLevelWriteError(t *testing.T) {
+	var main, console syncBuffer
+	l := newTestLogger(&main)
+	broken := &failingWriter{}
+	broken.fail(errors.New("broken pipe"))
+	var handled []error
+	l.SetErrorHandler(func(err error) { handled = append(handled, err) })
+	l.AddOutputLevel(broken, INFO)
+	l.AddOutputLevel(&console, INFO)
+	l.Info("m")
+
+	if main.String() != "INFO m\n" || console.String() != "INFO m\n" || len(handled) != 1 {
+		t.Errorf("main %q, console %q, handled %v", main.String(), console.String(), handled)
+	}
+	if got := l.Stats().OutputBytes; !reflect.DeepEqual(got, []uint64{0, 7}) {
+		t.Errorf("OutputBytes = %v", got)
+	}
+}
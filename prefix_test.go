@@ -0,0 +1,72 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoratePrefix(t *testing.T) {
+	tests := []struct {
+		prefix, want string
+	}{
+		{"", ""},
+		{"api", "[api] "},
+		{"[api]", "[api] "},
+		{"[api", "[[api] "},
+		{"a b", "[a b] "},
+	}
+	for _, tt := range tests {
+		if got := decoratePrefix(tt.prefix); got != tt.want {
+			t.Errorf("decoratePrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixTags(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${prefix_decorated}${message}|${prefix}|\n")
+	l.Info("none")
+	l.SetPrefix("api")
+	l.Info("set")
+
+	// the raw tag is left as is
+	want := []string{"none||", "[api] set|api|"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestDefaultFormatPrefix(t *testing.T) {
+	var out syncBuffer
+	l := New("", INFO, 0, 0)
+	l.SetOutput(&out)
+	l.Info("none")
+	l.SetPrefix("api")
+	l.Info("set")
+
+	lines := out.lines()
+	if !regexp.MustCompile(`^\d{4}-`).MatchString(lines[0]) || !regexp.MustCompile(`^\[api\] \d{4}-`).MatchString(lines[1]) {
+		t.Errorf("lines = %q, want the timestamp first, then after the decorated prefix", lines)
+	}
+}
+
+// TestTailSinceAfterThePrefix reads the timestamp after the decorated prefix.
+func TestTailSinceAfterThePrefix(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	content := "[api] 2024-01-02 03:04:05.000 INFO:old\n[api] 2024-01-02 03:04:07.000 INFO:recent\n"
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+	l.SetPrefix("api")
+	since := time.Date(2024, 1, 2, 3, 4, 6, 0, time.Local)
+	if got, err := l.TailSince(since); err != nil || len(got) != 1 || !strings.HasSuffix(got[0], "recent") {
+		t.Errorf("TailSince = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+)
+
+// format preset names, see SetFormatPreset
+const (
+	FormatDefault    = "default"
+	FormatMinimal    = "minimal"
+	FormatDetailed   = "detailed"
+	FormatJSONCompat = "json"
+	FormatApache     = "apache"
+)
+
+var presets = map[string]string{
+	FormatDefault:  defaultFormat,
+	FormatMinimal:  "${time_local} ${level} ${message}${fields}\n",
+	FormatDetailed: "${prefix_decorated}${time_local} ${level}:${pid}:${long_file}:${line}:${func}: ${message}${fields}\n",
+	FormatApache:   ApacheCombined,
+	// FormatJSONCompat switches to the JSON output instead of a template
+	FormatJSONCompat: "",
+}
+
+// SetFormatPreset switches to one of the named built-in formats.
+func (l *Logger) SetFormatPreset(name string) error {
+	f, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("log: unknown format preset %q", name)
+	}
+	if name == FormatJSONCompat {
+		l.EnableJSON()
+		return nil
+	}
+	if err := l.SetFormat(f); err != nil {
+		return err
+	}
+	l.DisableJSON()
+	return nil
+}
+
+// Presets lists the names accepted by SetFormatPreset.
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func SetFormatPreset(name string) error {
+	return global.SetFormatPreset(name)
+}
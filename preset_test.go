@@ -0,0 +1,52 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatPresets(t *testing.T) {
+	for _, policy := range []int{UnknownTagPlaceholder, UnknownTagEmpty, UnknownTagError} {
+		for _, name := range Presets() {
+			var out syncBuffer
+			l := newTestLogger(&out)
+			l.SetUnknownTagPolicy(policy)
+			if err := l.SetFormatPreset(name); err != nil {
+				t.Errorf("policy %d: SetFormatPreset(%q) = %v", policy, name, err)
+				continue
+			}
+			l.WithField("k", "v").Info("preset")
+
+			got := out.String()
+			// the Apache format renders request fields, not the message
+			if got == "" || name != FormatApache && !strings.Contains(got, "preset") ||
+				strings.Contains(got, "[unknown tag") || strings.Contains(got, "${") {
+				t.Errorf("policy %d, preset %q: output = %q", policy, name, got)
+			}
+			if name == FormatJSONCompat && !json.Valid([]byte(got)) {
+				t.Errorf("preset %q: output = %q, want JSON", name, got)
+			}
+		}
+	}
+	if err := SetFormatPreset("nope"); err == nil {
+		t.Error("SetFormatPreset accepted an unknown preset")
+	}
+}
+
+func TestFormatPresetError(t *testing.T) {
+	presets["broken"] = "${nope} ${message}\n"
+	defer delete(presets, "broken")
+
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.SetUnknownTagPolicy(UnknownTagError)
+	if err := l.SetFormatPreset("broken"); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("SetFormatPreset = %v, want the unknown tag reported", err)
+	}
+	l.Info("kept")
+	if !json.Valid([]byte(out.String())) {
+		t.Errorf("output = %q, want the JSON output kept", out.String())
+	}
+}
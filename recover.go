@@ -0,0 +1,98 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// partSuffix marks archives being copied or compressed, they get their
+// name once complete.
+const partSuffix = ".part"
+
+// recoverRotations finishes the rotations of the file of s interrupted by
+// a crash, once per file and before the first entry is written to it: a
+// rotated file is renamed to name.N.tmp (name.tmp by older versions)
+// right away, the archival which follows may not have run. They are
+// archived oldest first, and the partial copies and compressions left
+// behind removed.
+func (l *Logger) recoverRotations(s *sharedFile) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.recovered {
+		return
+	}
+	s.recovered = true
+
+	name := s.name
+	dir, base := filepath.Dir(name), filepath.Base(name)
+	list, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var leftovers []os.FileInfo
+	for _, fi := range list {
+		if !fi.IsDir() && isRotationTmp(base, fi.Name()) {
+			leftovers = append(leftovers, fi)
+		}
+	}
+
+	archiveDir, _ := l.archiveLayout(name)
+	dirs := []string{archiveDir}
+	if l.datedArchives {
+		days, _ := archiveDays(archiveDir)
+		dirs = append(dirs, days...)
+	}
+	for _, d := range dirs {
+		parts, _ := filepath.Glob(filepath.Join(d, globEscape(base)+"*"+partSuffix))
+		for _, p := range parts {
+			os.Remove(p)
+			l.diag("removed the partial archive %s", p)
+		}
+	}
+
+	sort.Slice(leftovers, func(i, j int) bool {
+		return leftovers[i].ModTime().Before(leftovers[j].ModTime())
+	})
+	for _, fi := range leftovers {
+		backupFile := filepath.Join(dir, fi.Name())
+		l.diag("archiving %s, left by an interrupted rotation", backupFile)
+		_, archive := l.archiver(name, backupFile, fi.ModTime())
+		if err := archive(); err != nil {
+			l.handleError(err)
+		}
+	}
+}
+
+// isRotationTmp reports whether name is a temporary name given by rotate
+// to the file base: base.N.tmp, or base.tmp as before they were numbered.
+func isRotationTmp(base, name string) bool {
+	if name == base+".tmp" {
+		return true
+	}
+	if !strings.HasPrefix(name, base+".") || !strings.HasSuffix(name, ".tmp") {
+		return false
+	}
+	return allDigits(strings.TrimSuffix(name[len(base)+1:], ".tmp"))
+}
+
+// globEscape quotes the metacharacters of filepath.Match in s.
+func globEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(s)
+}
+
+// syncFile commits the content of the file name to stable storage.
+func syncFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// crashDuringRotation rotates name and exits before the archival runs, as
+// a crash or a kill right after the rename would.
+func crashDuringRotation(name string) {
+	l := New(name, INFO, 1, 5)
+	l.Info("before the crash")
+	l.file.maint.push(func() { select {} })
+	l.mutex.Lock()
+	l.file.mutex.Lock()
+	l.rotate("size", nil, nil)
+	os.Exit(0)
+}
+
+func TestRecoverRotations(t *testing.T) {
+	if dir := os.Getenv("LOG_TEST_CRASH_DIR"); dir != "" {
+		crashDuringRotation(filepath.Join(dir, "app.log"))
+		return
+	}
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRecoverRotations$")
+	cmd.Env = append(os.Environ(), "LOG_TEST_CRASH_DIR="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("crashing process: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(name + ".1.tmp"); err != nil {
+		t.Fatalf("the crash left no rotation behind: %v", err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "app.log.2"+partSuffix), []byte("partial"), 0644)
+
+	l := New(name, INFO, 1, 5)
+	defer l.Close()
+	l.Info("after the crash")
+
+	archive := readFile(t, name+".1")
+	if !strings.Contains(archive, "before the crash") {
+		t.Errorf("archive = %q, want the entry written before the crash", archive)
+	}
+	if active := readFile(t, name); !strings.Contains(active, "after the crash") || strings.Contains(active, "before the crash") {
+		t.Errorf("active file = %q", active)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	parts, _ := filepath.Glob(filepath.Join(dir, "*"+partSuffix))
+	if len(left)+len(parts) > 0 {
+		t.Errorf("leftovers not cleaned up: %v %v", left, parts)
+	}
+}
+
+func TestRecoverUnnumberedTmp(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(name+".tmp", []byte("rotated by an older version\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(name, INFO, 1, 5)
+	defer l.Close()
+	l.Info("first entry")
+
+	if got := readFile(t, name+".1"); got != "rotated by an older version\n" {
+		t.Errorf("archive = %q", got)
+	}
+	if _, err := os.Stat(name + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("%s.tmp still exists: %v", name, err)
+	}
+}
+
+func TestIsRotationTmp(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app.log.tmp", true},
+		{"app.log.1.tmp", true},
+		{"app.log.12.tmp", true},
+		{"app.log..tmp", false},
+		{"app.log.x.tmp", false},
+		{"app.log.-1.tmp", false},
+		{"app.log.1", false},
+		{"app.logtmp", false},
+		{"other.log.1.tmp", false},
+		{"app.log.1.tmp.gz", false},
+	}
+	for _, tt := range tests {
+		if got := isRotationTmp("app.log", tt.name); got != tt.want {
+			t.Errorf("isRotationTmp(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
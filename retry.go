@@ -0,0 +1,80 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy retries the writes failing with a transient error, e.g. to a
+// network connection or a FUSE mount, see WithRetry.
+type RetryPolicy struct {
+	Attempts   int           // writes of an entry at most, retries are off below 2
+	Backoff    time.Duration // before the first retry, doubled for each next one
+	MaxBackoff time.Duration // longest wait between two attempts, 0 for no limit
+	NoSpace    bool          // retry ENOSPC too, e.g. while old logs are cleaned up
+
+	// Retryable overrides which errors are transient: by default network
+	// timeouts, EAGAIN, EINTR and ENOBUFS.
+	Retryable func(err error) bool
+}
+
+// WithRetry retries the failed writes of entries, to the file or the
+// output and to the AddOutputLevel outputs, according to p. The logger
+// stays locked meanwhile, so later entries wait behind the retried one and
+// are never reordered. An entry still failing after the last attempt is
+// dropped and counted in Stats.Dropped.
+func WithRetry(p RetryPolicy) Option {
+	return func(l *Logger) {
+		l.retry = p
+	}
+}
+
+func (l *Logger) SetRetryPolicy(p RetryPolicy) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.retry = p
+}
+
+func SetRetryPolicy(p RetryPolicy) {
+	global.SetRetryPolicy(p)
+}
+
+func (p RetryPolicy) transient(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.ENOBUFS) || p.NoSpace && errors.Is(err, syscall.ENOSPC)
+}
+
+// writeRetry is writeFull retried with the retry policy, l.mutex must be
+// held. The rest of b is written on each attempt, so a partial write
+// isn't repeated.
+func (l *Logger) writeRetry(w io.Writer, b []byte) (int, error) {
+	written, err := writeFull(w, b)
+	p := l.retry
+	backoff := p.Backoff
+	for attempt := 1; err != nil && attempt < p.Attempts && p.transient(err); attempt++ {
+		l.diag("write failed (%v), retrying in %s", err, backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+		var n int
+		n, err = writeFull(w, b[written:])
+		written += n
+	}
+	if err != nil {
+		atomic.AddUint64(&l.dropped, 1)
+	}
+	return written, err
+}
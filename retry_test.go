@@ -0,0 +1,190 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyWriter fails its next failures writes with err, after writing half
+// of them when partial.
+type flakyWriter struct {
+	syncBuffer
+	fails    sync.Mutex
+	failures int
+	calls    int
+	err      error
+	partial  bool
+}
+
+func (w *flakyWriter) Write(b []byte) (int, error) {
+	w.fails.Lock()
+	w.calls++
+	failing := w.failures > 0
+	if failing {
+		w.failures--
+	}
+	w.fails.Unlock()
+	if !failing {
+		return w.syncBuffer.Write(b)
+	}
+	if w.partial && len(b) > 1 {
+		n, _ := w.syncBuffer.Write(b[:len(b)/2])
+		return n, w.err
+	}
+	return 0, w.err
+}
+
+func newFlakyLogger(w *flakyWriter) *Logger {
+	l := newTestLogger(&syncBuffer{})
+	l.SetOutput(w)
+	return l
+}
+
+func TestRetryTransient(t *testing.T) {
+	p := RetryPolicy{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{syscall.EAGAIN, true},
+		{syscall.EINTR, true},
+		{&os.PathError{Op: "write", Path: "x", Err: syscall.ENOBUFS}, true},
+		{&net.OpError{Op: "write", Err: os.ErrDeadlineExceeded}, true},
+		{syscall.ENOSPC, false},
+		{syscall.EBADF, false},
+		{io.ErrClosedPipe, false},
+	}
+	for _, tt := range tests {
+		if got := p.transient(tt.err); got != tt.want {
+			t.Errorf("transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if !(RetryPolicy{NoSpace: true}).transient(syscall.ENOSPC) {
+		t.Error("ENOSPC isn't retried with NoSpace")
+	}
+	custom := RetryPolicy{Retryable: func(err error) bool { return err == io.ErrClosedPipe }}
+	if !custom.transient(io.ErrClosedPipe) || custom.transient(syscall.EAGAIN) {
+		t.Error("Retryable doesn't override the default")
+	}
+}
+
+func TestRetryRecovers(t *testing.T) {
+	for _, partial := range []bool{false, true} {
+		w := &flakyWriter{failures: 4, err: syscall.EAGAIN, partial: partial}
+		l := newFlakyLogger(w)
+		l.SetRetryPolicy(RetryPolicy{Attempts: 5, Backoff: time.Millisecond})
+		var errs []error
+		l.SetErrorHandler(func(err error) { errs = append(errs, err) })
+		l.Info("kept")
+		l.Info("next")
+
+		// written once, a partial write not repeated
+		if got := w.String(); got != "INFO kept\nINFO next\n" {
+			t.Errorf("partial %v: output = %q", partial, got)
+		}
+		if len(errs) != 0 || l.Stats().Dropped != 0 {
+			t.Errorf("partial %v: errors %v, %d dropped", partial, errs, l.Stats().Dropped)
+		}
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	w := &flakyWriter{failures: 1 << 30, err: syscall.EAGAIN}
+	l := newFlakyLogger(w)
+	l.SetRetryPolicy(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+	var errs []error
+	l.SetErrorHandler(func(err error) { errs = append(errs, err) })
+	l.Info("lost")
+
+	if w.calls != 3*maxWriteRetries {
+		t.Errorf("%d writes, want %d", w.calls, 3*maxWriteRetries)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], syscall.EAGAIN) || l.Stats().Dropped != 1 {
+		t.Errorf("errors %v, %d dropped", errs, l.Stats().Dropped)
+	}
+
+	// a permanent error isn't retried
+	w = &flakyWriter{failures: 1 << 30, err: syscall.EBADF}
+	l = newFlakyLogger(w)
+	l.SetRetryPolicy(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+	l.SetErrorHandler(func(error) {})
+	l.Info("lost")
+	if w.calls != maxWriteRetries || l.Stats().Dropped != 1 {
+		t.Errorf("%d writes, %d dropped", w.calls, l.Stats().Dropped)
+	}
+
+	// nor anything without a policy
+	w = &flakyWriter{failures: 1 << 30, err: syscall.EAGAIN}
+	l = newFlakyLogger(w)
+	l.SetErrorHandler(func(error) {})
+	l.Info("lost")
+	if w.calls != maxWriteRetries {
+		t.Errorf("%d writes without a policy", w.calls)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	w := &flakyWriter{failures: 1 << 30, err: syscall.EAGAIN}
+	l := newFlakyLogger(w)
+	l.SetRetryPolicy(RetryPolicy{Attempts: 5, Backoff: time.Millisecond, MaxBackoff: 3 * time.Millisecond})
+	l.SetErrorHandler(func(error) {})
+	l.Info("lost")
+
+	var waits []string
+	for _, d := range l.Stats().Diagnostics {
+		if i := strings.Index(d.Message, "retrying in "); i >= 0 {
+			waits = append(waits, d.Message[i+len("retrying in "):])
+		}
+	}
+	want := []string{"1ms", "2ms", "3ms", "3ms"}
+	if strings.Join(waits, " ") != strings.Join(want, " ") {
+		t.Errorf("waits %q, want %q", waits, want)
+	}
+}
+
+// TestRetryKeepsTheOrder logs from several goroutines to a flaky output
+// added with AddOutputLevel: entries wait behind the retried one.
+func TestRetryKeepsTheOrder(t *testing.T) {
+	w := &flakyWriter{failures: 20, err: syscall.EAGAIN, partial: true}
+	l := newTestLogger(&syncBuffer{})
+	l.AddOutputLevel(w, INFO)
+	l.SetRetryPolicy(RetryPolicy{Attempts: 50, Backoff: time.Microsecond})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				l.Info(strconv.Itoa(g) + " " + strconv.Itoa(i))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	next := map[string]int{}
+	lines := w.lines()
+	for _, line := range lines {
+		var g, i int
+		if _, err := fmt.Sscanf(line, "INFO %d %d", &g, &i); err != nil {
+			t.Fatalf("line %q is mangled", line)
+		}
+		key := strconv.Itoa(g)
+		if i != next[key] {
+			t.Fatalf("line %q, want entry %d of goroutine %d", line, next[key], g)
+		}
+		next[key]++
+	}
+	if len(lines) != 200 || l.Stats().Dropped != 0 {
+		t.Errorf("%d lines, %d dropped", len(lines), l.Stats().Dropped)
+	}
+}
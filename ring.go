@@ -0,0 +1,144 @@
+package log
+
+import (
+	"io"
+	"sync"
+)
+
+var (
+	replayMark  = []byte("[replayed] ")
+	replayField = []byte(`"replayed":true`)
+)
+
+// ringBuffer keeps the last formatted entries that were below the output
+// level, so they can be replayed when something goes wrong.
+type ringBuffer struct {
+	mutex   sync.Mutex
+	entries [][]byte
+	next    int
+	full    bool
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	return &ringBuffer{entries: make([][]byte, capacity)}
+}
+
+func (r *ringBuffer) add(b []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries[r.next] = append(r.entries[r.next][:0], b...)
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// each calls fn on the recorded entries, oldest first.
+func (r *ringBuffer) each(fn func(b []byte)) {
+	if r.full {
+		for _, b := range r.entries[r.next:] {
+			fn(b)
+		}
+	}
+	for _, b := range r.entries[:r.next] {
+		fn(b)
+	}
+}
+
+func (r *ringBuffer) drain(fn func(b []byte)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.each(fn)
+	r.next = 0
+	r.full = false
+}
+
+func (r *ringBuffer) dump(w io.Writer) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var err error
+	r.each(func(b []byte) {
+		if err == nil {
+			_, err = w.Write(b)
+		}
+	})
+	return err
+}
+
+// EnableRingBuffer keeps the last capacity entries at or above captureLevel
+// that are below the output level in memory. They are replayed to the output
+// right before the next entry at or above the ring trigger level (ERROR by default).
+func (l *Logger) EnableRingBuffer(capacity int, captureLevel Level) {
+	l.lazyInit()
+	if capacity <= 0 {
+		l.ring = nil
+		return
+	}
+	l.ring = newRingBuffer(capacity)
+	l.ringLevel = captureLevel
+}
+
+func (l *Logger) DisableRingBuffer() {
+	l.ring = nil
+}
+
+func (l *Logger) SetRingTrigger(level Level) {
+	l.lazyInit()
+	l.ringTrigger = level
+}
+
+// DumpRing writes the entries currently held in the ring buffer to w,
+// without clearing them.
+func (l *Logger) DumpRing(w io.Writer) error {
+	r := l.ring
+	if r == nil {
+		return nil
+	}
+	return r.dump(w)
+}
+
+// replayRing flushes the ring to the output, l.mutex must be held.
+func (l *Logger) replayRing() {
+	r := l.ring
+	if r == nil {
+		return
+	}
+	json := l.template.Load().(*textFormat).json
+	r.drain(func(b []byte) {
+		l.writeLocked(markReplayed(b, json))
+	})
+}
+
+// markReplayed prefixes a text entry with replayMark and adds the
+// "replayed" field to a JSON one, keeping it a single object.
+func markReplayed(b []byte, json bool) []byte {
+	if !json || len(b) < 2 || b[0] != '{' {
+		return append(replayMark[:len(replayMark):len(replayMark)], b...)
+	}
+	out := make([]byte, 0, len(b)+len(replayField)+1)
+	out = append(append(out, '{'), replayField...)
+	if b[1] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, b[1:]...)
+}
+
+func EnableRingBuffer(capacity int, captureLevel Level) {
+	global.EnableRingBuffer(capacity, captureLevel)
+}
+
+func DisableRingBuffer() {
+	global.DisableRingBuffer()
+}
+
+func SetRingTrigger(level Level) {
+	global.SetRingTrigger(level)
+}
+
+func DumpRing(w io.Writer) error {
+	return global.DumpRing(w)
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newRingLogger(w *syncBuffer, capacity int, capture Level) *Logger {
+	l := newTestLogger(w)
+	l.SetLevel(ERROR)
+	l.SetExitFunc(func(int) {})
+	l.EnableRingBuffer(capacity, capture)
+	return l
+}
+
+func TestRingBufferEvictsTheOldest(t *testing.T) {
+	var out syncBuffer
+	l := newRingLogger(&out, 3, DEBUG)
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		l.Info(m)
+	}
+	var dump bytes.Buffer
+	if err := l.DumpRing(&dump); err != nil {
+		t.Fatal(err)
+	}
+	if got := dump.String(); got != "INFO c\nINFO d\nINFO e\n" {
+		t.Errorf("ring = %q, want the last 3 entries", got)
+	}
+	if out.String() != "" {
+		t.Errorf("output = %q, want nothing before the trigger", out.String())
+	}
+}
+
+func TestRingBufferCapture(t *testing.T) {
+	var out syncBuffer
+	l := newRingLogger(&out, 10, INFO)
+	l.Debug("below the capture level")
+	l.Info("captured")
+	l.Warn("captured too")
+	l.Error("written")
+	l.Info("after")
+
+	want := []string{"[replayed] INFO captured", "[replayed] WARN captured too", "ERROR written"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	// the replay drained the ring, entries at the output level aren't kept
+	var dump bytes.Buffer
+	l.DumpRing(&dump)
+	if got := dump.String(); got != "INFO after\n" {
+		t.Errorf("ring = %q, want only the entry after the replay", got)
+	}
+}
+
+func TestRingBufferReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger Level
+		log     func(l *Logger)
+		want    string
+	}{
+		{"error", ERROR, func(l *Logger) { l.Error("boom") }, "ERROR boom"},
+		{"fatal", ERROR, func(l *Logger) { l.Fatal("boom") }, "FATAL boom"},
+		{"fatal only", FATAL, func(l *Logger) { l.Fatal("boom") }, "FATAL boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := newRingLogger(&out, 10, DEBUG)
+			l.SetRingTrigger(tt.trigger)
+			l.Debug("context")
+			tt.log(l)
+
+			lines := out.lines()
+			if len(lines) < 2 || lines[0] != "[replayed] DEBUG context" || lines[1] != tt.want {
+				t.Errorf("lines = %q, want the ring replayed before %q", lines, tt.want)
+			}
+		})
+	}
+
+	var out syncBuffer
+	l := newRingLogger(&out, 10, DEBUG)
+	l.SetRingTrigger(FATAL)
+	l.Debug("context")
+	l.Error("not the trigger")
+	if got := out.lines(); len(got) != 1 || got[0] != "ERROR not the trigger" {
+		t.Errorf("lines = %q, want no replay below the trigger", got)
+	}
+}
+
+func TestRingBufferJSON(t *testing.T) {
+	var out syncBuffer
+	l := newRingLogger(&out, 10, DEBUG)
+	l.EnableJSON()
+	l.WithField("id", 7).Debug("context")
+	l.Error("boom")
+
+	lines := out.lines()
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want the replayed entry and the error", lines)
+	}
+	var replayed, trigger map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &replayed); err != nil {
+		t.Fatalf("replayed line %q: %v", lines[0], err)
+	}
+	if replayed["replayed"] != true || replayed["msg"] != "context" || replayed["id"] != float64(7) {
+		t.Errorf("replayed = %v", replayed)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &trigger); err != nil {
+		t.Fatalf("trigger line %q: %v", lines[1], err)
+	}
+	if _, ok := trigger["replayed"]; ok || trigger["msg"] != "boom" {
+		t.Errorf("trigger = %v", trigger)
+	}
+}
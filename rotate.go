@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Rotate switches to a new file right away and archives the current one
+// as the size limit would, e.g. to get a complete file for a support
+// bundle. It returns once the archive is in place, with the error of any
+// step. Logging carries on in the new file meanwhile.
+func (l *Logger) Rotate() error {
+	l.mutex.Lock()
+	f := l.file
+	if f == nil {
+		l.mutex.Unlock()
+		return ErrNoFile
+	}
+	done := make(chan error, 1)
+	f.mutex.Lock()
+	err := l.rotate("manual", nil, done)
+	f.mutex.Unlock()
+	l.mutex.Unlock()
+
+	if aerr := <-done; err == nil {
+		err = aerr
+	}
+	return err
+}
+
+func Rotate() error {
+	return global.Rotate()
+}
+
+// writeHeaderLocked starts a fresh file with the JSON schema header when
+// enabled, f.mutex must be held.
+func (l *Logger) writeHeaderLocked(f *sharedFile) {
+	if f.fresh && l.jsonConfig.EmitSchemaHeader && l.template.Load().(*textFormat).json {
+		h := l.schemaHeader()
+		n, _ := writeFull(f, h)
+		f.size += n
+		f.lines++
+		f.entries++
+	}
+	f.fresh = false
+}
+
+// writeMarkerLocked writes the first entry of a file switched to by a
+// rotation or a reopen: where the previous content went, its size and
+// why. It's formatted like the other entries but only goes to the file,
+// and never triggers a rotation itself: it's left out when the next entry
+// wouldn't fit after it. f.mutex must be held.
+func (l *Logger) writeMarkerLocked(f *sharedFile, reason, from string, size int, next []byte) {
+	l.writeHeaderLocked(f)
+	// the entry comes from the logger itself
+	_, file, line, _ := runtime.Caller(0)
+	e := Entry{
+		Level:   INFO,
+		Time:    time.Now(),
+		Message: fmt.Sprintf("rotated from %s, previous size %d", from, size),
+		Fields:  Fields{"reason": reason},
+		File:    file,
+		Line:    line,
+		Forced:  true,
+		logger:  l,
+	}
+	var buf bytes.Buffer
+	var err error
+	if tf := l.template.Load().(*textFormat); tf.json {
+		err = l.formatJSON(&buf, tf, &e)
+	} else {
+		err = l.formatText(&buf, tf, &e)
+	}
+	if err != nil {
+		l.handleError(err)
+		return
+	}
+	l.terminate(&buf)
+	out := fillOffset(buf.Bytes(), f.size)
+	if next != nil && l.maxsize > 0 && f.size+len(out)+len(next)+l.footerSize(f, out, next) > l.maxsize {
+		l.diag("left out the marker of a rotation, the next entry wouldn't fit after it")
+		return
+	}
+	n, err := writeFull(f, out)
+	f.size += n
+	lines := bytes.Count(out[:n], []byte(l.eol()))
+	f.lines += lines
+	f.entries += lines
+	if err != nil {
+		l.handleError(err)
+	}
+}
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRotateWithoutAFile(t *testing.T) {
+	l := New("", INFO, 0, 0)
+	if err := l.Rotate(); err != ErrNoFile {
+		t.Errorf("Rotate = %v, want ErrNoFile", err)
+	}
+	l = New(filepath.Join(t.TempDir(), "app.log"), INFO, 0, 0, WithLazyOpen(true))
+	if err := l.Rotate(); err != ErrNoFile {
+		t.Errorf("Rotate before the first write = %v, want ErrNoFile", err)
+	}
+
+	old := GetLogger()
+	defer SetLogger(old)
+	SetLogger(New("", INFO, 0, 0))
+	if err := Rotate(); err != ErrNoFile {
+		t.Errorf("package Rotate = %v, want ErrNoFile", err)
+	}
+}
+
+func TestRotateReturnsWithTheArchive(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 5)
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.SetCompression(Gzip)
+	l.Info("before")
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// compressed by the time Rotate returns
+	if got := readGzip(t, name+".1.gz"); got != "before\n" {
+		t.Errorf("archive = %q", got)
+	}
+	l.Info("after")
+	if got := readFile(t, name); !strings.HasSuffix(got, "after\n") || strings.Contains(got, "before\n") {
+		t.Errorf("active file = %q", got)
+	}
+}
+
+// TestRotateWhileLogging rotates by hand while goroutines log and the size
+// limit rotates too: no entry is lost or duplicated.
+func TestRotateWhileLogging(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 1000, WithMaxSize(2*KB))
+	defer l.Close()
+	l.SetFormat("${message}\n")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				l.Info("entry " + strconv.Itoa(g) + "-" + strconv.Itoa(i))
+			}
+		}(g)
+	}
+	for i := 0; i < 10; i++ {
+		if err := l.Rotate(); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+	if err := l.Barrier(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(name + "*")
+	seen := map[string]int{}
+	for _, file := range files {
+		for _, line := range strings.Split(readFile(t, file), "\n") {
+			if strings.HasPrefix(line, "entry ") {
+				seen[line]++
+			}
+		}
+	}
+	if len(seen) != 400 {
+		t.Errorf("%d entries found, want 400", len(seen))
+	}
+	for line, n := range seen {
+		if n != 1 {
+			t.Errorf("%q written %d times", line, n)
+		}
+	}
+}
+
+func readGzip(t *testing.T, name string) string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var markerFrom = regexp.MustCompile(`rotated from (\S+), previous size`)
+
+func TestRotateMarkerNamesTheArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"dated", []Option{WithDatedArchives(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			name := filepath.Join(dir, "app.log")
+			l := New(name, INFO, 0, 5, tt.opts...)
+			defer l.Close()
+			l.SetCompression(Gzip)
+			l.Info("first file")
+			if err := l.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+
+			m := markerFrom.FindStringSubmatch(readFile(t, name))
+			if m == nil {
+				t.Fatalf("no rotation marker in %q", readFile(t, name))
+			}
+			if _, err := os.Stat(m[1]); err != nil {
+				t.Errorf("the marker names %s: %v", m[1], err)
+			}
+		})
+	}
+}
+
+// TestRotatedFilesStayUnderTheMaxSize writes entries of all sizes up to
+// nearly the limit: the marker, and the JSON header, only go first when
+// the entry fits after them.
+func TestRotatedFilesStayUnderTheMaxSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(l *Logger)
+	}{
+		{"text", func(l *Logger) {}},
+		{"json header", func(l *Logger) {
+			l.EnableJSON()
+			l.SetJSONConfig(JSONConfig{EmitSchemaHeader: true})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "app.log")
+			l := New(name, INFO, 0, 1000, WithMaxSize(KB))
+			tt.setup(l)
+			for i := 0; i < 60; i++ {
+				l.Info(strings.Repeat("x", i*i%900))
+			}
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			files, _ := filepath.Glob(name + "*")
+			markers := 0
+			for _, file := range files {
+				fi, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				content := readFile(t, file)
+				marker := markerFrom.MatchString(content)
+				if marker {
+					markers++
+				}
+				// only an entry that doesn't fit with the header may be over
+				if fi.Size() > int64(KB) && (marker || strings.Count(content, "\n") > 2) {
+					t.Errorf("%s is %d bytes, over the max size", file, fi.Size())
+				}
+			}
+			if len(files) < 10 || markers == 0 {
+				t.Errorf("%d files, %d markers, the test needs both", len(files), markers)
+			}
+		})
+	}
+}
+
+// TestMaxFileSize pins the largest file a rotation can leave: everything
+// a file holds, the JSON header, the marker and the integrity footer
+// included, fits in the max size, except a single entry larger than it,
+// which gets a file of its own.
+func TestMaxFileSize(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 1000, WithMaxSize(KB), WithIntegrityFooter(true))
+	l.EnableJSON()
+	l.SetJSONConfig(JSONConfig{EmitSchemaHeader: true})
+	for i := 0; i < 60; i++ {
+		l.Info(strings.Repeat("x", i*i%600))
+		if i%20 == 10 {
+			l.Info(strings.Repeat("y", 2048))
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, _ := filepath.Glob(name + ".*")
+	if len(archives) < 10 {
+		t.Fatalf("archives = %v, the test needs rotations", archives)
+	}
+	large := 0
+	for _, archive := range archives {
+		fi, err := os.Stat(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := readFile(t, archive)
+		if !strings.HasPrefix(content, `{"schema":`) || !strings.Contains(content, `{"integrity":`) {
+			t.Errorf("%s misses the header or the footer", archive)
+		}
+		if fi.Size() <= int64(KB) {
+			continue
+		}
+		large++
+		if !strings.Contains(content, strings.Repeat("y", 2048)) || strings.Contains(content, "xx") {
+			t.Errorf("%s is %d bytes, over the max size without a single large entry", archive, fi.Size())
+		}
+	}
+	if large != 3 {
+		t.Errorf("%d files over the max size, want one per large entry", large)
+	}
+}
+
+// TestPlainRotates checks that plain lines, the first ones written by the
+// Logger, count towards the size and trigger the rotations.
+func TestPlainRotates(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(name string) *Logger
+	}{
+		{"new", func(name string) *Logger { return New(name, INFO, 0, 100, WithMaxSize(KB)) }},
+		{"lazy open", func(name string) *Logger { return New(name, INFO, 0, 100, WithMaxSize(KB), WithLazyOpen(true)) }},
+		{"zero logger", func(name string) *Logger {
+			l := &Logger{}
+			l.maxsize, l.backups = int(KB), 100
+			l.SetFile(name)
+			return l
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "app.log")
+			l := tt.new(name)
+			line := "BEGIN-ARTIFACT " + strings.Repeat("x", 80)
+			for i := 0; i < 40; i++ {
+				if err := l.Plain(INFO, line); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			files, _ := filepath.Glob(name + "*")
+			if len(files) < 4 {
+				t.Fatalf("files = %v, want the plain lines to rotate", files)
+			}
+			plain := 0
+			for _, file := range files {
+				fi, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Size() > int64(KB) {
+					t.Errorf("%s is %d bytes, over the max size", file, fi.Size())
+				}
+				plain += strings.Count(readFile(t, file), line+"\n")
+			}
+			if plain != 40 {
+				t.Errorf("%d plain lines written, want 40", plain)
+			}
+		})
+	}
+}
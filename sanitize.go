@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SetSanitize escapes control characters in the messages, events and
+// fields of text entries, so user input can't fake entries with newlines
+// or send escape sequences to a terminal: "a\nb" is written as `a\nb`.
+// The FATAL stack is left as is. JSON is always escaped.
+func (l *Logger) SetSanitize(enabled bool) {
+	l.sanitize = enabled
+}
+
+func SetSanitize(enabled bool) {
+	global.SetSanitize(enabled)
+}
+
+// isControl reports the C0 controls but tab, DEL and the C1 controls.
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\t' || r >= 0x7f && r <= 0x9f
+}
+
+// escapeControl escapes the controls in s and its invalid UTF-8 bytes,
+// which terminals may take for 8-bit controls: 0x9b starts a sequence
+// like ESC [ does.
+func escapeControl(s string) string {
+	if strings.IndexFunc(s, isControl) < 0 && utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(s[i:], "\uFFFD"):
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x80 && isControl(r):
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case isControl(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// escapeJSONControl escapes DEL and the C1 controls, which encoding/json
+// writes as is. They can only appear within strings, so the raw bytes are
+// replaced.
+func escapeJSONControl(b []byte) []byte {
+	if bytes.IndexByte(b, 0x7f) < 0 && bytes.IndexByte(b, 0xc2) < 0 {
+		return b
+	}
+	out := make([]byte, 0, len(b)+12)
+	for i := 0; i < len(b); {
+		r, n := utf8.DecodeRune(b[i:])
+		if r >= 0x7f && r <= 0x9f {
+			out = append(out, fmt.Sprintf(`\u%04x`, r)...)
+		} else {
+			out = append(out, b[i:i+n]...)
+		}
+		i += n
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// adversarial are inputs trying to fake entries or drive a terminal.
+var adversarial = []struct {
+	name, in, escaped string
+}{
+	{"newline", "ok\nINFO forged entry", `ok\nINFO forged entry`},
+	{"crlf", "ok\r\nINFO forged", `ok\r\nINFO forged`},
+	{"carriage return", "secret\roverwritten", `secret\roverwritten`},
+	{"ansi color", "\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`},
+	{"ansi clear screen", "\x1b[2J\x1b[H", `\x1b[2J\x1b[H`},
+	{"osc title", "\x1b]0;owned\x07", `\x1b]0;owned\x07`},
+	{"nul and bell", "a\x00b\x07", `a\x00b\x07`},
+	{"del", "a\x7fb", `a\x7fb`},
+	{"c1 csi", "a\u009b31mb", `a\u009b31mb`},
+	{"raw 8-bit csi", "a\x9b31mb", `a\x9b31mb`},
+	{"invalid utf-8", "bad \xff\xfe end", `bad \xff\xfe end`},
+	{"truncated rune", "cut \xe2\x82", `cut \xe2\x82`},
+	{"replacement char", "kept �", "kept �"},
+	{"tab", "a\tb", "a\tb"},
+	{"unicode", "héllo 世界", "héllo 世界"},
+}
+
+func TestEscapeControl(t *testing.T) {
+	for _, tt := range adversarial {
+		if got := escapeControl(tt.in); got != tt.escaped {
+			t.Errorf("%s: escapeControl(%q) = %q, want %q", tt.name, tt.in, got, tt.escaped)
+		}
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	for _, tt := range adversarial {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.SetFormat("${level} ${event}|${message}${fields}\n")
+		l.SetSanitize(true)
+		l.Event(tt.in).Info(tt.in)
+		l.WithField(tt.in, tt.in).Info("fields")
+
+		lines := out.lines()
+		if len(lines) != 2 {
+			t.Errorf("%s: output = %q, want 2 lines", tt.name, out.String())
+			continue
+		}
+		if want := "INFO " + tt.escaped + "|" + tt.escaped; lines[0] != want {
+			t.Errorf("%s: line = %q, want %q", tt.name, lines[0], want)
+		}
+		if want := "INFO |fields " + tt.escaped + "="; !strings.HasPrefix(lines[1], want) {
+			t.Errorf("%s: line = %q, want the key escaped", tt.name, lines[1])
+		}
+		for _, r := range lines[1] {
+			if isControl(r) {
+				t.Errorf("%s: line = %q holds the control %U", tt.name, lines[1], r)
+			}
+		}
+		if !utf8.ValidString(out.String()) {
+			t.Errorf("%s: output = %q, want valid UTF-8", tt.name, out.String())
+		}
+	}
+}
+
+func TestSanitizeKeepsTheFatalStack(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetSanitize(true)
+	l.SetExitFunc(func(int) {})
+	l.Fatal("a\nb")
+
+	lines := out.lines()
+	if len(lines) < 2 || lines[0] != `FATAL a\nb` || !strings.Contains(out.String(), "goroutine") {
+		t.Errorf("output = %q, want the message escaped and the stack on its own lines", out.String())
+	}
+}
+
+func TestSanitizeDisabled(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.Info("a\nb")
+	if got := out.String(); got != "INFO a\nb\n" {
+		t.Errorf("output = %q, want the message as is", got)
+	}
+}
+
+func TestJSONEscapesAdversarialInput(t *testing.T) {
+	for _, tt := range adversarial {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.EnableJSON()
+		l.Event(tt.in).WithField("k", tt.in).Info(tt.in)
+
+		line := out.String()
+		if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+			t.Errorf("%s: output = %q, want a single line", tt.name, line)
+			continue
+		}
+		for _, r := range strings.TrimSuffix(line, "\n") {
+			if isControl(r) && r != '\t' {
+				t.Errorf("%s: output = %q holds the control %U", tt.name, line, r)
+			}
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("%s: output = %q, want valid UTF-8", tt.name, line)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Errorf("%s: line %q: %v", tt.name, line, err)
+			continue
+		}
+		// the valid input comes back as is, each invalid byte as U+FFFD
+		var want strings.Builder
+		for _, r := range tt.in {
+			want.WriteRune(r)
+		}
+		if v["msg"] != want.String() || v["k"] != want.String() {
+			t.Errorf("%s: msg = %q, k = %q, want %q", tt.name, v["msg"], v["k"], want.String())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Schedule rotates the file every day at a fixed local time and prunes
+// the archives past their retention, see WithSchedule.
+type Schedule struct {
+	RotateAt time.Duration // offset into the local day, 0 for midnight
+
+	// Retention returns how long an archive whose last entry was written
+	// at archiveTime is kept, 0 keeping it until the backups limit prunes
+	// it. nil disables the pruning by age.
+	Retention func(archiveTime time.Time) time.Duration
+}
+
+// WithSchedule rotates the file every day at s.RotateAt, unless it's
+// empty, and prunes the archives by age at the same time, driven by a
+// timer so that quiet loggers are rotated and pruned too. The age of an
+// archive is read from its name, see SuffixTimestamp, the default naming
+// with a retention. The backups limit still applies, set it high enough
+// for the retention. E.g. keep
+// Sunday's file for 8 weeks and the others for 2:
+//
+//	log.WithSchedule(log.Schedule{Retention: func(t time.Time) time.Duration {
+//		if t.Weekday() == time.Sunday {
+//			return 8 * 7 * 24 * time.Hour
+//		}
+//		return 2 * 7 * 24 * time.Hour
+//	}})
+func WithSchedule(s Schedule) Option {
+	return func(l *Logger) {
+		l.schedule = &s
+	}
+}
+
+// WithMaxAge removes the archives rotated more than d ago at each
+// rotation, and with WithSchedule at the scheduled time too. As with a
+// schedule retention, the archives are named with their time by default.
+// The backups limit still applies.
+func WithMaxAge(d time.Duration) Option {
+	return func(l *Logger) {
+		l.maxAge = d
+	}
+}
+
+// retention returns how long an archive is kept after archiveTime, the
+// shortest of the schedule retention and the max age, nil when neither
+// is set.
+func (l *Logger) retention() func(archiveTime time.Time) time.Duration {
+	var policy func(time.Time) time.Duration
+	if l.schedule != nil {
+		policy = l.schedule.Retention
+	}
+	maxAge := l.maxAge
+	if maxAge <= 0 {
+		return policy
+	}
+	return func(t time.Time) time.Duration {
+		if policy != nil {
+			if keep := policy(t); keep > 0 && keep < maxAge {
+				return keep
+			}
+		}
+		return maxAge
+	}
+}
+
+// nextRotation returns the first time at offset at into a day after now.
+func nextRotation(now time.Time, at time.Duration) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if next := day.Add(at); next.After(now) {
+		return next
+	}
+	return day.AddDate(0, 0, 1).Add(at)
+}
+
+// startSchedule runs the schedule until the output is closed, pruning
+// right away the archives which expired while the process wasn't running.
+func (l *Logger) startSchedule() {
+	stop := make(chan struct{})
+	l.scheduleStop = stop
+	l.runSchedule(false)
+	go func() {
+		for {
+			t := time.NewTimer(time.Until(nextRotation(time.Now(), l.schedule.RotateAt)))
+			select {
+			case <-stop:
+				t.Stop()
+				return
+			case <-t.C:
+			}
+			l.runSchedule(true)
+		}
+	}()
+}
+
+func (l *Logger) runSchedule(rotate bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	f := l.file
+	if f == nil {
+		return
+	}
+	f.mutex.Lock()
+	if rotate && f.size > 0 {
+		l.rotate("schedule", nil, nil)
+	}
+	name := f.name
+	f.mutex.Unlock()
+
+	retention := l.retention()
+	if retention == nil {
+		return
+	}
+	dir, naming := l.archiveLayout(name)
+	dated := l.datedArchives
+	// after the archival of the rotation just queued
+	f.maint.push(func() {
+		if err := l.pruneExpired(dir, filepath.Base(name), naming, dated, retention); err != nil {
+			l.handleError(err)
+		}
+	})
+}
+
+// pruneExpired removes the archives of base past their retention, the time
+// of an archive being its {time}, else the end of its day with dated
+// archives. Archives without either are left to the backups limit.
+func (l *Logger) pruneExpired(dir, base, naming string, dated bool, retention func(time.Time) time.Duration) error {
+	dirs := []string{dir}
+	if dated {
+		days, err := archiveDays(dir)
+		if err != nil {
+			return err
+		}
+		dirs = days
+	}
+	now := time.Now()
+	for _, d := range dirs {
+		archives, err := listArchives(d, base, naming)
+		if err != nil {
+			return err
+		}
+		for _, a := range archives {
+			at := a.time
+			if at.IsZero() && dated {
+				rel, _ := filepath.Rel(dir, d)
+				day, err := time.ParseInLocation(datedLayout, filepath.ToSlash(rel), time.Local)
+				if err == nil {
+					at = day.AddDate(0, 0, 1)
+				}
+			}
+			if at.IsZero() {
+				continue
+			}
+			if keep := retention(at); keep > 0 && now.Sub(at) > keep {
+				os.Remove(a.path)
+				l.diag("removed %s, past its retention of %s", a.path, keep)
+			}
+		}
+		if dated {
+			for ; d != dir && d != filepath.Dir(d); d = filepath.Dir(d) {
+				if os.Remove(d) != nil {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNextRotation(t *testing.T) {
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		now  time.Time
+		at   time.Duration
+		want time.Time
+	}{
+		{day.Add(time.Hour), 0, day.AddDate(0, 0, 1)},
+		{day, 0, day.AddDate(0, 0, 1)},
+		{day.Add(time.Hour), 2 * time.Hour, day.Add(2 * time.Hour)},
+		{day.Add(3 * time.Hour), 2 * time.Hour, day.AddDate(0, 0, 1).Add(2 * time.Hour)},
+	}
+	for _, tt := range tests {
+		if got := nextRotation(tt.now, tt.at); !got.Equal(tt.want) {
+			t.Errorf("nextRotation(%v, %v) = %v, want %v", tt.now, tt.at, got, tt.want)
+		}
+	}
+}
+
+// weekly keeps Sunday's archives for 8 weeks and the others for 2.
+func weekly(t time.Time) time.Duration {
+	if t.Weekday() == time.Sunday {
+		return 8 * 7 * 24 * time.Hour
+	}
+	return 2 * 7 * 24 * time.Hour
+}
+
+func TestPruneExpiredReadsTheName(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	sunday := now.AddDate(0, 0, -21)
+	for sunday.Weekday() != time.Sunday {
+		sunday = sunday.AddDate(0, 0, -1)
+	}
+	monday := sunday.AddDate(0, 0, 1)
+	recent := now.Add(-time.Hour)
+	names := map[string]bool{
+		archiveName(SuffixTimestamp, "app.log", 1, recent):     true,
+		archiveName(SuffixTimestamp, "app.log", 2, monday):     false,
+		archiveName(SuffixTimestamp, "app.log", 3, sunday):     true,
+		archiveName(SuffixAfterExt, "app.log", 4, time.Time{}): true, // no time, left to backups
+		"app.log.20240115T000000.x":                            true, // not an archive
+	}
+	for name := range names {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// the modification time must not matter
+		old := now.AddDate(-1, 0, 0)
+		os.Chtimes(path, old, old)
+	}
+
+	var l Logger
+	if err := l.pruneExpired(dir, "app.log", SuffixTimestamp, false, weekly); err != nil {
+		t.Fatal(err)
+	}
+	for name, kept := range names {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: exists = %v, want %v", name, exists, kept)
+		}
+	}
+}
+
+func TestPruneExpiredDatedDays(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -30)
+	fresh := time.Now()
+	for _, day := range []time.Time{old, fresh} {
+		d := filepath.Join(root, day.Format(datedLayout))
+		os.MkdirAll(d, 0755)
+		if err := ioutil.WriteFile(filepath.Join(d, "app.log.1"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var l Logger
+	keep := func(time.Time) time.Duration { return 7 * 24 * time.Hour }
+	if err := l.pruneExpired(root, "app.log", SuffixAfterExt, true, keep); err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(left)
+	want := fresh.Format(datedLayout) + "/app.log.1"
+	if len(left) != 1 || left[0] != want {
+		t.Errorf("left %v, want [%s]", left, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, old.Format("2006"), old.Format("01"), old.Format("02"))); !os.IsNotExist(err) {
+		t.Errorf("the emptied day was not removed: %v", err)
+	}
+}
+
+func TestScheduleNamesArchivesWithTheirTime(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, INFO, 0, 5, WithSchedule(Schedule{Retention: weekly}))
+	defer l.Close()
+	l.Info("hello")
+	before := time.Now().Truncate(time.Second)
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, err := listArchives(dir, "app.log", SuffixTimestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 1 || archives[0].idx != 1 || archives[0].time.Before(before) {
+		t.Errorf("archives = %+v", archives)
+	}
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type schemaLine struct {
+	Schema *struct {
+		Version int      `json:"version"`
+		Keys    []string `json:"keys"`
+	} `json:"schema"`
+}
+
+// checkSchema checks that content starts with a header listing every key
+// of the entries that follow, and returns the entries.
+func checkSchema(t *testing.T, content string) []map[string]interface{} {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	var h schemaLine
+	if err := json.Unmarshal([]byte(lines[0]), &h); err != nil || h.Schema == nil {
+		t.Fatalf("first line %q isn't a schema header: %v", lines[0], err)
+	}
+	if h.Schema.Version != 2 {
+		t.Errorf("schema version %d, want 2", h.Schema.Version)
+	}
+	keys := map[string]bool{}
+	for _, k := range h.Schema.Keys {
+		keys[k] = true
+	}
+	var entries []map[string]interface{}
+	for _, line := range lines[1:] {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if _, ok := v["schema"]; ok {
+			t.Errorf("second header %q", line)
+		}
+		if v["v"] != 2.0 {
+			t.Errorf("entry %q, want \"v\":2", line)
+		}
+		for k := range v {
+			if !keys[k] && k != "user" && k != "reason" {
+				t.Errorf("key %s of %q isn't in the header %v", k, line, h.Schema.Keys)
+			}
+		}
+		entries = append(entries, v)
+	}
+	return entries
+}
+
+func TestJSONSchemaHeader(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 5)
+	defer l.Close()
+	l.EnableJSON()
+	l.SetPrefix("app")
+	l.SetJSONConfig(JSONConfig{SchemaVersion: 2, EmitSchemaHeader: true, LevelNum: true, Delta: true})
+	l.WithField("user", "alice").Info("first")
+	l.Event("saved").Warn("second")
+
+	if entries := checkSchema(t, readFile(t, name)); len(entries) != 2 {
+		t.Errorf("entries = %v", entries)
+	}
+
+	// every file starts with its header
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("third")
+	entries := checkSchema(t, readFile(t, name))
+	if len(entries) != 2 || !strings.HasPrefix(entries[0]["msg"].(string), "rotated from") || entries[1]["msg"] != "third" {
+		t.Errorf("entries after the rotation = %v", entries)
+	}
+}
+
+func TestJSONSchemaVersionOnly(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	l.SetJSONConfig(JSONConfig{SchemaVersion: 3})
+	l.Info("m")
+	if got := out.String(); !strings.HasPrefix(got, `{"v":3,"time":`) || strings.Count(got, "\n") != 1 {
+		t.Errorf("output = %q, want the version first and no header", got)
+	}
+}
+
+func TestSchemaHeaderNotInText(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.SetJSONConfig(JSONConfig{SchemaVersion: 2, EmitSchemaHeader: true})
+	l.Info("plain")
+	if got := readFile(t, name); got != "plain\n" {
+		t.Errorf("file = %q", got)
+	}
+}
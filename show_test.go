@@ -0,0 +1,60 @@
+package log
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestShowToggles(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	stamp := `\d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? `
+	tests := []struct {
+		name  string
+		setup func(l *Logger)
+		want  string
+	}{
+		{"default", func(l *Logger) {}, `^\[app\] ` + stamp + `INFO:` + pid + `:\S*show_test.go:\d+: m$`},
+		{"no pid", func(l *Logger) { l.ShowPID(false) }, `^\[app\] ` + stamp + `INFO:\S*show_test.go:\d+: m$`},
+		{"no prefix", func(l *Logger) { l.ShowPrefix(false) }, `^` + stamp + `INFO:` + pid + `:\S*show_test.go:\d+: m$`},
+		{"no caller", func(l *Logger) { l.ShowCaller(false) }, `^\[app\] ` + stamp + `INFO:` + pid + `: m$`},
+		{"none", func(l *Logger) {
+			l.ShowPID(false)
+			l.ShowPrefix(false)
+			l.ShowCaller(false)
+		}, `^` + stamp + `INFO: m$`},
+		{"shown again", func(l *Logger) {
+			l.ShowPID(false)
+			l.ShowPID(true)
+		}, `^\[app\] ` + stamp + `INFO:` + pid + `:\S*show_test.go:\d+: m$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			l := New("", INFO, 0, 0)
+			l.SetOutput(&out)
+			l.SetPrefix("app")
+			tt.setup(l)
+			l.Info("m")
+			if got := out.lines()[0]; !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("line %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShowTogglesLeaveCustomFormats(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${prefix}|${pid}|${short_file}|${message}\n")
+	l.SetPrefix("app")
+	l.ShowPID(false)
+	l.ShowPrefix(false)
+	l.ShowCaller(false)
+	l.Info("m")
+
+	if got, want := out.String(), "app|"+strconv.Itoa(os.Getpid())+"|show_test.go|m\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// flusher is the process wide signal handler shared by every Logger using
+// FlushOnSignal, so all of them are flushed before the process exits.
+var flusher = struct {
+	sync.Mutex
+	ch      chan os.Signal
+	loggers map[*Logger][]os.Signal
+}{loggers: make(map[*Logger][]os.Signal)}
+
+// FlushOnSignal flushes and syncs the logger when one of signals (SIGINT
+// and SIGTERM by default) is received. Then the callbacks set with
+// SetSignalFunc are called, or without any the handler is removed and the
+// signal raised again, so the process still dies from it. The returned
+// function uninstalls it.
+func (l *Logger) FlushOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	flusher.Lock()
+	defer flusher.Unlock()
+
+	flusher.loggers[l] = signals
+	if flusher.ch == nil {
+		flusher.ch = make(chan os.Signal, 1)
+		go handleSignals(flusher.ch)
+	}
+	signal.Notify(flusher.ch, signals...)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			flusher.Lock()
+			defer flusher.Unlock()
+
+			delete(flusher.loggers, l)
+			notifyLocked()
+		})
+	}
+}
+
+// SetSignalFunc is called after a FlushOnSignal flush instead of raising
+// the signal again, e.g. to run a graceful shutdown.
+func (l *Logger) SetSignalFunc(fn func(sig os.Signal)) {
+	l.signalFunc = fn
+}
+
+func FlushOnSignal(signals ...os.Signal) (stop func()) {
+	return global.FlushOnSignal(signals...)
+}
+
+func SetSignalFunc(fn func(sig os.Signal)) {
+	global.SetSignalFunc(fn)
+}
+
+// notifyLocked narrows the handled signals to the ones still registered.
+func notifyLocked() {
+	if flusher.ch == nil {
+		return
+	}
+	signal.Stop(flusher.ch)
+	for _, signals := range flusher.loggers {
+		signal.Notify(flusher.ch, signals...)
+	}
+}
+
+func handleSignals(ch chan os.Signal) {
+	for sig := range ch {
+		var funcs []func(os.Signal)
+		flusher.Lock()
+		for l, signals := range flusher.loggers {
+			for _, s := range signals {
+				if s == sig {
+					l.Sync()
+					if l.signalFunc != nil {
+						funcs = append(funcs, l.signalFunc)
+					}
+					break
+				}
+			}
+		}
+		if len(funcs) == 0 {
+			// let the default action run
+			signal.Stop(ch)
+			flusher.ch = nil
+			flusher.loggers = make(map[*Logger][]os.Signal)
+		}
+		flusher.Unlock()
+
+		if len(funcs) == 0 {
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+			return
+		}
+		for _, fn := range funcs {
+			fn(sig)
+		}
+	}
+}
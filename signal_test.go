@@ -0,0 +1,86 @@
+//go:build !windows
+
+package log
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFlushOnSignal(t *testing.T) {
+	newFlushed := func() (*Logger, *streamWriter, chan os.Signal) {
+		w := &streamWriter{}
+		l := New("", INFO, 0, 0)
+		l.SetOutput(w)
+		received := make(chan os.Signal, 1)
+		l.SetSignalFunc(func(sig os.Signal) { received <- sig })
+		return l, w, received
+	}
+	wait := func(received chan os.Signal) {
+		t.Helper()
+		select {
+		case sig := <-received:
+			if sig != syscall.SIGUSR1 {
+				t.Errorf("received %v", sig)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the signal func wasn't called")
+		}
+	}
+
+	l1, w1, received1 := newFlushed()
+	l2, w2, received2 := newFlushed()
+	stop1 := l1.FlushOnSignal(syscall.SIGUSR1)
+	stop2 := l2.FlushOnSignal(syscall.SIGUSR1)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	// every registered Logger is flushed
+	wait(received1)
+	wait(received2)
+	if w1.flushes != 1 || w2.flushes != 1 {
+		t.Errorf("flushes = %d, %d, want both flushed", w1.flushes, w2.flushes)
+	}
+
+	stop2()
+	stop2()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	wait(received1)
+	select {
+	case <-received2:
+		t.Error("an uninstalled handler was called")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if w1.flushes != 2 || w2.flushes != 1 {
+		t.Errorf("flushes = %d, %d after uninstalling the second", w1.flushes, w2.flushes)
+	}
+	stop1()
+}
+
+func TestFlushOnSignalRaisesItAgain(t *testing.T) {
+	if name := os.Getenv("LOG_TEST_SIGNAL_FILE"); name != "" {
+		l := New(name, INFO, 0, 0)
+		l.FlushOnSignal()
+		l.Info("before the signal")
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		time.Sleep(5 * time.Second)
+		os.Exit(0)
+	}
+	name := filepath.Join(t.TempDir(), "app.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFlushOnSignalRaisesItAgain$")
+	cmd.Env = append(os.Environ(), "LOG_TEST_SIGNAL_FILE="+name)
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		t.Fatalf("process ended with %v, want killed by the signal", err)
+	}
+	if status, ok := exit.Sys().(syscall.WaitStatus); !ok || status.Signal() != syscall.SIGTERM {
+		t.Errorf("process ended with %v, want killed by SIGTERM", err)
+	}
+	if got := readFile(t, name); len(got) == 0 {
+		t.Error("the entry was lost")
+	}
+}
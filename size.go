@@ -0,0 +1,101 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a file size in bytes, e.g. 100*log.MB.
+type Size int64
+
+const (
+	KB Size = 1 << (10 * (iota + 1))
+	MB
+	GB
+)
+
+var sizeUnits = []struct {
+	suffix string
+	size   Size
+}{{"GB", GB}, {"MB", MB}, {"KB", KB}, {"G", GB}, {"M", MB}, {"K", KB}, {"B", 1}}
+
+// ParseSize reads sizes like "100MB", "1.5 GB" or "512k", in bytes when
+// there's no unit. Units are powers of 1024.
+func ParseSize(s string) (Size, error) {
+	num := strings.TrimSpace(s)
+	unit := Size(1)
+	upper := strings.ToUpper(num)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			num, unit = strings.TrimSpace(num[:len(num)-len(u.suffix)]), u.size
+			break
+		}
+	}
+	// integers are exact, whatever their size
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		if n < 0 || Size(n) > math.MaxInt64/unit {
+			return 0, fmt.Errorf("log: invalid size %q", s)
+		}
+		return Size(n) * unit, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	// NaN fails every comparison, the last one also rejects Inf and
+	// anything overflowing once in bytes
+	if err != nil || math.IsNaN(f) || f < 0 || f >= float64(math.MaxInt64/unit)+1 {
+		return 0, fmt.Errorf("log: invalid size %q", s)
+	}
+	return Size(f * float64(unit)), nil
+}
+
+// String renders s with the largest unit dividing it, e.g. "100MB".
+func (s Size) String() string {
+	for _, u := range sizeUnits[:3] {
+		if s != 0 && s%u.size == 0 {
+			return strconv.FormatInt(int64(s/u.size), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// MarshalText renders s as String does.
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads text with ParseSize.
+func (s *Size) UnmarshalText(text []byte) error {
+	parsed, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts a string, as UnmarshalText, or a number of bytes.
+func (s *Size) UnmarshalJSON(b []byte) error {
+	var n int64
+	if err := json.Unmarshal(b, &n); err == nil {
+		if n < 0 {
+			return fmt.Errorf("log: invalid size %d", n)
+		}
+		*s = Size(n)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return fmt.Errorf("log: invalid size %s", b)
+	}
+	return s.UnmarshalText([]byte(str))
+}
+
+// WithMaxSize rotates the file before it would exceed size, 0 for no
+// limit. It replaces the maxsize in megabytes given to New.
+func WithMaxSize(size Size) Option {
+	return func(l *Logger) {
+		l.maxsize = int(size)
+	}
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Size
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"512k", 512 * KB},
+		{"1.5 GB", 3 * GB / 2},
+		{" 100MB ", 100 * MB},
+		{"1e3", 1000},
+		{"8589934591GB", 8589934591 * GB},
+		{"9223372036854775807", math.MaxInt64},
+		{"9.2e18", 9200000000000000000},
+	}
+	for _, tt := range tests {
+		if got, err := ParseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSizeRejects(t *testing.T) {
+	for _, in := range []string{
+		"", "MB", "abc", "-1", "-1KB",
+		"NaN", "nan MB", "Inf", "+Inf", "-Inf", "infinity GB",
+		"9223372036854775808", "9223372036854775807K", "1e19",
+		"8589934592GB", "8796093022208MB", "9007199254740992K",
+		"8.6e9GB", "1e400",
+	} {
+		if got, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestSizeText(t *testing.T) {
+	for _, s := range []Size{0, 1, 1023, KB, 3 * MB, 5 * GB, 1536, math.MaxInt64} {
+		text, _ := s.MarshalText()
+		var back Size
+		if err := back.UnmarshalText(text); err != nil || back != s {
+			t.Errorf("%d round-trips as %q to %d, %v", int64(s), text, int64(back), err)
+		}
+	}
+	var s Size
+	if err := json.Unmarshal([]byte(`"NaN"`), &s); err == nil {
+		t.Errorf("unmarshaled NaN as %d", s)
+	}
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+)
+
+// stack policies, see SetStackPolicy
+const (
+	StackRaw    = iota // appended to the message as is
+	StackIndent        // every line prefixed with the stack marker
+	StackEntry         // written as a second entry right after the FATAL
+)
+
+// SetStackPolicy sets how text formats render the stack of FATAL entries.
+// JSON always writes it in the "stack" key.
+func (l *Logger) SetStackPolicy(policy int) {
+	l.stackPolicy = policy
+}
+
+// SetStackMarker sets the prefix of stack lines with StackIndent, a tab by
+// default.
+func (l *Logger) SetStackMarker(marker string) {
+	l.stackMarker = marker
+}
+
+func SetStackPolicy(policy int) {
+	global.SetStackPolicy(policy)
+}
+
+func SetStackMarker(marker string) {
+	global.SetStackMarker(marker)
+}
+
+// messageText is the message as rendered by text formats: sanitized when
+// enabled, followed by the stack according to the stack policy.
+func (l *Logger) messageText(e *Entry) string {
+	if e.stackOnly {
+		return strings.TrimSuffix(e.Stack, "\n")
+	}
+	msg := e.Message
+	if l.sanitize {
+		msg = escapeControl(msg)
+	}
+	if e.Stack == "" {
+		return msg
+	}
+	switch l.stackPolicy {
+	case StackIndent:
+		marker := l.stackMarker
+		if marker == "" {
+			marker = "\t"
+		}
+		stack := strings.TrimSuffix(e.Stack, "\n")
+		return msg + "\n" + marker + strings.ReplaceAll(stack, "\n", "\n"+marker)
+	case StackEntry:
+		return msg
+	}
+	return msg + "\n" + e.Stack
+}
+
+// stackEntry is the entry carrying the stack of e with StackEntry.
+func (l *Logger) stackEntry(e *Entry) (Entry, bool) {
+	if l.stackPolicy != StackEntry || e.Stack == "" {
+		return Entry{}, false
+	}
+	s := *e
+	s.Fields, s.fields, s.stackOnly = nil, nil, true
+	return s, true
+}
+
+// WithCrashFile writes the stacks of all goroutines of a FATAL to a file
+// named after path and the time, e.g. crash.20240115-103000.log for
+// crash.log, instead of the first 4 KiB in the entry. The entry gets its
+// name in the "crash_file" field. The file is synced before the exit.
+func WithCrashFile(path string) Option {
+	return func(l *Logger) {
+		l.crashFile = path
+	}
+}
+
+// dumpCrash writes the crash file of the FATAL e, reporting whether it
+// was written.
+func (l *Logger) dumpCrash(e *Entry) bool {
+	if l.crashFile == "" {
+		return false
+	}
+	ext := filepath.Ext(l.crashFile)
+	name := strings.TrimSuffix(l.crashFile, ext) + "." + e.Time.Format("20060102-150405") + ext
+	if err := writeCrash(name, e); err != nil {
+		l.handleError(err)
+		return false
+	}
+	l.diag("wrote the goroutines of a FATAL to %s", name)
+	e.materialize()
+	fields := make(Fields, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	fields["crash_file"] = name
+	e.Fields = fields
+	return true
+}
+
+func writeCrash(name string, e *Entry) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "%s FATAL %s:%d: %s\n\n", e.Time.Format(timeLocal), midFile(e.File), e.Line, e.Message)
+	// streamed, whatever the number of goroutines
+	err = pprof.Lookup("goroutine").WriteTo(f, 2)
+	if serr := f.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func fatalWithPolicy(t *testing.T, setup func(l *Logger)) string {
+	t.Helper()
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetExitFunc(func(int) {})
+	setup(l)
+	l.Fatal("boom")
+	return out.String()
+}
+
+func TestStackPolicyText(t *testing.T) {
+	raw := fatalWithPolicy(t, func(l *Logger) {})
+	if !strings.HasPrefix(raw, "FATAL boom\ngoroutine ") {
+		t.Errorf("raw = %q, want the stack right after the message", raw)
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(l *Logger)
+		marker string
+	}{
+		{"default marker", func(l *Logger) { l.SetStackPolicy(StackIndent) }, "\t"},
+		{"custom marker", func(l *Logger) {
+			l.SetStackPolicy(StackIndent)
+			l.SetStackMarker("  | ")
+		}, "  | "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := fatalWithPolicy(t, tt.setup)
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			if len(lines) < 3 || lines[0] != "FATAL boom" || !strings.HasPrefix(lines[1], tt.marker+"goroutine ") {
+				t.Fatalf("output = %q", out)
+			}
+			// a multiline parser keys the continuation on the marker
+			for _, line := range lines[1:] {
+				if !strings.HasPrefix(line, tt.marker) {
+					t.Errorf("line %q misses the marker %q", line, tt.marker)
+				}
+			}
+		})
+	}
+
+	t.Run("entry", func(t *testing.T) {
+		out := fatalWithPolicy(t, func(l *Logger) {
+			l.SetFormat("${level}|${message}${fields}\n")
+			l.SetStackPolicy(StackEntry)
+		})
+		entries := strings.SplitN(out, "\nFATAL|", 2)
+		if len(entries) != 2 || entries[0] != "FATAL|boom" || !strings.HasPrefix(entries[1], "goroutine ") {
+			t.Fatalf("output = %q, want the stack as a second decorated entry", out)
+		}
+	})
+}
+
+func TestStackPolicyJSON(t *testing.T) {
+	for _, policy := range []int{StackRaw, StackIndent, StackEntry} {
+		out := fatalWithPolicy(t, func(l *Logger) {
+			l.EnableJSON()
+			l.SetStackPolicy(policy)
+			l.SetStackMarker("> ")
+		})
+		if strings.Count(out, "\n") != 1 {
+			t.Errorf("policy %d: output = %q, want a single entry", policy, out)
+			continue
+		}
+		var v struct {
+			Msg   string `json:"msg"`
+			Stack string `json:"stack"`
+		}
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			t.Fatalf("policy %d: %v", policy, err)
+		}
+		if v.Msg != "boom" || !strings.HasPrefix(v.Stack, "goroutine ") || strings.Contains(v.Stack, "\n> ") {
+			t.Errorf("policy %d: msg = %q, stack = %q, want the raw stack in its key", policy, v.Msg, v.Stack)
+		}
+	}
+}
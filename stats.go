@@ -0,0 +1,105 @@
+package log
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of the logger's output state.
+type Stats struct {
+	Filename string // active file, empty when logging to stdout
+	Size     int    // bytes written to the active file
+	Lines    int    // lines in the active file, only counted with SetMaxLines
+	Opened   bool   // false until the first write with WithLazyOpen
+	Nested   uint64 // entries logged from within hooks and dropped
+	Dropped  uint64 // entries lost to write errors, see WithRetry
+	Queued   int    // rotations waiting to be archived
+
+	Suppressed Suppressed // entries dropped before being written
+
+	OutputBytes []uint64     // written to each AddOutputLevel output, in order
+	Diagnostics []Diagnostic // last internal events, see DumpDiagnostics
+}
+
+func (l *Logger) Stats() Stats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	st := Stats{
+		Filename: l.filename,
+		Opened:   l.file != nil,
+		Nested:   atomic.LoadUint64(&l.nested),
+		Dropped:  atomic.LoadUint64(&l.dropped),
+
+		Suppressed:  l.suppressedStats(),
+		OutputBytes: l.outputBytes(),
+		Diagnostics: l.diagnostics.list(),
+	}
+	if f := l.file; f != nil {
+		f.mutex.Lock()
+		st.Filename, st.Size, st.Lines = f.name, f.size, f.lines
+		f.mutex.Unlock()
+		st.Queued = f.maint.pending()
+	}
+	return st
+}
+
+// SetMaxLines rotates the file once it would exceed n lines, alongside the
+// size limit: whichever is hit first rotates. 0 disables the line limit.
+func (l *Logger) SetMaxLines(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if f := l.file; n > 0 && l.maxLines <= 0 && f != nil {
+		f.mutex.Lock()
+		f.lines = countLines(f.name, l.eol())
+		f.mutex.Unlock()
+	}
+	l.maxLines = n
+}
+
+func SetMaxLines(n int) {
+	global.SetMaxLines(n)
+}
+
+// Reopen closes and reopens the active file, e.g. after it was moved by an
+// external logrotate, starting it with a marker entry like a rotation. A
+// lazily opened file that wasn't written yet stays closed.
+func (l *Logger) Reopen() error {
+	defer l.notifyColor()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.filename == "" {
+		return errors.New("log: no file to reopen")
+	}
+	if l.lazyOpen && l.file == nil {
+		return nil
+	}
+	size := 0
+	if f := l.file; f != nil {
+		f.mutex.Lock()
+		size = f.size
+		// the file was moved away and is complete
+		if moved(f) {
+			l.writeFooterLocked(f)
+		}
+		f.mutex.Unlock()
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	f := l.file
+	f.mutex.Lock()
+	l.writeMarkerLocked(f, "reopen", f.name, size, nil)
+	f.mutex.Unlock()
+	return nil
+}
+
+func GetStats() Stats {
+	return global.Stats()
+}
+
+func Reopen() error {
+	return global.Reopen()
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	stdlog "log"
+	"strings"
+)
+
+// stdWriter logs every line written by a standard library logger as an
+// entry, see StdLogger.
+type stdWriter struct {
+	l     *Logger
+	level Level
+}
+
+func (w stdWriter) Write(b []byte) (int, error) {
+	// frames: emit, Write, log.Logger.output, log.Logger.Println, caller
+	err := w.l.emit(Entry{Level: w.level}, 4, "", []interface{}{strings.TrimSuffix(string(b), "\n")})
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// StdLogger returns a standard library logger writing through l at level
+// v, for code that wants a *log.Logger. The time and caller come from l's
+// format, so the standard logger has no flags nor prefix.
+func (l *Logger) StdLogger(v Level) *stdlog.Logger {
+	return stdlog.New(stdWriter{l, v}, "", 0)
+}
+
+// Std returns a standard library logger writing to filename with rotation
+// at maxsize megabytes, at INFO without colors, e.g.
+//
+//	logger := log.Std("/var/log/tool.log", 100, 7)
+//	logger.Println("started")
+func Std(filename string, maxsize, backups int) *stdlog.Logger {
+	l := New(filename, INFO, maxsize, backups)
+	l.DisableColor()
+	return l.StdLogger(INFO)
+}
+
+// StdErr is Std for stderr.
+func StdErr() *stdlog.Logger {
+	l := NewStderr(INFO)
+	l.DisableColor()
+	return l.StdLogger(INFO)
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStd(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "tool.log")
+	logger := Std(name, 100, 7)
+	logger.Println("started")
+	logger.Printf("%d files", 3)
+
+	got := readFile(t, name)
+	for _, want := range []string{"INFO", "/std_test.go:", ": started\n", ": 3 files\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("file = %q, want %q", got, want)
+		}
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("file = %q, want no colors", got)
+	}
+}
+
+func TestStdErr(t *testing.T) {
+	stderr := redirect(t, &os.Stderr)
+	StdErr().Println("to stderr")
+	if got := stderr(); !strings.Contains(got, "INFO") || !strings.Contains(got, ": to stderr\n") {
+		t.Errorf("stderr = %q", got)
+	}
+}
+
+// TestStdLoggerRotation is TestConcurrentIntegrity through a standard
+// library logger.
+func TestStdLoggerRotation(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 1000, WithMaxSize(2*KB))
+	l.SetFormat("${message}\n")
+	logger := l.StdLogger(INFO)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logger.Println(fmt.Sprintf("#g%d-%d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(name + "*")
+	if len(files) < 3 {
+		t.Fatalf("files = %v, the test needs rotations", files)
+	}
+	seen := make(map[string]int)
+	for _, file := range files {
+		for _, line := range strings.Split(readFile(t, file), "\n") {
+			if strings.HasPrefix(line, "#") {
+				seen[line]++
+			}
+		}
+	}
+	if len(seen) != 8*100 {
+		t.Errorf("%d distinct lines, want %d", len(seen), 8*100)
+	}
+	for line, n := range seen {
+		if n != 1 {
+			t.Errorf("%q written %d times", line, n)
+		}
+	}
+}
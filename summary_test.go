@@ -0,0 +1,66 @@
+package log
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCounts(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(INFO)
+	l.Debug("dropped")
+	for i := 0; i < 3; i++ {
+		l.Info("i")
+	}
+	l.Warn("w")
+	l.Error("e")
+	l.Error("e")
+
+	want := map[string]uint64{"debug": 0, "info": 3, "warn": 1, "error": 2, "fatal": 0}
+	if got := l.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Counts = %v, want %v", got, want)
+	}
+}
+
+func TestCloseSummary(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, DEBUG, 0, 0, WithCloseSummary(true))
+	l.SetFormat("${level} ${message}\n")
+	l.Info("i")
+	l.Info("i")
+	l.Warn("w")
+	l.Error("e")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after close")
+
+	lines := strings.Split(strings.TrimSuffix(readFile(t, name), "\n"), "\n")
+	want := "INFO logged 4 entries: 1 error, 1 warn, 2 info"
+	if len(lines) != 5 || lines[4] != want {
+		t.Errorf("lines = %q, want the summary last: %q", lines, want)
+	}
+}
+
+func TestCloseSummaryOfNothing(t *testing.T) {
+	var out syncBuffer
+	l := New("", INFO, 0, 0, WithCloseSummary(true))
+	l.SetOutput(&out)
+	l.SetFormat("${message}\n")
+	l.Close()
+	if got := out.String(); got != "logged 0 entries\n" {
+		t.Errorf("output = %q", got)
+	}
+
+	// disabled by default
+	out = syncBuffer{}
+	l = newTestLogger(&out)
+	l.Info("i")
+	l.Close()
+	if got := out.String(); got != "INFO i\n" {
+		t.Errorf("output = %q, want no summary", got)
+	}
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// suppression causes, see Suppressed
+const (
+	suppressedLevel = iota
+	suppressedFilter
+	suppressedCaller
+	suppressedEmpty
+	suppressCauses
+)
+
+var suppressNames = [suppressCauses]string{"level", "filter", "caller", "empty"}
+
+// suppressionTraceTime bounds TraceSuppression, which is meant for a live
+// investigation and must not be left on by mistake.
+const suppressionTraceTime = time.Minute
+
+// Suppressed counts the entries dropped before being written, by cause.
+// Entries logged from hooks are counted in Stats.Nested.
+type Suppressed struct {
+	Level  uint64 // below the level, and not captured by the ring
+	Filter uint64 // dropped by AddFilter or AddMessageFilter filters
+	Caller uint64 // dropped by the caller prefix rules
+	Empty  uint64 // empty messages with EmptyDrop
+}
+
+func (l *Logger) suppressedStats() Suppressed {
+	return Suppressed{
+		Level:  atomic.LoadUint64(&l.suppressed[suppressedLevel]),
+		Filter: atomic.LoadUint64(&l.suppressed[suppressedFilter]),
+		Caller: atomic.LoadUint64(&l.suppressed[suppressedCaller]),
+		Empty:  atomic.LoadUint64(&l.suppressed[suppressedEmpty]),
+	}
+}
+
+// TraceSuppression records, for a minute at most, a diagnostic for every
+// dropped entry with its cause, caller and fingerprint, to find out where
+// a line went. See DumpDiagnostics.
+func (l *Logger) TraceSuppression(enabled bool) {
+	var until int64
+	if enabled {
+		until = time.Now().Add(suppressionTraceTime).UnixNano()
+	}
+	atomic.StoreInt64(&l.traceUntil, until)
+}
+
+func TraceSuppression(enabled bool) {
+	global.TraceSuppression(enabled)
+}
+
+// suppress counts e as dropped for cause, calldepth being the one given to
+// emit.
+func (l *Logger) suppress(cause int, e *Entry, calldepth int) {
+	atomic.AddUint64(&l.suppressed[cause], 1)
+	until := atomic.LoadInt64(&l.traceUntil)
+	if until == 0 || time.Now().UnixNano() > until {
+		return
+	}
+	if e.File == "" {
+		_, e.File, e.Line, _ = runtime.Caller(calldepth + 1)
+	}
+	l.diag("suppressed by %s: %s %s:%d fingerprint %s", suppressNames[cause], levelName(e.Level), midFile(e.File), e.Line, e.Fingerprint())
+}
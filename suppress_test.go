@@ -0,0 +1,98 @@
+package log
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSuppressedByCause(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(INFO)
+	l.SetEmptyMessagePolicy(EmptyDrop)
+	l.AddFilter(func(e *Entry) bool { return e.Format() != "early" })
+	l.AddMessageFilter(func(e *Entry) bool { return e.Message != "late" })
+
+	l.Debug("below")
+	l.Debug("below")
+	l.Infof("early")
+	l.Info("late")
+	l.Info("")
+	l.AllowCallerPrefix("/elsewhere/")
+	l.Info("denied")
+
+	want := Suppressed{Level: 2, Filter: 2, Caller: 1, Empty: 1}
+	if got := l.Stats().Suppressed; got != want {
+		t.Errorf("suppressed %+v, want %+v", got, want)
+	}
+	if out.String() != "" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func suppressionTraces(l *Logger) []string {
+	var traces []string
+	for _, d := range l.Stats().Diagnostics {
+		if strings.HasPrefix(d.Message, "suppressed by ") {
+			traces = append(traces, d.Message)
+		}
+	}
+	return traces
+}
+
+func TestTraceSuppression(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetLevel(INFO)
+	l.AddMessageFilter(func(e *Entry) bool { return e.Message != "late" })
+	l.Debug("untraced")
+	if got := suppressionTraces(l); len(got) != 0 {
+		t.Fatalf("traces = %q before TraceSuppression", got)
+	}
+
+	l.TraceSuppression(true)
+	for i := 0; i < 2; i++ {
+		l.Debug("below")
+	}
+	l.Info("late")
+	got := suppressionTraces(l)
+	if len(got) != 3 {
+		t.Fatalf("traces = %q, want 3", got)
+	}
+	for i, cause := range []string{"level", "level", "filter"} {
+		if !strings.HasPrefix(got[i], "suppressed by "+cause+": ") || !strings.Contains(got[i], "suppress_test.go:") || !strings.Contains(got[i], " fingerprint ") {
+			t.Errorf("trace %q, want the %s cause, the caller and the fingerprint", got[i], cause)
+		}
+	}
+
+	// the same call site, the same fingerprint
+	if fp := got[1][strings.Index(got[1], " fingerprint "):]; !strings.HasSuffix(got[0], fp) {
+		t.Errorf("fingerprints %q and %q differ", got[0], got[1])
+	}
+
+	l.TraceSuppression(false)
+	l.Debug("untraced")
+	if got := suppressionTraces(l); len(got) != 3 {
+		t.Errorf("%d traces once disabled, want 3", len(got))
+	}
+}
+
+func TestTraceSuppressionExpires(t *testing.T) {
+	l := newTestLogger(&syncBuffer{})
+	l.SetLevel(INFO)
+	l.TraceSuppression(true)
+	if until := time.Unix(0, atomic.LoadInt64(&l.traceUntil)); until.After(time.Now().Add(suppressionTraceTime)) {
+		t.Errorf("traced until %v, beyond the bound", until)
+	}
+
+	atomic.StoreInt64(&l.traceUntil, time.Now().Add(-time.Second).UnixNano())
+	l.Debug("expired")
+	if got := suppressionTraces(l); len(got) != 0 {
+		t.Errorf("traces = %q once expired", got)
+	}
+	if l.Stats().Suppressed.Level != 1 {
+		t.Error("the entry isn't counted once the trace expired")
+	}
+}
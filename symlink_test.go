@@ -0,0 +1,49 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCurrentSymlink(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	l := New(name, INFO, 0, 0, WithCurrentSymlink(true))
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.Info("first")
+
+	link := name + ".current"
+	if target, err := os.Readlink(link); err != nil || target != "app.log" {
+		t.Fatalf("Readlink = %q, %v, want the relative active file", target, err)
+	}
+
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("second")
+	if target, err := os.Readlink(link); err != nil || target != "app.log" {
+		t.Fatalf("Readlink after rotation = %q, %v", target, err)
+	}
+	if got := readFile(t, link); !strings.HasSuffix(got, "second\n") || strings.Contains(got, "first") {
+		t.Errorf("the link reads %q, want the new file", got)
+	}
+
+	// the link is swapped in with a rename, no temporary file is left
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if len(matches) != 0 {
+		t.Errorf("left %q", matches)
+	}
+}
+
+func TestCurrentSymlinkDisabled(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+	l.Info("first")
+	if _, err := os.Lstat(name + ".current"); !os.IsNotExist(err) {
+		t.Errorf("Lstat = %v, want no link", err)
+	}
+}
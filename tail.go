@@ -0,0 +1,179 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNoFile is returned by TailLines, TailSince and Rotate when the logger
+// doesn't write to a file.
+var ErrNoFile = errors.New("log: not logging to a file")
+
+const tailChunk = 4096
+
+// TailLines returns the last n lines of the active file, oldest first. The
+// file is read backwards from its end, so only about n lines are held in
+// memory, and not while it's being rotated.
+func (l *Logger) TailLines(n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	lines := make([]string, 0, n)
+	err := l.tail(func(line string) bool {
+		lines = append(lines, line)
+		return len(lines) < n
+	})
+	reverse(lines)
+	return lines, err
+}
+
+// TailSince returns the lines of the active file logged at or after t,
+// according to their leading timestamp (${time_local}, ${time_rfc3339}
+// or JSON). Lines without a timestamp, like stacks, go with the entry
+// they follow. Older entries of rotated files aren't included.
+func (l *Logger) TailSince(t time.Time) ([]string, error) {
+	var lines []string
+	err := l.tail(func(line string) bool {
+		if ts, ok := l.lineTime(line); ok && ts.Before(t) {
+			return false
+		}
+		lines = append(lines, line)
+		return true
+	})
+	reverse(lines)
+
+	// drop continuation lines of the entry before t
+	for len(lines) > 0 {
+		if _, ok := l.lineTime(lines[0]); ok {
+			break
+		}
+		lines = lines[1:]
+	}
+	return lines, err
+}
+
+func TailLines(n int) ([]string, error) {
+	return global.TailLines(n)
+}
+
+func TailSince(t time.Time) ([]string, error) {
+	return global.TailSince(t)
+}
+
+// tail calls fn with the lines of the active file from the last one,
+// until fn returns false.
+func (l *Logger) tail(fn func(line string) bool) error {
+	l.mutex.Lock()
+	s, name, eol := l.file, l.filename, l.eol()
+	l.mutex.Unlock()
+
+	if s == nil {
+		if name == "" {
+			return ErrNoFile
+		}
+		// lazily opened and not written to yet
+		return tailFile(name, -1, eol, fn)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return tailFile(s.name, int64(s.size), eol, fn)
+}
+
+// tailFile reads the first size bytes of name (all of it for -1) backwards.
+func tailFile(name string, size int64, eol string, fn func(line string) bool) error {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	if size < 0 {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		size = fi.Size()
+	}
+
+	sep := []byte(eol)
+	var rest []byte // start of the line cut by the chunk boundary
+	first := true
+	buf := make([]byte, tailChunk)
+	for off := size; off > 0; {
+		c := int64(tailChunk)
+		if off < c {
+			c = off
+		}
+		off -= c
+		if _, err := f.ReadAt(buf[:c], off); err != nil && err != io.EOF {
+			return err
+		}
+		rest = append(append([]byte(nil), buf[:c]...), rest...)
+
+		for {
+			i := bytes.LastIndex(rest, sep)
+			if i < 0 {
+				break
+			}
+			line := rest[i+len(sep):]
+			rest = rest[:i]
+			if first {
+				first = false
+				// the file ends with a line ending, not an empty line
+				if len(line) == 0 {
+					continue
+				}
+			}
+			if !fn(string(line)) {
+				return nil
+			}
+		}
+	}
+	if len(rest) > 0 || !first {
+		fn(string(rest))
+	}
+	return nil
+}
+
+// lineTime parses the timestamp a line starts with, after the prefix.
+func (l *Logger) lineTime(line string) (time.Time, bool) {
+	prefix := l.Prefix()
+	if d := decoratePrefix(prefix); d != "" && strings.HasPrefix(line, d) {
+		line = line[len(d):]
+	} else {
+		line = strings.TrimPrefix(line, prefix)
+	}
+	if strings.HasPrefix(line, "{") {
+		var v struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(line), &v); err == nil && !v.Time.IsZero() {
+			return v.Time, true
+		}
+		return time.Time{}, false
+	}
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) >= 2 {
+		if t, err := time.ParseInLocation(timeLocal, fields[0]+" "+fields[1], time.Local); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, fields[0]); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func reverse(lines []string) {
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+}
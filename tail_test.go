@@ -0,0 +1,91 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailLines(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	var all []string
+	// lines of varied lengths, cut across the read chunks
+	for i := 0; i < 300; i++ {
+		line := strconv.Itoa(i) + " " + strings.Repeat("x", i*7%150)
+		all = append(all, line)
+		l.Info(line)
+	}
+
+	for _, n := range []int{1, 5, 100, 300, 1000} {
+		got, err := l.TailLines(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := all
+		if n < len(all) {
+			want = all[len(all)-n:]
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("TailLines(%d) = %d lines from %q, want %d", n, len(got), got[0], len(want))
+		}
+	}
+	if got, err := l.TailLines(0); got != nil || err != nil {
+		t.Errorf("TailLines(0) = %q, %v", got, err)
+	}
+}
+
+func TestTailLinesWithoutAFile(t *testing.T) {
+	l := New("", INFO, 0, 0)
+	if _, err := l.TailLines(3); err != ErrNoFile {
+		t.Errorf("TailLines on stdout = %v, want ErrNoFile", err)
+	}
+	if _, err := l.TailSince(time.Time{}); err != ErrNoFile {
+		t.Errorf("TailSince on stdout = %v, want ErrNoFile", err)
+	}
+
+	// not written to yet
+	l = New(filepath.Join(t.TempDir(), "app.log"), INFO, 0, 0, WithLazyOpen(true))
+	if got, err := l.TailLines(3); len(got) != 0 || err != nil {
+		t.Errorf("TailLines before the first write = %q, %v", got, err)
+	}
+}
+
+func TestTailSince(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	content := "2024-01-02 03:04:05.000 INFO old\n" +
+		"2024-01-02 03:04:06.000 ERROR boom\n" +
+		"goroutine 1 [running]:\n" +
+		"2024-01-02 03:04:07.500 INFO recent\n" +
+		"2024-01-02 03:04:08.000 WARN last\n"
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := New(name, INFO, 0, 0)
+	defer l.Close()
+
+	tests := []struct {
+		since string
+		want  []string
+	}{
+		{"2024-01-02 03:04:07.000", []string{"2024-01-02 03:04:07.500 INFO recent", "2024-01-02 03:04:08.000 WARN last"}},
+		// the stack goes with its entry
+		{"2024-01-02 03:04:06.000", []string{"2024-01-02 03:04:06.000 ERROR boom", "goroutine 1 [running]:", "2024-01-02 03:04:07.500 INFO recent", "2024-01-02 03:04:08.000 WARN last"}},
+		{"2024-01-02 03:04:09.000", nil},
+	}
+	for _, tt := range tests {
+		since, err := time.ParseInLocation(timeLocal, tt.since, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := l.TailSince(since)
+		if err != nil || strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("TailSince(%s) = %q, %v, want %q", tt.since, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/labstack/gommon/color"
+)
+
+// Theme holds the colors of each part of a text entry as SGR codes, e.g.
+// color.Cyn or "1;31" for bold red, an empty code leaves the part as is.
+// Times, callers and messages are only colored when the color scope is
+// ColorLevelOnly, ColorFullLine colors the whole line by level.
+type Theme struct {
+	Debug   string
+	Info    string
+	Warn    string
+	Error   string
+	Fatal   string
+	Time    string
+	Caller  string
+	Message string
+}
+
+var (
+	// DarkTheme suits terminals with a dark background.
+	DarkTheme = Theme{
+		Debug:  color.Cyn,
+		Info:   color.Grn,
+		Warn:   color.Yel,
+		Error:  color.Rd,
+		Fatal:  color.RdBg,
+		Time:   color.Gry,
+		Caller: color.Gry,
+	}
+
+	// LightTheme suits terminals with a light background.
+	LightTheme = Theme{
+		Debug:  color.Blu,
+		Info:   color.Grn,
+		Warn:   color.Mgn,
+		Error:  color.Rd,
+		Fatal:  color.RdBg,
+		Time:   color.D,
+		Caller: color.D,
+	}
+
+	// defaultTheme only colors the level.
+	defaultTheme = Theme{
+		Debug: color.Blu,
+		Info:  color.Grn,
+		Warn:  color.Yel,
+		Error: color.Rd,
+		Fatal: color.RdBg,
+	}
+)
+
+// SetTheme colors the entries with t once color is enabled. The theme is
+// compiled into the format, so an entry is never rendered with a mix of
+// the old and the new theme.
+func (l *Logger) SetTheme(t Theme) {
+	l.reformat(func() {
+		l.theme = &t
+	})
+}
+
+func SetTheme(t Theme) {
+	global.SetTheme(t)
+}
+
+func (l *Logger) currentTheme() *Theme {
+	if l.theme == nil {
+		return &defaultTheme
+	}
+	return l.theme
+}
+
+func (t *Theme) level(v Level) string {
+	switch v {
+	case DEBUG:
+		return t.Debug
+	case INFO:
+		return t.Info
+	case WARN:
+		return t.Warn
+	case ERROR:
+		return t.Error
+	default:
+		return t.Fatal
+	}
+}
+
+// tag returns the code of the part rendered by tag.
+func (t *Theme) tag(tag string) string {
+	if strings.HasPrefix(tag, "time:") || strings.HasPrefix(tag, "time_utc:") {
+		return t.Time
+	}
+	switch tag {
+	case "time_local", "time_rfc3339", "time_apache":
+		return t.Time
+	case "long_file", "short_file", "mid_file", "rel_file", "line", "func":
+		return t.Caller
+	case "message":
+		return t.Message
+	}
+	return ""
+}
+
+func colorize(code, s string) string {
+	if code == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
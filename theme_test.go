@@ -0,0 +1,82 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetTheme(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${time_rfc3339} ${level} ${short_file}:${line} ${message}\n")
+	l.SetTheme(Theme{Info: "32", Time: "2", Caller: "90", Message: "1"})
+	l.Info("m")
+	l.SetColorOverride(true)
+	l.Info("m")
+	l.Warn("m")
+
+	lines := out.lines()
+	if len(lines) != 3 || strings.Contains(lines[0], "\x1b[") {
+		t.Fatalf("lines = %q, want no color before it's enabled", lines)
+	}
+	for _, want := range []string{colorize("32", "INFO"), colorize("1", "m"), "\x1b[90mtheme_test.go\x1b[0m:\x1b[90m"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("line %q misses %q", lines[1], want)
+		}
+	}
+	if !strings.HasPrefix(lines[1], "\x1b[2m") {
+		t.Errorf("line %q, want the time colored", lines[1])
+	}
+	// no code for WARN, the level is left as is
+	if !strings.Contains(lines[2], "m WARN \x1b[90m") {
+		t.Errorf("line %q, want the level uncolored", lines[2])
+	}
+}
+
+func TestThemeFullLine(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${time_rfc3339} ${level} ${message}\n")
+	l.SetColorOverride(true)
+	l.SetColorScope(ColorFullLine)
+	l.SetTheme(DarkTheme)
+	l.Error("m")
+
+	got := out.String()
+	// the line is colored by level only, the parts are left alone
+	if !strings.HasPrefix(got, "\x1b["+DarkTheme.Error+"m") || strings.Count(got, "\x1b[0m") != 1 || strings.Contains(got, "\x1b["+DarkTheme.Time+"m") {
+		t.Errorf("output = %q", got)
+	}
+}
+
+// TestSetThemeIsAtomic renders entries while the theme is swapped: every
+// line is rendered with a single theme.
+func TestSetThemeIsAtomic(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${time_rfc3339} ${level} ${message}\n")
+	l.SetColorOverride(true)
+	a := Theme{Info: "31", Time: "31", Message: "31"}
+	b := Theme{Info: "32", Time: "32", Message: "32"}
+	l.SetTheme(a)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			l.SetTheme([]Theme{a, b}[i%2])
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		l.Info("m")
+	}
+	wg.Wait()
+
+	for _, line := range out.lines() {
+		if n := strings.Count(line, "\x1b[3"); n != 3 || strings.Count(line, "\x1b[31m") != n && strings.Count(line, "\x1b[32m") != n {
+			t.Fatalf("line %q mixes the themes", line)
+		}
+	}
+}
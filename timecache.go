@@ -0,0 +1,41 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// the cached time tags, see timeCache
+const (
+	cacheTimeLocal = iota
+	cacheTimeRFC3339
+	cacheTimeApache
+	cachedTimes
+)
+
+// timeCache keeps the last formatted time of a tag, consecutive entries
+// mostly falling within the same unit of its layout: the millisecond for
+// ${time_local}, the second for the others.
+type timeCache struct {
+	last atomic.Value // *cachedTime
+}
+
+type cachedTime struct {
+	unit int64 // time since the epoch, in units
+	loc  *time.Location
+	text string
+}
+
+func (c *timeCache) format(t time.Time, layout string, unit time.Duration) string {
+	n := t.UnixNano()
+	u := n / int64(unit)
+	if n < 0 && n%int64(unit) != 0 {
+		u--
+	}
+	if last, ok := c.last.Load().(*cachedTime); ok && last.unit == u && last.loc == t.Location() {
+		return last.text
+	}
+	text := t.Format(layout)
+	c.last.Store(&cachedTime{u, t.Location(), text})
+	return text
+}
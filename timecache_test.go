@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTimeCacheMillisecondBoundary(t *testing.T) {
+	var c timeCache
+	last := time.Date(2024, 1, 2, 3, 4, 5, 123999999, time.UTC)
+	next := last.Add(time.Nanosecond)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{last.Add(-999999), "2024-01-02 03:04:05.123"},
+		{last, "2024-01-02 03:04:05.123"},
+		{next, "2024-01-02 03:04:05.124"},
+		{last, "2024-01-02 03:04:05.123"},
+		{next.Add(999999), "2024-01-02 03:04:05.124"},
+		{next.Add(1000000), "2024-01-02 03:04:05.125"},
+	}
+	for _, tt := range tests {
+		if got := c.format(tt.t, timeLocal, time.Millisecond); got != tt.want {
+			t.Errorf("format(%s) = %q, want %q", tt.t.Format(time.RFC3339Nano), got, tt.want)
+		}
+	}
+}
+
+// TestTimeCacheMatchesFormat sweeps times across millisecond and second
+// boundaries, before the epoch too, where the units round down.
+func TestTimeCacheMatchesFormat(t *testing.T) {
+	for _, start := range []time.Time{
+		time.Date(2024, 12, 31, 23, 59, 59, 990000000, time.UTC),
+		time.Unix(-1, 990000000),
+		time.Unix(0, -5000000),
+	} {
+		var local, rfc timeCache
+		for tm := start; tm.Before(start.Add(20 * time.Millisecond)); tm = tm.Add(250 * time.Microsecond) {
+			if got, want := local.format(tm, timeLocal, time.Millisecond), tm.Format(timeLocal); got != want {
+				t.Errorf("time_local of %s = %q, want %q", tm.Format(time.RFC3339Nano), got, want)
+			}
+			if got, want := rfc.format(tm, time.RFC3339, time.Second), tm.Format(time.RFC3339); got != want {
+				t.Errorf("time_rfc3339 of %s = %q, want %q", tm.Format(time.RFC3339Nano), got, want)
+			}
+		}
+	}
+}
+
+func TestTimeCacheFollowsTheLocation(t *testing.T) {
+	var c timeCache
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	utc := c.format(tm, time.RFC3339, time.Second)
+	paris := c.format(tm.In(time.FixedZone("CET", 3600)), time.RFC3339, time.Second)
+	if utc != "2024-01-02T03:04:05Z" || paris != "2024-01-02T04:04:05+01:00" {
+		t.Errorf("formats = %q, %q, want the same instant in both zones", utc, paris)
+	}
+}
+
+func TestTimeTagsAcrossAMillisecond(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${time_local} ${message}\n")
+	tf := l.template.Load().(*textFormat)
+	last := time.Date(2024, 1, 2, 3, 4, 5, 999999999, time.Local)
+	for i, tm := range []time.Time{last, last.Add(time.Nanosecond)} {
+		e := &Entry{Level: INFO, Time: tm, Message: "m", logger: l}
+		var b bytes.Buffer
+		if err := l.formatText(&b, tf, e); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"2024-01-02 03:04:05.999 m\n", "2024-01-02 03:04:06 m\n"}[i]
+		if b.String() != want {
+			t.Errorf("entry %d = %q, want %q", i, b.String(), want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package log
+
+import "time"
+
+// SetTimedLevel sets the level used by Timed and TimeTrack, INFO by default.
+func (l *Logger) SetTimedLevel(level Level) {
+	l.lazyInit()
+	l.timedLevel = level
+}
+
+// Timed returns a func which logs how long it took since Timed was called,
+// meant to be deferred: defer l.Timed("rebuild index")().
+func (l *Logger) Timed(msg string) func() {
+	return l.timed(msg, time.Now())
+}
+
+// TimeTrack logs the time elapsed since start: defer l.TimeTrack(time.Now(), "rebuild index").
+func (l *Logger) TimeTrack(start time.Time, msg string) {
+	l.trackTime(start, msg, 3)
+}
+
+func SetTimedLevel(level Level) {
+	global.SetTimedLevel(level)
+}
+
+func Timed(msg string) func() {
+	return global.timed(msg, time.Now())
+}
+
+func TimeTrack(start time.Time, msg string) {
+	global.trackTime(start, msg, 3)
+}
+
+func (l *Logger) timed(msg string, start time.Time) func() {
+	v := l.timedLevel
+	if v < l.Level() {
+		return func() {}
+	}
+
+	// attribute the entry to the function deferring, not to the closure
+	pc, file, line := caller(2)
+	return func() {
+		d := time.Since(start)
+		l.emit(Entry{Level: v, File: file, Line: line, pc: pc, Fields: Fields{"duration_ms": durationMs(d)}}, 0, "", []interface{}{msg, " took ", roundDuration(d)})
+	}
+}
+
+func (l *Logger) trackTime(start time.Time, msg string, calldepth int) {
+	v := l.timedLevel
+	if v < l.Level() {
+		return
+	}
+	d := time.Since(start)
+	l.emit(Entry{Level: v, Fields: Fields{"duration_ms": durationMs(d)}}, calldepth, "", []interface{}{msg, " took ", roundDuration(d)})
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// roundDuration keeps three significant digits or so, 1.234567ms -> 1.235ms.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	default:
+		return d
+	}
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// rebuild defers Timed as documented, returning the line of the defer.
+func rebuild(l *Logger) int {
+	defer l.Timed("rebuild index")()
+	time.Sleep(2 * time.Millisecond)
+	return here() - 2
+}
+
+func TestTimed(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${level} ${short_file}:${line} ${func} ${message}${fields}\n")
+	line := rebuild(l)
+
+	lines := out.lines()
+	want := regexp.MustCompile(`^INFO timed_test.go:` + strconv.Itoa(line) + ` \S*rebuild rebuild index took (\S+) duration_ms=(\S+)$`)
+	m := want.FindStringSubmatch(lines[0])
+	if len(lines) != 1 || m == nil {
+		t.Fatalf("lines = %q, want %s", lines, want)
+	}
+	took, err := time.ParseDuration(m[1])
+	if err != nil || took < 2*time.Millisecond {
+		t.Errorf("took %s, want at least 2ms", m[1])
+	}
+	if ms, err := strconv.ParseFloat(m[2], 64); err != nil || ms < 2 {
+		t.Errorf("duration_ms = %s, want at least 2", m[2])
+	}
+}
+
+func TestTimedLevel(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetTimedLevel(WARN)
+	l.Timed("warned")()
+	l.TimeTrack(time.Now(), "tracked")
+
+	// disabled when Timed is called, whatever the level when it ends
+	l.SetLevel(ERROR)
+	done := l.Timed("disabled")
+	l.SetLevel(DEBUG)
+	done()
+	l.SetLevel(ERROR)
+	l.TimeTrack(time.Now(), "disabled")
+
+	lines := out.lines()
+	if len(lines) != 2 || !regexp.MustCompile(`^WARN warned took \S+$`).MatchString(lines[0]) ||
+		!regexp.MustCompile(`^WARN tracked took \S+$`).MatchString(lines[1]) {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestTimeTrackJSON(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.EnableJSON()
+	start := time.Now().Add(-1500 * time.Millisecond)
+	l.TimeTrack(start, "sync")
+
+	var v struct {
+		Msg        string  `json:"msg"`
+		Caller     string  `json:"caller"`
+		DurationMs float64 `json:"duration_ms"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^sync took 1\.5\d*s$`).MatchString(v.Msg) || v.DurationMs < 1500 || !regexp.MustCompile(`/timed_test.go:\d+$`).MatchString(v.Caller) {
+		t.Errorf("entry = %+v", v)
+	}
+}
+
+func TestRoundDuration(t *testing.T) {
+	tests := []struct {
+		d, want time.Duration
+	}{
+		{0, 0},
+		{999, 999},
+		{1234567, 1235 * time.Microsecond},
+		{999999999, time.Second},
+		{1234567890, 1235 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := roundDuration(tt.d); got != tt.want {
+			t.Errorf("roundDuration(%s) = %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkTimedDisabled(b *testing.B) {
+	l := New("", ERROR, 0, 0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Timed("disabled")()
+	}
+}
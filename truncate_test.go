@@ -0,0 +1,60 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTruncateOnOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"append by default", nil, "previous run\nthis run\n"},
+		{"truncate", []Option{WithTruncateOnOpen(true)}, "this run\n"},
+		{"truncate lazily", []Option{WithTruncateOnOpen(true), WithLazyOpen(true)}, "this run\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "app.log")
+			if err := ioutil.WriteFile(name, []byte("previous run\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			l := New(name, INFO, 0, 0, tt.opts...)
+			defer l.Close()
+			l.SetFormat("${message}\n")
+			l.Info("this run")
+
+			if got := readFile(t, name); got != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+			if st := l.Stats(); st.Size != len(tt.want) {
+				t.Errorf("Stats().Size = %d, want %d", st.Size, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestTruncateOnlyTheFirstOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := New(name, INFO, 0, 0, WithTruncateOnOpen(true))
+	defer l.Close()
+	l.SetFormat("${message}\n")
+	l.Info("first")
+	if err := l.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after reopen")
+	l.SetFile(name)
+	l.Info("after SetFile")
+
+	got := readFile(t, name)
+	for _, want := range []string{"first\n", "after reopen\n", "after SetFile\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("file = %q, want %q kept", got, want)
+		}
+	}
+}
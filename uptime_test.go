@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestUptimeTags(t *testing.T) {
+	tests := []struct {
+		width   int
+		elapsed time.Duration
+		want    string
+	}{
+		{0, 382 * time.Millisecond, "+0.382s|382"},
+		{0, 61*time.Second + 5*time.Millisecond, "+61.005s|61005"},
+		{8, 382 * time.Millisecond, " +0.382s|382"},
+		{8, 1234*time.Second + 999999*time.Nanosecond, "+1234.001s|1234000"},
+	}
+	for _, tt := range tests {
+		var out syncBuffer
+		l := newTestLogger(&out)
+		l.SetUptimeWidth(tt.width)
+		l.SetFormat("${uptime}|${uptime_ms}")
+		tf := l.template.Load().(*textFormat)
+		e := &Entry{Level: INFO, Time: l.start.Add(tt.elapsed), logger: l}
+		var b bytes.Buffer
+		if err := l.formatText(&b, tf, e); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("width %d, %s: %q, want %q", tt.width, tt.elapsed, b.String(), tt.want)
+		}
+	}
+}
+
+func TestUptimeSinceNew(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${uptime} ${uptime_ms}\n")
+	time.Sleep(10 * time.Millisecond)
+	l.Info("m")
+	m := regexp.MustCompile(`^\+(\d+)\.(\d{3})s (\d+)$`).FindStringSubmatch(out.lines()[0])
+	if m == nil || m[1] == "0" && m[2] < "010" {
+		t.Errorf("output = %q, want at least 10ms since New", out.String())
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// Duration, Bytes and Time wrap field values for consistent rendering:
+// text formats get a human readable form (1.24s, 3.5MiB, RFC3339) while
+// JSON gets raw numbers (milliseconds, bytes, unix seconds).
+type (
+	Duration time.Duration
+	Bytes    int64
+	Time     time.Time
+)
+
+func (d Duration) String() string {
+	v := time.Duration(d)
+	// -v overflows for the smallest duration, which is left unrounded
+	if v < 0 && -v > 0 {
+		return "-" + roundDuration(-v).String()
+	}
+	return roundDuration(v).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, durationMs(time.Duration(d)), 'f', -1, 64), nil
+}
+
+var byteUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+func (b Bytes) String() string {
+	// unsigned, so the smallest value has a magnitude too
+	n := uint64(b)
+	sign := ""
+	if b < 0 {
+		sign, n = "-", -n
+	}
+	if n < 1024 {
+		return sign + strconv.FormatUint(n, 10) + "B"
+	}
+
+	// from 1023.95 on, the value rounds to 1024.0 of the unit: use the next
+	v, unit := float64(n)/1024, 0
+	for v >= 1023.95 && unit < len(byteUnits)-1 {
+		v /= 1024
+		unit++
+	}
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	if s[len(s)-2:] == ".0" {
+		s = s[:len(s)-2]
+	}
+	return sign + s + byteUnits[unit]
+}
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(b), 10), nil
+}
+
+func (t Time) String() string {
+	return time.Time(t).Format(time.RFC3339)
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, time.Time(t).Unix(), 10), nil
+}
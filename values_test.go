@@ -0,0 +1,116 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		text string
+		json string
+	}{
+		{0, "0s", "0"},
+		{1, "1ns", "0.000001"},
+		{1234567, "1.235ms", "1.234567"},
+		{1240 * time.Millisecond, "1.24s", "1240"},
+		{-1240 * time.Millisecond, "-1.24s", "-1240"},
+		{-1234567, "-1.235ms", "-1.234567"},
+		{-1, "-1ns", "-0.000001"},
+		{90 * time.Minute, "1h30m0s", "5400000"},
+		{math.MaxInt64, "2562047h47m16.854775807s", "9223372036854.775"},
+		{math.MinInt64, "-2562047h47m16.854775808s", "-9223372036854.775"},
+	}
+	for _, tt := range tests {
+		if got := Duration(tt.d).String(); got != tt.text {
+			t.Errorf("Duration(%d).String() = %q, want %q", int64(tt.d), got, tt.text)
+		}
+		if got, _ := Duration(tt.d).MarshalJSON(); string(got) != tt.json {
+			t.Errorf("Duration(%d).MarshalJSON() = %s, want %s", int64(tt.d), got, tt.json)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		text string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1KiB"},
+		{1536, "1.5KiB"},
+		{1<<20 - 1, "1MiB"},
+		{1<<20 - 52, "1023.9KiB"},
+		{3<<20 + 1<<19, "3.5MiB"},
+		{1 << 40, "1TiB"},
+		{5<<40 + 1<<39, "5.5TiB"},
+		{1 << 50, "1PiB"},
+		{math.MaxInt64, "8EiB"},
+		{-1, "-1B"},
+		{-1 << 40, "-1TiB"},
+		{math.MinInt64, "-8EiB"},
+	}
+	for _, tt := range tests {
+		if got := Bytes(tt.n).String(); got != tt.text {
+			t.Errorf("Bytes(%d).String() = %q, want %q", tt.n, got, tt.text)
+		}
+		var back int64
+		if b, _ := Bytes(tt.n).MarshalJSON(); json.Unmarshal(b, &back) != nil || back != tt.n {
+			t.Errorf("Bytes(%d).MarshalJSON() = %s", tt.n, b)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		text string
+		json string
+	}{
+		{time.Unix(0, 0).UTC(), "1970-01-01T00:00:00Z", "0"},
+		{time.Date(2024, 2, 29, 23, 59, 59, 999999999, time.FixedZone("", 3600)), "2024-02-29T23:59:59+01:00", "1709247599"},
+		{time.Unix(-1, 0).UTC(), "1969-12-31T23:59:59Z", "-1"},
+	}
+	for _, tt := range tests {
+		if got := Time(tt.t).String(); got != tt.text {
+			t.Errorf("Time(%v).String() = %q, want %q", tt.t, got, tt.text)
+		}
+		if got, _ := Time(tt.t).MarshalJSON(); string(got) != tt.json {
+			t.Errorf("Time(%v).MarshalJSON() = %s, want %s", tt.t, got, tt.json)
+		}
+	}
+}
+
+func TestValuesInBothFormats(t *testing.T) {
+	var out syncBuffer
+	l := newTestLogger(&out)
+	l.SetFormat("${message}${fields}\n")
+	log := func() {
+		l.WithFields(Fields{
+			"took": Duration(-1240 * time.Millisecond),
+			"size": Bytes(2 << 40),
+			"at":   Time(time.Unix(0, 0).UTC()),
+		}).Info("m")
+	}
+	log()
+	l.EnableJSON()
+	log()
+
+	lines := out.lines()
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if want := "m at=1970-01-01T00:00:00Z size=2TiB took=-1.24s"; lines[0] != want {
+		t.Errorf("text = %q, want %q", lines[0], want)
+	}
+	for _, want := range []string{`"at":0`, `"size":2199023255552`, `"took":-1240`} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("json = %q, want %s", lines[1], want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// streamWriter records the flushes and closes of a buffered stream, like
+// a gzip.Writer.
+type streamWriter struct {
+	syncBuffer
+	flushes, closes int
+	flushErr        error
+	closeErr        error
+}
+
+func (w *streamWriter) Flush() error {
+	w.flushes++
+	return w.flushErr
+}
+
+func (w *streamWriter) Close() error {
+	w.closes++
+	return w.closeErr
+}
+
+func TestSyncFlushesTheOutput(t *testing.T) {
+	w := &streamWriter{}
+	l := newTestLogger(&w.syncBuffer)
+	l.SetOutput(w)
+	l.Info("m")
+	if err := l.Sync(); err != nil || w.flushes != 1 || w.closes != 0 {
+		t.Fatalf("Sync = %v, %d flushes, %d closes", err, w.flushes, w.closes)
+	}
+	w.flushErr = errors.New("flush failed")
+	if err := l.Sync(); err != w.flushErr {
+		t.Errorf("Sync = %v, want %v", err, w.flushErr)
+	}
+}
+
+func TestCloseClosesTheOutput(t *testing.T) {
+	w := &streamWriter{}
+	l := newTestLogger(&w.syncBuffer)
+	l.SetOutput(w)
+	l.Info("m")
+	if err := l.Close(); err != nil || w.flushes != 1 || w.closes != 1 {
+		t.Fatalf("Close = %v, %d flushes, %d closes", err, w.flushes, w.closes)
+	}
+	// the stream is closed once, later entries are discarded
+	l.Info("after close")
+	l.Close()
+	if w.closes != 1 || w.String() != "INFO m\n" {
+		t.Errorf("%d closes, output %q", w.closes, w.String())
+	}
+}
+
+func TestCloseReturnsTheFirstError(t *testing.T) {
+	w := &streamWriter{flushErr: errors.New("flush failed"), closeErr: errors.New("close failed")}
+	l := newTestLogger(&w.syncBuffer)
+	l.SetOutput(w)
+	if err := l.Close(); err != w.flushErr || w.closes != 1 {
+		t.Errorf("Close = %v after %d closes, want %v and the stream closed", err, w.closes, w.flushErr)
+	}
+
+	w = &streamWriter{closeErr: errors.New("close failed")}
+	l.SetOutput(w)
+	if err := l.Close(); err != w.closeErr {
+		t.Errorf("Close = %v, want %v", err, w.closeErr)
+	}
+}
+
+func TestCloseOfTheManagedFile(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "app.log"), INFO, 0, 0)
+	l.Info("m")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// not closed twice
+	if err := l.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+func TestFatalFlushesTheOutput(t *testing.T) {
+	w := &streamWriter{}
+	l := newTestLogger(&w.syncBuffer)
+	l.SetOutput(w)
+	code := 0
+	l.SetExitFunc(func(c int) { code = c })
+	l.Fatal("boom")
+	if code != 1 || w.flushes != 1 {
+		t.Errorf("exit code %d after %d flushes, want the output flushed first", code, w.flushes)
+	}
+}
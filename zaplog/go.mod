@@ -0,0 +1,20 @@
+module github.com/seaguest/log/zaplog
+
+go 1.19
+
+require (
+	github.com/seaguest/log v0.0.0
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/labstack/gommon v0.3.0 // indirect
+	github.com/mattn/go-colorable v0.1.8 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
+)
+
+replace github.com/seaguest/log => ../
@@ -0,0 +1,72 @@
+// Package zaplog provides a zapcore.Core writing through a log.Logger, so
+// code still using zap shares its output and rotation, kept apart so the
+// dependency is only pulled in by its users:
+//
+//	logger := zap.New(zaplog.NewZapCore(log.GetLogger(), nil), zap.AddCaller())
+package zaplog
+
+import (
+	"github.com/seaguest/log"
+	"go.uber.org/zap/zapcore"
+)
+
+type core struct {
+	l      *log.Logger
+	enab   zapcore.LevelEnabler
+	fields []zapcore.Field
+}
+
+// NewZapCore returns a Core logging to l, its levels mapped by
+// l.MapLevel(log.SchemeZap, ...). A nil enab enables the levels l logs.
+// The caller of the zap Entry is kept, and entries go through Entry.Log,
+// in order with the ones logged with l.
+func NewZapCore(l *log.Logger, enab zapcore.LevelEnabler) zapcore.Core {
+	return &core{l: l, enab: enab}
+}
+
+func (c *core) Enabled(lvl zapcore.Level) bool {
+	if c.enab != nil {
+		return c.enab.Enabled(lvl)
+	}
+	v := c.l.MapLevel(log.SchemeZap, int(lvl))
+	return v >= c.l.Level() || v == log.FATAL
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write logs ent, zap itself exits or panics afterwards if needed.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	msg := ent.Message
+	if ent.LoggerName != "" {
+		msg = ent.LoggerName + ": " + msg
+	}
+	e := c.l.WithFields(log.Fields(enc.Fields))
+	if ent.Caller.Defined {
+		e.File, e.Line = ent.Caller.File, ent.Caller.Line
+	}
+	// without zap.AddCaller: Write, CheckedEntry.Write, the zap Logger
+	// method and its caller
+	return e.Log(c.l.MapLevel(log.SchemeZap, int(ent.Level)), 4, msg)
+}
+
+func (c *core) Sync() error {
+	return c.l.Sync()
+}
@@ -0,0 +1,213 @@
+package zaplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/seaguest/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+// entries decodes the JSON lines written so far.
+func (b *syncBuffer) entries(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.Lock()
+	defer b.Unlock()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(b.buf.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		entries = append(entries, v)
+	}
+	return entries
+}
+
+// newObserved returns a zap Logger writing both through the adapter, to a
+// JSON Logger at level, and to an observer recording what zap intended.
+func newObserved(level log.Level, opts ...zap.Option) (*zap.Logger, *syncBuffer, *observer.ObservedLogs) {
+	out := &syncBuffer{}
+	l := log.New("", level, 0, 0)
+	l.SetOutput(out)
+	l.EnableJSON()
+	obs, logs := observer.New(zapcore.DebugLevel)
+	core := zapcore.NewTee(NewZapCore(l, nil), obs)
+	return zap.New(core, append([]zap.Option{zap.AddCaller()}, opts...)...), out, logs
+}
+
+// compare checks that every observed entry was written with the mapped
+// level, the same message, fields and caller.
+func compare(t *testing.T, out *syncBuffer, logs *observer.ObservedLogs, mapped []string) {
+	t.Helper()
+	observed := logs.AllUntimed()
+	written := out.entries(t)
+	if len(written) != len(mapped) {
+		t.Fatalf("%d entries written, %d observed, want %d", len(written), len(observed), len(mapped))
+	}
+	for i, w := range written {
+		o := observed[len(observed)-len(mapped)+i]
+		msg := o.Message
+		if o.LoggerName != "" {
+			msg = o.LoggerName + ": " + msg
+		}
+		if w["level"] != mapped[i] || w["msg"] != msg {
+			t.Errorf("entry %d = %s %q, want %s %q", i, w["level"], w["msg"], mapped[i], msg)
+		}
+		want := "/zaplog_test.go:" + strconv.Itoa(o.Caller.Line)
+		if caller, _ := w["caller"].(string); !strings.HasSuffix(caller, want) {
+			t.Errorf("entry %d: caller = %q, want %q", i, caller, want)
+		}
+		for k, v := range o.ContextMap() {
+			// compared as JSON, the numbers being float64 once decoded
+			a, _ := json.Marshal(v)
+			b, _ := json.Marshal(w[k])
+			if !bytes.Equal(a, b) {
+				t.Errorf("entry %d: %s = %s, want %s", i, k, b, a)
+			}
+		}
+	}
+}
+
+func TestLevelMapping(t *testing.T) {
+	z, out, logs := newObserved(log.DEBUG, zap.WithFatalHook(zapcore.WriteThenPanic))
+	z.Debug("debug")
+	z.Info("info")
+	z.Warn("warn")
+	z.Error("error")
+	z.DPanic("dpanic")
+	func() {
+		defer func() { recover() }()
+		z.Panic("panic")
+	}()
+	func() {
+		defer func() { recover() }()
+		z.Fatal("fatal")
+	}()
+	compare(t, out, logs, []string{"debug", "info", "warn", "error", "error", "error", "fatal"})
+}
+
+func TestEnabled(t *testing.T) {
+	core := NewZapCore(log.New("", log.ERROR, 0, 0), nil)
+	for lvl, want := range map[zapcore.Level]bool{
+		zapcore.DebugLevel:  false,
+		zapcore.InfoLevel:   false,
+		zapcore.WarnLevel:   false,
+		zapcore.ErrorLevel:  true,
+		zapcore.DPanicLevel: true,
+		zapcore.FatalLevel:  true,
+	} {
+		if got := core.Enabled(lvl); got != want {
+			t.Errorf("Enabled(%s) = %v, want %v", lvl, got, want)
+		}
+	}
+	// an explicit enabler wins over the level of the Logger
+	core = NewZapCore(log.New("", log.ERROR, 0, 0), zapcore.DebugLevel)
+	if !core.Enabled(zapcore.DebugLevel) {
+		t.Error("the enabler is ignored")
+	}
+
+	z, out, logs := newObserved(log.WARN)
+	z.Info("dropped")
+	z.Warn("kept")
+	if n := logs.FilterMessage("dropped").Len(); n != 1 {
+		t.Fatalf("the observer saw %d entries, want 1", n)
+	}
+	compare(t, out, logs, []string{"warn"})
+}
+
+func TestFields(t *testing.T) {
+	z, out, logs := newObserved(log.DEBUG)
+	z.Info("typed",
+		zap.String("s", "v"),
+		zap.Int("n", 42),
+		zap.Float64("f", 1.5),
+		zap.Bool("b", true),
+		zap.Strings("list", []string{"a", "b"}),
+		zap.Any("map", map[string]int{"k": 1}),
+	)
+	z.Named("db").Warn("named", zap.Int64("rows", 7))
+	compare(t, out, logs, []string{"info", "warn"})
+}
+
+func TestWith(t *testing.T) {
+	z, out, logs := newObserved(log.DEBUG)
+	svc := z.With(zap.String("svc", "api"))
+	req := svc.With(zap.Int("req", 1))
+	req.Info("first")
+	svc.Info("second", zap.String("extra", "x"))
+	// a With doesn't leak into its parent or siblings
+	svc.With(zap.Int("req", 2)).Error("third")
+	z.Info("plain")
+	compare(t, out, logs, []string{"info", "info", "error", "info"})
+
+	written := out.entries(t)
+	if _, ok := written[1]["req"]; ok {
+		t.Errorf("second = %v, want no req field", written[1])
+	}
+	if _, ok := written[3]["svc"]; ok {
+		t.Errorf("plain = %v, want no svc field", written[3])
+	}
+}
+
+// flushBuffer counts the flushes, failing them once err is set.
+type flushBuffer struct {
+	syncBuffer
+	flushes int
+	err     error
+}
+
+func (b *flushBuffer) Flush() error {
+	b.Lock()
+	defer b.Unlock()
+	b.flushes++
+	return b.err
+}
+
+func TestSync(t *testing.T) {
+	out := &flushBuffer{}
+	l := log.New("", log.INFO, 0, 0)
+	l.SetOutput(out)
+	z := zap.New(NewZapCore(l, nil))
+	z.Info("synced")
+	if err := z.Sync(); err != nil || out.flushes != 1 {
+		t.Fatalf("Sync = %v after %d flushes, want a flush", err, out.flushes)
+	}
+
+	out.err = errors.New("disk full")
+	if err := z.With(zap.Int("n", 1)).Sync(); err != out.err {
+		t.Errorf("Sync = %v, want %v", err, out.err)
+	}
+
+	// a file output is synced without error
+	name := filepath.Join(t.TempDir(), "app.log")
+	l = log.New(name, log.INFO, 0, 0)
+	defer l.Close()
+	z = zap.New(NewZapCore(l, nil))
+	z.Info("synced")
+	if err := z.Sync(); err != nil {
+		t.Errorf("Sync of a file = %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package zstd provides a zstd Codec for log.SetCompression, kept apart so
+// the dependency is only pulled in by its users:
+//
+//	log.SetCompression(zstd.New(zstd.SpeedDefault))
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/seaguest/log"
+)
+
+// Speed levels, SpeedDefault is roughly zstd level 3.
+const (
+	SpeedFastest           = zstd.SpeedFastest
+	SpeedDefault           = zstd.SpeedDefault
+	SpeedBetterCompression = zstd.SpeedBetterCompression
+	SpeedBestCompression   = zstd.SpeedBestCompression
+)
+
+// Codec compresses archives with zstd into ".zst" files, and reads them
+// back for log.VerifyLogFile.
+type Codec struct {
+	level zstd.EncoderLevel
+}
+
+var (
+	_ log.Codec        = Codec{}
+	_ log.Decompressor = Codec{}
+)
+
+// New returns a Codec compressing at level.
+func New(level zstd.EncoderLevel) Codec {
+	return Codec{level: level}
+}
+
+func (c Codec) Extension() string {
+	return ".zst"
+}
+
+func (c Codec) Compress(dst, src string) error {
+	return log.CompressFile(dst, src, func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(c.level))
+	})
+}
+
+func (c Codec) Decompress(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+func init() {
+	log.RegisterCodec(New(SpeedDefault))
+}
@@ -0,0 +1,26 @@
+package zstd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/seaguest/log"
+)
+
+func TestVerifyCompressedArchive(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	l := log.New(name, log.INFO, 0, 5, log.WithIntegrityFooter(true))
+	defer l.Close()
+	l.SetCompression(New(SpeedFastest))
+	l.SetLineEnding("\r\n")
+	l.Info("first")
+	l.Info("second")
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := name + ".1.zst"
+	if err := l.VerifyLogFile(archive); err != nil {
+		t.Error(err)
+	}
+}